@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"strings"
+	"text/tabwriter"
+)
+
+const cliUsage = `usage:
+  restaurant-system                              start the server
+  restaurant-system device list
+  restaurant-system device register <device-id> [name]
+  restaurant-system device rename <device-id> <name>
+  restaurant-system device disable <device-id>
+  restaurant-system device enable <device-id>
+  restaurant-system device delete <device-id>
+  restaurant-system device pairings
+  restaurant-system device approve <pairing-code> [name]`
+
+// runCLI handles administrative subcommands instead of starting the server.
+func runCLI(db *database.DB, args []string) error {
+	if len(args) < 2 || args[0] != "device" {
+		return errors.New(cliUsage)
+	}
+
+	devices := services.NewDeviceService(db)
+	cmd, rest := args[1], args[2:]
+
+	switch {
+	case cmd == "list" && len(rest) == 0:
+		list, err := devices.ListDevices()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DEVICE ID\tNAME\tDISABLED\tREGISTERED\tLAST SEEN")
+		for _, d := range list {
+			lastSeen := "-"
+			if d.LastSeenAt != nil {
+				lastSeen = d.LastSeenAt.Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", d.DeviceID, d.Name, d.Disabled, d.RegisteredAt.Format("2006-01-02 15:04"), lastSeen)
+		}
+		return w.Flush()
+	case cmd == "register" && len(rest) >= 1:
+		device, err := devices.RegisterDevice(&models.RegisterDeviceRequest{DeviceID: rest[0], Name: strings.Join(rest[1:], " ")}, "")
+		if err != nil {
+			return err
+		}
+		fmt.Println("registered", device.DeviceID)
+		return nil
+	case cmd == "rename" && len(rest) >= 2:
+		name := strings.Join(rest[1:], " ")
+		_, err := devices.UpdateDevice(rest[0], &models.UpdateDeviceRequest{Name: &name})
+		return err
+	case (cmd == "disable" || cmd == "enable") && len(rest) == 1:
+		disabled := cmd == "disable"
+		_, err := devices.UpdateDevice(rest[0], &models.UpdateDeviceRequest{Disabled: &disabled})
+		return err
+	case cmd == "delete" && len(rest) == 1:
+		return devices.DeleteDevice(rest[0])
+	case cmd == "pairings" && len(rest) == 0:
+		pairings, err := devices.ListPendingPairings()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tDEVICE ID\tNAME\tEXPIRES")
+		for _, p := range pairings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Code, p.DeviceID, p.Name, p.ExpiresAt.Format("15:04:05"))
+		}
+		return w.Flush()
+	case cmd == "approve" && len(rest) >= 1:
+		device, err := devices.ApprovePairing(rest[0], &models.ApprovePairingRequest{Name: strings.Join(rest[1:], " ")}, "")
+		if err != nil {
+			return err
+		}
+		fmt.Println("approved", device.DeviceID)
+		return nil
+	default:
+		return errors.New(cliUsage)
+	}
+}
@@ -1,8 +1,13 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // PaymentsConfig holds Telebirr/Fabric configuration loaded from environment variables.
@@ -12,9 +17,135 @@ type PaymentsConfig struct {
 	ShortCode     string
 	AppSecret     string
 	PrivateKeyPEM string
+	// RefundRetryInterval is how often refunds the gateway did not take are retried.
+	RefundRetryInterval time.Duration
+}
+
+// AuthConfig holds authentication and authorization settings.
+type AuthConfig struct {
+	// AdminPhoneNumbers are granted the admin role when they sign in, so a fresh
+	// install always has someone able to manage roles.
+	AdminPhoneNumbers []string
+
+	// OTPSecret keys the HMAC used to store OTP codes.
+	OTPSecret string
+	// OTPMaxAttempts is how many guesses a single code accepts before it is burned.
+	OTPMaxAttempts int
+	// OTPMaxFailures is how many failed verifications a phone or device may make
+	// before it is locked out for OTPLockout.
+	OTPMaxFailures int
+	OTPLockout     time.Duration
+	// OTPRequestCooldown is the minimum gap between OTP requests for a phone or device.
+	OTPRequestCooldown time.Duration
+
+	// SessionTTL is how long a session token stays valid.
+	SessionTTL time.Duration
+	// SessionRefreshWindow is how close to expiry a token must be before it can be refreshed.
+	SessionRefreshWindow time.Duration
+	// SweepInterval is how often expired sessions and OTPs are deleted.
+	SweepInterval time.Duration
+
+	// TrustedProxies are the addresses whose X-Forwarded-For header is believed when
+	// working out a client's address for rate limits. None by default.
+	TrustedProxies []string
+}
+
+// SMSConfig selects and configures the SMS provider used for OTP delivery.
+type SMSConfig struct {
+	// Provider is one of "http", "file" or "console". It must be set; "console" is for
+	// local development.
+	Provider      string
+	GatewayURL    string
+	BodyTemplate  string
+	ContentType   string
+	AuthHeader    string
+	SpoolDir      string
+	DefaultLocale string
+}
+
+// BranchConfig identifies this restaurant branch and its business day.
+type BranchConfig struct {
+	// ID keeps order numbers of different branches apart when they share a database.
+	ID string
+	// Location is the time zone the business day is counted in.
+	Location *time.Location
+	// DayStart is the time after midnight at which a new business day, and with it a new
+	// run of order numbers, begins. Orders taken before it count towards the previous day.
+	DayStart time.Duration
+	// CashOnDelivery lets customers choose to pay for delivery orders at the door.
+	CashOnDelivery bool
+}
+
+// BusinessDay returns the business day t falls on, as YYYY-MM-DD.
+func (b BranchConfig) BusinessDay(t time.Time) string {
+	return t.In(b.Location).Add(-b.DayStart).Format("2006-01-02")
+}
+
+// GuestConfig controls ordering by guests who scan a table's QR code.
+type GuestConfig struct {
+	// WebURL is the page the QR codes open; the table and its key are added as query params.
+	WebURL string
+	// TableTokenTTL is how long a guest may keep ordering after scanning.
+	TableTokenTTL time.Duration
+}
+
+// ReservationConfig controls reservations and the walk-in waitlist.
+type ReservationConfig struct {
+	// RestaurantName is used in the text messages sent to guests.
+	RestaurantName string
+	// TurnTime is how long a party usually keeps a table; parties of LargePartySize or
+	// more keep it for LargePartyTurnTime.
+	TurnTime           time.Duration
+	LargePartySize     int
+	LargePartyTurnTime time.Duration
+	// CleanTime is how long a dirty table takes to be ready again.
+	CleanTime time.Duration
+}
+
+// TurnTimeFor returns how long a party of partySize is expected to keep a table.
+func (r ReservationConfig) TurnTimeFor(partySize int) time.Duration {
+	if r.LargePartySize > 0 && partySize >= r.LargePartySize {
+		return r.LargePartyTurnTime
+	}
+	return r.TurnTime
+}
+
+// KitchenConfig controls ready time estimates and late order alerts.
+type KitchenConfig struct {
+	// StationCapacity is how many items a station works on at once.
+	StationCapacity int
+	// LateGrace is how long past its prep time an order may take before it is late.
+	LateGrace time.Duration
+	// MonitorInterval is how often orders are checked for being late.
+	MonitorInterval time.Duration
+}
+
+// PrintingConfig controls the kitchen ticket and receipt printers.
+type PrintingConfig struct {
+	// SpoolDir holds the output of file printers.
+	SpoolDir string
+	// PollInterval is how often newly confirmed or fired items are printed.
+	PollInterval time.Duration
+	// Timeout bounds connecting and writing to a network printer.
+	Timeout time.Duration
+	// MaxAttempts and RetryDelay control retries of a failed job; the delay doubles
+	// after each attempt.
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// QueueSize is how many jobs may wait for each printer.
+	QueueSize int
+	// ReceiptHeader is printed at the top of receipts.
+	ReceiptHeader string
 }
 
 var paymentsConfig PaymentsConfig
+var authConfig AuthConfig
+var smsConfig SMSConfig
+var branchConfig BranchConfig
+var guestConfig GuestConfig
+var reservationConfig ReservationConfig
+var kitchenConfig KitchenConfig
+var printingConfig PrintingConfig
 
 // Load reads and validates required environment variables. It should be called once at startup.
 func Load() {
@@ -24,12 +155,102 @@ func Load() {
 		ShortCode:     os.Getenv("TELEBIRR_SHORT_CODE"),
 		AppSecret:     os.Getenv("TELEBIRR_APP_SECRET"),
 		PrivateKeyPEM: os.Getenv("TELEBIRR_PRIVATE_KEY_PEM"),
+
+		RefundRetryInterval: time.Duration(getenvPositiveInt("REFUND_RETRY_INTERVAL_MINUTES", 5)) * time.Minute,
 	}
 
 	// Do not fatally exit to keep non-payment features usable in dev; just warn if missing.
 	if paymentsConfig.MerchantAppID == "" || paymentsConfig.FabricAppID == "" || paymentsConfig.ShortCode == "" || paymentsConfig.AppSecret == "" || paymentsConfig.PrivateKeyPEM == "" {
 		log.Println("warning: missing Telebirr env vars; payment features may not work")
 	}
+
+	authConfig = AuthConfig{
+		AdminPhoneNumbers:    splitList(os.Getenv("ADMIN_PHONE_NUMBERS")),
+		OTPSecret:            os.Getenv("OTP_SECRET"),
+		OTPMaxAttempts:       getenvPositiveInt("OTP_MAX_ATTEMPTS", 5),
+		OTPMaxFailures:       getenvPositiveInt("OTP_MAX_FAILURES", 10),
+		OTPLockout:           time.Duration(getenvPositiveInt("OTP_LOCKOUT_MINUTES", 15)) * time.Minute,
+		OTPRequestCooldown:   time.Duration(getenvInt("OTP_REQUEST_COOLDOWN_SECONDS", 60)) * time.Second,
+		SessionTTL:           time.Duration(getenvPositiveInt("SESSION_TTL_HOURS", 24)) * time.Hour,
+		SessionRefreshWindow: time.Duration(getenvPositiveInt("SESSION_REFRESH_WINDOW_MINUTES", 120)) * time.Minute,
+		SweepInterval:        time.Duration(getenvPositiveInt("SESSION_SWEEP_INTERVAL_MINUTES", 10)) * time.Minute,
+		TrustedProxies:       splitList(os.Getenv("TRUSTED_PROXIES")),
+	}
+	if authConfig.OTPSecret == "" {
+		// Codes only live a few minutes, so a per-process secret is workable in dev
+		log.Println("warning: OTP_SECRET not set; using a random secret, pending OTPs will not survive a restart")
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Fatal("failed to generate OTP secret:", err)
+		}
+		authConfig.OTPSecret = hex.EncodeToString(buf)
+	}
+
+	smsConfig = SMSConfig{
+		Provider:      os.Getenv("SMS_PROVIDER"),
+		GatewayURL:    os.Getenv("SMS_GATEWAY_URL"),
+		BodyTemplate:  os.Getenv("SMS_GATEWAY_BODY_TEMPLATE"),
+		ContentType:   os.Getenv("SMS_GATEWAY_CONTENT_TYPE"),
+		AuthHeader:    os.Getenv("SMS_GATEWAY_AUTH"),
+		SpoolDir:      getenvDefault("SMS_SPOOL_DIR", "./sms-spool"),
+		DefaultLocale: getenvDefault("SMS_DEFAULT_LOCALE", "en"),
+	}
+
+	branchConfig = BranchConfig{
+		ID:       getenvDefault("BRANCH_ID", "main"),
+		Location: time.Local,
+		DayStart: 4 * time.Hour,
+
+		CashOnDelivery: getenvBool("CASH_ON_DELIVERY", false),
+	}
+	if tz := os.Getenv("BRANCH_TIMEZONE"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			log.Printf("warning: invalid BRANCH_TIMEZONE=%q; using local time", tz)
+		} else {
+			branchConfig.Location = loc
+		}
+	}
+	if v := os.Getenv("BUSINESS_DAY_START"); v != "" {
+		start, err := time.Parse("15:04", v)
+		if err != nil {
+			log.Printf("warning: invalid BUSINESS_DAY_START=%q; using 04:00", v)
+		} else {
+			branchConfig.DayStart = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+		}
+	}
+
+	guestConfig = GuestConfig{
+		WebURL:        strings.TrimRight(getenvDefault("PUBLIC_WEB_URL", "http://localhost:3000"), "/") + "/table",
+		TableTokenTTL: time.Duration(getenvInt("GUEST_TOKEN_TTL_MINUTES", 180)) * time.Minute,
+	}
+
+	reservationConfig = ReservationConfig{
+		RestaurantName:     getenvDefault("RESTAURANT_NAME", "our restaurant"),
+		TurnTime:           time.Duration(getenvInt("TABLE_TURN_MINUTES", 90)) * time.Minute,
+		LargePartySize:     getenvInt("LARGE_PARTY_SIZE", 6),
+		LargePartyTurnTime: time.Duration(getenvInt("LARGE_PARTY_TURN_MINUTES", 120)) * time.Minute,
+		CleanTime:          time.Duration(getenvInt("TABLE_CLEAN_MINUTES", 5)) * time.Minute,
+	}
+
+	kitchenConfig = KitchenConfig{
+		StationCapacity: getenvInt("KITCHEN_STATION_CAPACITY", 3),
+		LateGrace:       time.Duration(getenvInt("KITCHEN_LATE_GRACE_MINUTES", 10)) * time.Minute,
+		MonitorInterval: time.Duration(getenvPositiveInt("KITCHEN_MONITOR_INTERVAL_SECONDS", 60)) * time.Second,
+	}
+	if kitchenConfig.StationCapacity < 1 {
+		kitchenConfig.StationCapacity = 1
+	}
+
+	printingConfig = PrintingConfig{
+		SpoolDir:      getenvDefault("PRINT_SPOOL_DIR", "./print-spool"),
+		PollInterval:  time.Duration(getenvPositiveInt("PRINT_POLL_INTERVAL_SECONDS", 3)) * time.Second,
+		Timeout:       time.Duration(getenvPositiveInt("PRINTER_TIMEOUT_SECONDS", 5)) * time.Second,
+		MaxAttempts:   getenvInt("PRINT_MAX_ATTEMPTS", 5),
+		RetryDelay:    time.Duration(getenvInt("PRINT_RETRY_DELAY_SECONDS", 2)) * time.Second,
+		QueueSize:     getenvInt("PRINT_QUEUE_SIZE", 100),
+		ReceiptHeader: getenvDefault("RECEIPT_HEADER", os.Getenv("RESTAURANT_NAME")),
+	}
 }
 
 // Payments returns a copy of the loaded PaymentsConfig.
@@ -37,4 +258,91 @@ func Payments() PaymentsConfig {
 	return paymentsConfig
 }
 
+// Auth returns a copy of the loaded AuthConfig.
+func Auth() AuthConfig {
+	return authConfig
+}
+
+// SMS returns a copy of the loaded SMSConfig.
+func SMS() SMSConfig {
+	return smsConfig
+}
+
+// Branch returns a copy of the loaded BranchConfig.
+func Branch() BranchConfig {
+	return branchConfig
+}
 
+// Guest returns a copy of the loaded GuestConfig.
+func Guest() GuestConfig {
+	return guestConfig
+}
+
+// Reservations returns a copy of the loaded ReservationConfig.
+func Reservations() ReservationConfig {
+	return reservationConfig
+}
+
+// Kitchen returns a copy of the loaded KitchenConfig.
+func Kitchen() KitchenConfig {
+	return kitchenConfig
+}
+
+// Printing returns a copy of the loaded PrintingConfig.
+func Printing() PrintingConfig {
+	return printingConfig
+}
+
+func getenvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getenvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("warning: invalid %s=%q; using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+func getenvBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("warning: invalid %s=%q; using %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+// getenvPositiveInt is getenvInt for settings that must be above zero, such as the
+// intervals background loops tick at.
+func getenvPositiveInt(key string, def int) int {
+	n := getenvInt(key, def)
+	if n <= 0 {
+		log.Printf("warning: %s must be positive, got %d; using %d", key, n, def)
+		return def
+	}
+	return n
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadRejectsNonPositiveAuthLimits(t *testing.T) {
+	for _, value := range []string{"0", "-1"} {
+		t.Setenv("OTP_MAX_ATTEMPTS", value)
+		t.Setenv("OTP_MAX_FAILURES", value)
+		t.Setenv("OTP_LOCKOUT_MINUTES", value)
+		t.Setenv("SESSION_TTL_HOURS", value)
+		t.Setenv("SESSION_REFRESH_WINDOW_MINUTES", value)
+		Load()
+
+		cfg := Auth()
+		if cfg.OTPMaxAttempts != 5 || cfg.OTPMaxFailures != 10 {
+			t.Errorf("%s: OTPMaxAttempts = %d, OTPMaxFailures = %d, want the 5 and 10 defaults", value, cfg.OTPMaxAttempts, cfg.OTPMaxFailures)
+		}
+		if cfg.OTPLockout != 15*time.Minute {
+			t.Errorf("%s: OTPLockout = %v, want the 15m default", value, cfg.OTPLockout)
+		}
+		if cfg.SessionTTL != 24*time.Hour || cfg.SessionRefreshWindow != 2*time.Hour {
+			t.Errorf("%s: SessionTTL = %v, SessionRefreshWindow = %v, want the 24h and 2h defaults", value, cfg.SessionTTL, cfg.SessionRefreshWindow)
+		}
+	}
+}
+
+func TestLoadRejectsNonPositiveIntervals(t *testing.T) {
+	for _, value := range []string{"0", "-5"} {
+		t.Setenv("SESSION_SWEEP_INTERVAL_MINUTES", value)
+		t.Setenv("KITCHEN_MONITOR_INTERVAL_SECONDS", value)
+		t.Setenv("PRINT_POLL_INTERVAL_SECONDS", value)
+		t.Setenv("PRINTER_TIMEOUT_SECONDS", value)
+		Load()
+
+		if got := Auth().SweepInterval; got != 10*time.Minute {
+			t.Errorf("%s: SweepInterval = %v, want the 10m default", value, got)
+		}
+		if got := Kitchen().MonitorInterval; got != time.Minute {
+			t.Errorf("%s: MonitorInterval = %v, want the 1m default", value, got)
+		}
+		if got := Printing().PollInterval; got != 3*time.Second {
+			t.Errorf("%s: PollInterval = %v, want the 3s default", value, got)
+		}
+		if got := Printing().Timeout; got != 5*time.Second {
+			t.Errorf("%s: printer Timeout = %v, want the 5s default", value, got)
+		}
+	}
+}
+
+func TestLoadKeepsPositiveIntervals(t *testing.T) {
+	t.Setenv("SESSION_SWEEP_INTERVAL_MINUTES", "2")
+	t.Setenv("PRINT_POLL_INTERVAL_SECONDS", "7")
+	Load()
+
+	if got := Auth().SweepInterval; got != 2*time.Minute {
+		t.Errorf("SweepInterval = %v, want 2m", got)
+	}
+	if got := Printing().PollInterval; got != 7*time.Second {
+		t.Errorf("PollInterval = %v, want 7s", got)
+	}
+}
@@ -44,6 +44,14 @@ func Initialize() (*DB, error) {
 		return nil, err
 	}
 
+	if err := db.seedModifiers(); err != nil {
+		return nil, err
+	}
+
+	if err := db.seedRoles(); err != nil {
+		return nil, err
+	}
+
 	log.Println("Database initialized successfully")
 	return db, nil
 }
@@ -70,6 +78,8 @@ func (db *DB) createTables() error {
 			available BOOLEAN DEFAULT TRUE,
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
+		`ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT NOW()`,
+		`ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
 		`CREATE TABLE IF NOT EXISTS accounts (
 			id TEXT PRIMARY KEY,
 			phone_number TEXT UNIQUE NOT NULL,
@@ -86,6 +96,29 @@ func (db *DB) createTables() error {
 			updated_at TIMESTAMPTZ DEFAULT NOW(),
 			FOREIGN KEY (customer_id) REFERENCES accounts(id)
 		)`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS allergens TEXT[] NOT NULL DEFAULT '{}'`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS pay_on_delivery BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS branch_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS business_day DATE`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_number INTEGER`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_daily_number ON orders(branch_id, business_day, order_number)`,
+		`CREATE TABLE IF NOT EXISTS order_number_sequences (
+			branch_id TEXT NOT NULL,
+			business_day DATE NOT NULL,
+			last_number INTEGER NOT NULL,
+			PRIMARY KEY (branch_id, business_day)
+		)`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_type TEXT NOT NULL DEFAULT 'takeaway'`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS table_id TEXT`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS guest_count INTEGER`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_at TIMESTAMPTZ`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address TEXT`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS contact_name TEXT`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS contact_phone TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders(customer_id, created_at, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status, created_at)`,
 		`CREATE TABLE IF NOT EXISTS order_items (
 			id TEXT PRIMARY KEY,
 			order_id TEXT NOT NULL,
@@ -97,6 +130,114 @@ func (db *DB) createTables() error {
 			FOREIGN KEY (order_id) REFERENCES orders(id),
 			FOREIGN KEY (menu_item_id) REFERENCES menu_items(id)
 		)`,
+		`CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items(order_id)`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS allergens TEXT[] NOT NULL DEFAULT '{}'`,
+		// Kitchen stations; existing items are routed by category, see models.StationForCategory
+		`ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS station TEXT`,
+		`UPDATE menu_items SET station = CASE category
+			WHEN 'Beverage' THEN 'bar'
+			WHEN 'Salad' THEN 'cold'
+			WHEN 'Dessert' THEN 'cold'
+			ELSE 'grill' END
+		WHERE station IS NULL`,
+		`ALTER TABLE menu_items ALTER COLUMN station SET DEFAULT 'grill'`,
+		`ALTER TABLE menu_items ALTER COLUMN station SET NOT NULL`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS station TEXT`,
+		`UPDATE order_items SET station = menu_items.station FROM menu_items
+		WHERE order_items.menu_item_id = menu_items.id AND order_items.station IS NULL`,
+		`ALTER TABLE order_items ALTER COLUMN station SET NOT NULL`,
+		// Item preparation status; items of orders already through the kitchen are backfilled from the order
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS prep_status TEXT`,
+		`UPDATE order_items SET prep_status = CASE orders.status
+			WHEN 'completed' THEN 'served'
+			WHEN 'ready' THEN 'done'
+			ELSE 'queued' END
+		FROM orders WHERE order_items.order_id = orders.id AND order_items.prep_status IS NULL`,
+		`ALTER TABLE order_items ALTER COLUMN prep_status SET DEFAULT 'queued'`,
+		`ALTER TABLE order_items ALTER COLUMN prep_status SET NOT NULL`,
+		`ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS prep_minutes INTEGER NOT NULL DEFAULT 10`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS prep_minutes INTEGER`,
+		`UPDATE order_items SET prep_minutes = menu_items.prep_minutes FROM menu_items
+		WHERE order_items.menu_item_id = menu_items.id AND order_items.prep_minutes IS NULL`,
+		`ALTER TABLE order_items ALTER COLUMN prep_minutes SET DEFAULT 10`,
+		`ALTER TABLE order_items ALTER COLUMN prep_minutes SET NOT NULL`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS late_alerted_at TIMESTAMPTZ`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS course INTEGER NOT NULL DEFAULT 1`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS fired_at TIMESTAMPTZ`,
+		`CREATE TABLE IF NOT EXISTS printers (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			address TEXT NOT NULL DEFAULT '',
+			stations TEXT[] NOT NULL DEFAULT '{}',
+			receipts BOOLEAN NOT NULL DEFAULT FALSE,
+			line_width INTEGER NOT NULL DEFAULT 48,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		// Items already in the kitchen when ticket printing arrived count as printed;
+		// new items start unprinted
+		`ALTER TABLE order_items ADD COLUMN IF NOT EXISTS printed_at TIMESTAMPTZ DEFAULT NOW()`,
+		`ALTER TABLE order_items ALTER COLUMN printed_at DROP DEFAULT`,
+		`CREATE INDEX IF NOT EXISTS idx_order_items_unprinted ON order_items(order_id) WHERE printed_at IS NULL`,
+		`CREATE TABLE IF NOT EXISTS modifier_groups (
+			id TEXT PRIMARY KEY,
+			menu_item_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			required BOOLEAN NOT NULL DEFAULT FALSE,
+			min_select INTEGER NOT NULL DEFAULT 0,
+			max_select INTEGER NOT NULL DEFAULT 0,
+			position INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_modifier_groups_menu_item_id ON modifier_groups(menu_item_id)`,
+		`CREATE TABLE IF NOT EXISTS modifier_options (
+			id TEXT PRIMARY KEY,
+			group_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			price_delta REAL NOT NULL DEFAULT 0,
+			available BOOLEAN NOT NULL DEFAULT TRUE,
+			position INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY (group_id) REFERENCES modifier_groups(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_modifier_options_group_id ON modifier_options(group_id)`,
+		`CREATE TABLE IF NOT EXISTS order_item_modifiers (
+			id TEXT PRIMARY KEY,
+			order_item_id TEXT NOT NULL,
+			modifier_option_id TEXT NOT NULL,
+			group_name TEXT NOT NULL,
+			option_name TEXT NOT NULL,
+			price_delta REAL NOT NULL DEFAULT 0,
+			FOREIGN KEY (order_item_id) REFERENCES order_items(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_order_item_modifiers_order_item_id ON order_item_modifiers(order_item_id)`,
+		`CREATE TABLE IF NOT EXISTS order_events (
+			id TEXT PRIMARY KEY,
+			order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			from_status TEXT NOT NULL DEFAULT '',
+			to_status TEXT NOT NULL,
+			actor_account_id TEXT,
+			device_id TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_order_events_order_id ON order_events(order_id, created_at)`,
+		`CREATE TABLE IF NOT EXISTS order_cancellations (
+			id TEXT PRIMARY KEY,
+			order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+			reason TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL,
+			requested_by TEXT NOT NULL,
+			decided_by TEXT,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			decided_at TIMESTAMPTZ
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_order_cancellations_pending ON order_cancellations(order_id) WHERE status = 'pending_approval'`,
 		`CREATE TABLE IF NOT EXISTS payments (
 			id TEXT PRIMARY KEY,
 			order_id TEXT NOT NULL,
@@ -109,13 +250,29 @@ func (db *DB) createTables() error {
 			updated_at TIMESTAMPTZ DEFAULT NOW(),
 			FOREIGN KEY (order_id) REFERENCES orders(id)
 		)`,
+		`CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments(order_id)`,
 		`CREATE TABLE IF NOT EXISTS otps (
 			id TEXT PRIMARY KEY,
 			phone_number TEXT NOT NULL,
-			code TEXT NOT NULL,
+			code_hash TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
 			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
+		// OTP codes used to be stored in plaintext; legacy rows get an empty hash and can never verify
+		`ALTER TABLE otps DROP COLUMN IF EXISTS code`,
+		`ALTER TABLE otps ADD COLUMN IF NOT EXISTS code_hash TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE otps ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE otps ADD COLUMN IF NOT EXISTS used_at TIMESTAMPTZ`,
+		`CREATE INDEX IF NOT EXISTS idx_otps_phone_number ON otps(phone_number, created_at)`,
+		`CREATE TABLE IF NOT EXISTS auth_throttles (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			locked_until TIMESTAMPTZ,
+			last_request_at TIMESTAMPTZ,
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
 		`CREATE TABLE IF NOT EXISTS sessions (
 			id TEXT PRIMARY KEY,
 			account_id TEXT NOT NULL,
@@ -125,11 +282,140 @@ func (db *DB) createTables() error {
 			created_at TIMESTAMPTZ DEFAULT NOW(),
 			FOREIGN KEY (account_id) REFERENCES accounts(id)
 		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_account_id ON sessions(account_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at)`,
 		`CREATE TABLE IF NOT EXISTS authorized_devices (
 			device_id TEXT PRIMARY KEY,
 			name TEXT,
 			registered_at TIMESTAMPTZ DEFAULT NOW()
 		)`,
+		`ALTER TABLE authorized_devices ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE authorized_devices ADD COLUMN IF NOT EXISTS registered_by TEXT`,
+		`ALTER TABLE authorized_devices ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ`,
+		`CREATE TABLE IF NOT EXISTS device_pairings (
+			code TEXT PRIMARY KEY,
+			device_id TEXT NOT NULL,
+			name TEXT,
+			status TEXT NOT NULL DEFAULT 'pending',
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			approved_by TEXT,
+			approved_at TIMESTAMPTZ
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_device_pairings_device_id ON device_pairings(device_id)`,
+		`ALTER TABLE device_pairings ADD COLUMN IF NOT EXISTS requested_ip TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_device_pairings_requested_ip ON device_pairings(requested_ip, created_at)`,
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+			account_id TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			key TEXT NOT NULL,
+			request_hash TEXT NOT NULL,
+			status TEXT NOT NULL,
+			response_status INTEGER,
+			response_body BYTEA,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (account_id, scope, key)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at)`,
+		`CREATE TABLE IF NOT EXISTS areas (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			sort_order INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE TABLE IF NOT EXISTS dining_tables (
+			id TEXT PRIMARY KEY,
+			area_id TEXT NOT NULL REFERENCES areas(id),
+			name TEXT NOT NULL,
+			capacity INTEGER NOT NULL,
+			pos_x REAL NOT NULL DEFAULT 0,
+			pos_y REAL NOT NULL DEFAULT 0,
+			width REAL NOT NULL DEFAULT 1,
+			height REAL NOT NULL DEFAULT 1,
+			state TEXT NOT NULL DEFAULT 'free',
+			occupied_since TIMESTAMPTZ,
+			state_changed_at TIMESTAMPTZ DEFAULT NOW(),
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			deleted_at TIMESTAMPTZ
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_dining_tables_name ON dining_tables(area_id, name) WHERE deleted_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_table_id ON orders(table_id, created_at) WHERE table_id IS NOT NULL`,
+		`ALTER TABLE dining_tables ADD COLUMN IF NOT EXISTS qr_key TEXT`,
+		`UPDATE dining_tables SET qr_key = REPLACE(gen_random_uuid()::text, '-', '') WHERE qr_key IS NULL`,
+		`CREATE TABLE IF NOT EXISTS table_tokens (
+			id TEXT PRIMARY KEY,
+			token TEXT UNIQUE NOT NULL,
+			table_id TEXT NOT NULL REFERENCES dining_tables(id),
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_table_tokens_expires_at ON table_tokens(expires_at)`,
+		`ALTER TABLE orders ALTER COLUMN customer_id DROP NOT NULL`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'app'`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS table_token_id TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_orders_table_token_id ON orders(table_token_id, created_at) WHERE table_token_id IS NOT NULL`,
+		// Guest table orders used to be marked pay on delivery to skip the payment check
+		`UPDATE orders SET pay_on_delivery = FALSE WHERE source = 'qr' AND pay_on_delivery`,
+		`CREATE TABLE IF NOT EXISTS reservations (
+			id TEXT PRIMARY KEY,
+			account_id TEXT NOT NULL REFERENCES accounts(id),
+			previous_visits INTEGER NOT NULL DEFAULT 0,
+			phone_number TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			party_size INTEGER NOT NULL,
+			reserved_at TIMESTAMPTZ NOT NULL,
+			ends_at TIMESTAMPTZ NOT NULL,
+			table_id TEXT NOT NULL REFERENCES dining_tables(id),
+			notes TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'booked',
+			locale TEXT NOT NULL DEFAULT '',
+			created_by TEXT,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reservations_table_time ON reservations(table_id, reserved_at, ends_at) WHERE status IN ('booked', 'seated')`,
+		`CREATE INDEX IF NOT EXISTS idx_reservations_reserved_at ON reservations(reserved_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_reservations_account_id ON reservations(account_id)`,
+		`CREATE TABLE IF NOT EXISTS waitlist_entries (
+			id TEXT PRIMARY KEY,
+			account_id TEXT NOT NULL REFERENCES accounts(id),
+			previous_visits INTEGER NOT NULL DEFAULT 0,
+			phone_number TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			party_size INTEGER NOT NULL,
+			notes TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'waiting',
+			locale TEXT NOT NULL DEFAULT '',
+			quoted_wait_minutes INTEGER NOT NULL DEFAULT 0,
+			table_id TEXT REFERENCES dining_tables(id),
+			notified_at TIMESTAMPTZ,
+			seated_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_waitlist_entries_open ON waitlist_entries(created_at) WHERE status IN ('waiting', 'notified')`,
+		`CREATE TABLE IF NOT EXISTS roles (
+			name TEXT PRIMARY KEY,
+			description TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS role_permissions (
+			role TEXT NOT NULL,
+			permission TEXT NOT NULL,
+			PRIMARY KEY (role, permission),
+			FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS account_roles (
+			account_id TEXT NOT NULL,
+			role TEXT NOT NULL,
+			granted_by TEXT,
+			granted_at TIMESTAMPTZ DEFAULT NOW(),
+			PRIMARY KEY (account_id, role),
+			FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+			FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE
+		)`,
 	}
 
 	for _, query := range queries {
@@ -159,21 +445,23 @@ func (db *DB) seedMenuItems() error {
 		description string
 		price       float64
 		category    string
+		station     string
+		prepMinutes int
 	}{
-		{"item-1", "Burger Deluxe", "Juicy beef patty with fresh vegetables", 15.99, "Main Course"},
-		{"item-2", "Chicken Wings", "Spicy buffalo wings with ranch dip", 12.99, "Appetizer"},
-		{"item-3", "Caesar Salad", "Fresh romaine lettuce with caesar dressing", 8.99, "Salad"},
-		{"item-4", "Pizza Margherita", "Classic pizza with tomato and mozzarella", 18.99, "Main Course"},
-		{"item-5", "Fish & Chips", "Beer-battered fish with crispy fries", 16.99, "Main Course"},
-		{"item-6", "Chocolate Cake", "Rich chocolate cake with vanilla ice cream", 6.99, "Dessert"},
-		{"item-7", "Fresh Juice", "Orange, apple, or mixed fruit juice", 4.99, "Beverage"},
-		{"item-8", "Coffee", "Freshly brewed coffee", 3.99, "Beverage"},
+		{"item-1", "Burger Deluxe", "Juicy beef patty with fresh vegetables", 15.99, "Main Course", "grill", 12},
+		{"item-2", "Chicken Wings", "Spicy buffalo wings with ranch dip", 12.99, "Appetizer", "fryer", 10},
+		{"item-3", "Caesar Salad", "Fresh romaine lettuce with caesar dressing", 8.99, "Salad", "cold", 5},
+		{"item-4", "Pizza Margherita", "Classic pizza with tomato and mozzarella", 18.99, "Main Course", "grill", 15},
+		{"item-5", "Fish & Chips", "Beer-battered fish with crispy fries", 16.99, "Main Course", "fryer", 12},
+		{"item-6", "Chocolate Cake", "Rich chocolate cake with vanilla ice cream", 6.99, "Dessert", "cold", 3},
+		{"item-7", "Fresh Juice", "Orange, apple, or mixed fruit juice", 4.99, "Beverage", "bar", 3},
+		{"item-8", "Coffee", "Freshly brewed coffee", 3.99, "Beverage", "bar", 2},
 	}
 
 	for _, item := range menuItems {
 		_, err := db.conn.Exec(
-			"INSERT INTO menu_items (id, name, description, price, category) VALUES ($1, $2, $3, $4, $5)",
-			item.id, item.name, item.description, item.price, item.category,
+			"INSERT INTO menu_items (id, name, description, price, category, station, prep_minutes) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			item.id, item.name, item.description, item.price, item.category, item.station, item.prepMinutes,
 		)
 		if err != nil {
 			return err
@@ -184,40 +472,101 @@ func (db *DB) seedMenuItems() error {
 	return nil
 }
 
-// Helper to insert order items
-func (db *DB) StoreOrderItems(orderID string, items []models.OrderItem) error {
-	for _, item := range items {
-		_, err := db.conn.Exec(
-			"INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-			item.ID, item.OrderID, item.MenuItemID, item.Name, item.Price, item.Quantity, item.TotalPrice,
-		)
-		if err != nil {
+func (db *DB) seedModifiers() error {
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM modifier_groups").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil // Already seeded
+	}
+
+	type seedOption struct {
+		id, name   string
+		priceDelta float64
+	}
+	groups := []struct {
+		id, menuItemID, name string
+		required             bool
+		minSelect, maxSelect int
+		options              []seedOption
+	}{
+		{"mod-juice-fruit", "item-7", "Fruit", true, 1, 1, []seedOption{
+			{"opt-juice-orange", "Orange", 0},
+			{"opt-juice-apple", "Apple", 0},
+			{"opt-juice-mixed", "Mixed fruit", 0.50},
+		}},
+		{"mod-burger-extras", "item-1", "Extras", false, 0, 3, []seedOption{
+			{"opt-burger-cheese", "Extra cheese", 1.00},
+			{"opt-burger-bacon", "Bacon", 1.50},
+			{"opt-burger-egg", "Fried egg", 1.00},
+		}},
+		{"mod-burger-remove", "item-1", "Remove", false, 0, 0, []seedOption{
+			{"opt-burger-no-onions", "No onions", 0},
+			{"opt-burger-no-tomato", "No tomato", 0},
+		}},
+	}
+
+	for gi, group := range groups {
+		// Skip groups whose menu item was removed from the seed data
+		var exists int
+		if err := db.conn.QueryRow("SELECT 1 FROM menu_items WHERE id = $1", group.menuItemID).Scan(&exists); err != nil {
+			continue
+		}
+		if _, err := db.conn.Exec(
+			"INSERT INTO modifier_groups (id, menu_item_id, name, required, min_select, max_select, position) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			group.id, group.menuItemID, group.name, group.required, group.minSelect, group.maxSelect, gi,
+		); err != nil {
 			return err
 		}
+		for oi, option := range group.options {
+			if _, err := db.conn.Exec(
+				"INSERT INTO modifier_options (id, group_id, name, price_delta, position) VALUES ($1, $2, $3, $4, $5)",
+				option.id, group.id, option.name, option.priceDelta, oi,
+			); err != nil {
+				return err
+			}
+		}
 	}
+
+	log.Println("Menu modifiers seeded successfully")
 	return nil
 }
 
-// Helper to retrieve order items
-func (db *DB) GetOrderItems(orderID string) ([]models.OrderItem, error) {
-	rows, err := db.conn.Query(
-		"SELECT id, order_id, menu_item_id, name, price, quantity, total_price FROM order_items WHERE order_id = $1",
-		orderID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+var roleDescriptions = map[models.Role]string{
+	models.RoleCustomer: "Places and pays for their own orders",
+	models.RoleCashier:  "Records payments and manages orders at the register",
+	models.RoleWaiter:   "Serves tables and confirms orders guests place from them",
+	models.RoleKitchen:  "Works the kitchen queue",
+	models.RoleManager:  "Supervises front of house and kitchen",
+	models.RoleAdmin:    "Full access, including role management",
+}
 
-	var items []models.OrderItem
-	for rows.Next() {
-		var item models.OrderItem
-		err := rows.Scan(&item.ID, &item.OrderID, &item.MenuItemID, &item.Name, &item.Price, &item.Quantity, &item.TotalPrice)
-		if err != nil {
-			return nil, err
+// seedRoles makes sure every built-in role and its default permissions exist.
+// Accounts created before roles existed are backfilled as customers.
+func (db *DB) seedRoles() error {
+	for _, role := range models.AllRoles {
+		if _, err := db.conn.Exec(
+			"INSERT INTO roles (name, description) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING",
+			role, roleDescriptions[role],
+		); err != nil {
+			return err
+		}
+		for _, perm := range models.DefaultRolePermissions[role] {
+			if _, err := db.conn.Exec(
+				"INSERT INTO role_permissions (role, permission) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				role, perm,
+			); err != nil {
+				return err
+			}
 		}
-		items = append(items, item)
 	}
 
-	return items, nil
+	_, err := db.conn.Exec(
+		`INSERT INTO account_roles (account_id, role)
+		SELECT a.id, $1 FROM accounts a
+		WHERE NOT EXISTS (SELECT 1 FROM account_roles ar WHERE ar.account_id = a.id)`,
+		models.RoleCustomer,
+	)
+	return err
 }
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"database/sql"
+	"restaurant-system/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// OrderColumns is the column list ScanOrder expects, in order.
+// Orders placed before daily numbering have no number or business day, and guest
+// orders have no customer.
+const OrderColumns = "id, COALESCE(order_number, 0), branch_id, COALESCE(TO_CHAR(business_day, 'YYYY-MM-DD'), ''), COALESCE(customer_id, ''), source, " +
+	"order_type, COALESCE(table_id, ''), COALESCE(guest_count, 0), pickup_at, COALESCE(delivery_address, ''), COALESCE(contact_name, ''), COALESCE(contact_phone, ''), " +
+	"total_amount, status, notes, allergens, pay_on_delivery, created_at, updated_at"
+
+// Scanner is implemented by *sql.Row and *sql.Rows.
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ScanOrder scans a row selected with OrderColumns. Items are not loaded.
+func ScanOrder(row Scanner) (*models.Order, error) {
+	var order models.Order
+	var allergens pq.StringArray
+	var pickupAt sql.NullTime
+	err := row.Scan(&order.ID, &order.OrderNumber, &order.BranchID, &order.BusinessDay, &order.CustomerID, &order.Source,
+		&order.Type, &order.TableID, &order.GuestCount, &pickupAt, &order.DeliveryAddress, &order.ContactName, &order.ContactPhone,
+		&order.TotalAmount, &order.Status, &order.Notes, &allergens, &order.PayOnDelivery, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	order.Allergens = models.AllergensFromStrings(allergens)
+	if pickupAt.Valid {
+		order.PickupAt = &pickupAt.Time
+	}
+	if order.OrderNumber > 0 {
+		order.DisplayNumber = models.FormatOrderNumber(order.OrderNumber)
+	}
+	return &order, nil
+}
+
+// StoreOrderItems inserts order items and their modifiers.
+func StoreOrderItems(q Queryer, items []models.OrderItem) error {
+	for _, item := range items {
+		_, err := q.Exec(
+			"INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price, notes, allergens, station, prep_minutes, course, held) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+			item.ID, item.OrderID, item.MenuItemID, item.Name, item.Price, item.Quantity, item.TotalPrice, item.Notes, pq.Array(models.AllergenStrings(item.Allergens)), item.Station, item.PrepMinutes, item.Course, item.Held,
+		)
+		if err != nil {
+			return err
+		}
+		for _, mod := range item.Modifiers {
+			_, err := q.Exec(
+				"INSERT INTO order_item_modifiers (id, order_item_id, modifier_option_id, group_name, option_name, price_delta) VALUES ($1, $2, $3, $4, $5, $6)",
+				mod.ID, item.ID, mod.ModifierOptionID, mod.GroupName, mod.OptionName, mod.PriceDelta,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Helper to retrieve order items
+func (db *DB) GetOrderItems(orderID string) ([]models.OrderItem, error) {
+	items, err := db.getOrderItems([]string{orderID})
+	if err != nil {
+		return nil, err
+	}
+	return items[orderID], nil
+}
+
+// LoadOrderItems fills in the items of every order with two queries in total,
+// however many orders there are.
+func (db *DB) LoadOrderItems(orders []*models.Order) error {
+	orderIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderIDs[i] = order.ID
+	}
+	items, err := db.getOrderItems(orderIDs)
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		order.Items = items[order.ID]
+	}
+	return nil
+}
+
+// getOrderItems loads the items of the given orders with their modifiers, keyed by order ID.
+func (db *DB) getOrderItems(orderIDs []string) (map[string][]models.OrderItem, error) {
+	items := map[string][]models.OrderItem{}
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT id, order_id, menu_item_id, name, price, quantity, total_price, notes, allergens, station, prep_status, prep_minutes, course, held, fired_at FROM order_items WHERE order_id = ANY($1) ORDER BY course",
+		pq.Array(orderIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itemIDs []string
+	for rows.Next() {
+		var item models.OrderItem
+		var allergens pq.StringArray
+		err := rows.Scan(&item.ID, &item.OrderID, &item.MenuItemID, &item.Name, &item.Price, &item.Quantity, &item.TotalPrice, &item.Notes, &allergens, &item.Station, &item.PrepStatus, &item.PrepMinutes, &item.Course, &item.Held, &item.FiredAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Allergens = models.AllergensFromStrings(allergens)
+		items[item.OrderID] = append(items[item.OrderID], item)
+		itemIDs = append(itemIDs, item.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	modifiers, err := db.getOrderItemModifiers(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for orderID := range items {
+		for i := range items[orderID] {
+			items[orderID][i].Modifiers = modifiers[items[orderID][i].ID]
+		}
+	}
+
+	return items, nil
+}
+
+// getOrderItemModifiers loads the chosen modifiers for the given order items, keyed by order item ID.
+func (db *DB) getOrderItemModifiers(orderItemIDs []string) (map[string][]models.OrderItemModifier, error) {
+	modifiers := map[string][]models.OrderItemModifier{}
+	if len(orderItemIDs) == 0 {
+		return modifiers, nil
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT id, order_item_id, modifier_option_id, group_name, option_name, price_delta FROM order_item_modifiers WHERE order_item_id = ANY($1) ORDER BY group_name, option_name",
+		pq.Array(orderItemIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mod models.OrderItemModifier
+		if err := rows.Scan(&mod.ID, &mod.OrderItemID, &mod.ModifierOptionID, &mod.GroupName, &mod.OptionName, &mod.PriceDelta); err != nil {
+			return nil, err
+		}
+		modifiers[mod.OrderItemID] = append(modifiers[mod.OrderItemID], mod)
+	}
+
+	return modifiers, rows.Err()
+}
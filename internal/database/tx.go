@@ -0,0 +1,34 @@
+package database
+
+import "database/sql"
+
+// Queryer is satisfied by both *sql.DB and *sql.Tx, so helpers can run inside
+// or outside a transaction.
+type Queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// WithTx runs fn in a transaction. The transaction is committed if fn returns nil
+// and rolled back if it returns an error or panics.
+func (db *DB) WithTx(fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(tx)
+}
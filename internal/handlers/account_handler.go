@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"restaurant-system/internal/middleware"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 
@@ -44,6 +45,16 @@ func (h *AccountHandler) GetAccountBalance(c *gin.Context) {
 		return
 	}
 
+	// Callers may only read their own balance; "me" is accepted as an alias.
+	current := middleware.CurrentAccount(c)
+	if accountID == "me" {
+		accountID = current.ID
+	}
+	if accountID != current.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access to this account is not allowed"})
+		return
+	}
+
 	balance, err := h.accountService.GetAccountBalance(accountID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+	"restaurant-system/internal/middleware"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 
@@ -23,7 +27,7 @@ func (h *AuthHandler) RequestOTP(c *gin.Context) {
 		return
 	}
 	if err := h.authService.RequestOTP(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondAuthError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "OTP sent"})
@@ -37,9 +41,79 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	}
 	token, err := h.authService.VerifyOTP(&req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondAuthError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
 
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.authService.Logout(middleware.CurrentSession(c).ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	session := middleware.CurrentSession(c)
+	sessions, err := h.authService.ListSessions(session.AccountID, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID := c.Param("id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session ID is required"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(middleware.CurrentAccount(c).ID, sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+func (h *AuthHandler) RefreshSession(c *gin.Context) {
+	session, err := h.authService.RefreshSession(middleware.CurrentSession(c))
+	if errors.Is(err, services.ErrInvalidSession) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.RefreshSessionResponse{Token: session.Token, ExpiresAt: session.ExpiresAt})
+}
+
+// respondAuthError maps OTP flow errors to a status code and a body carrying a
+// machine-readable code, plus retry_after (seconds) for throttled requests.
+func respondAuthError(c *gin.Context, err error) {
+	var authErr *services.AuthError
+	if !errors.As(err, &authErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusBadRequest
+	body := gin.H{"error": authErr.Message, "code": authErr.Code}
+	switch authErr.Code {
+	case services.AuthErrUnauthorizedDevice:
+		status = http.StatusForbidden
+	case services.AuthErrOTPCooldown, services.AuthErrOTPLocked:
+		status = http.StatusTooManyRequests
+		retryAfter := int((authErr.RetryAfter + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		body["retry_after"] = retryAfter
+	case services.AuthErrOTPInvalid:
+		body["attempts_remaining"] = authErr.AttemptsRemaining
+	}
+
+	c.JSON(status, body)
+}
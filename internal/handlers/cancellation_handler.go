@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CancellationHandler struct {
+	cancellationService *services.CancellationService
+	orderService        *services.OrderService
+	tableService        *services.TableService
+	hub                 *websocket.Hub
+}
+
+func NewCancellationHandler(cancellationService *services.CancellationService, orderService *services.OrderService, tableService *services.TableService, hub *websocket.Hub) *CancellationHandler {
+	return &CancellationHandler{
+		cancellationService: cancellationService,
+		orderService:        orderService,
+		tableService:        tableService,
+		hub:                 hub,
+	}
+}
+
+func (h *CancellationHandler) CancelOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var req models.CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cancellation, err := h.cancellationService.CancelOrder(orderID, &req, middleware.CurrentActor(c))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	if cancellation.Status == models.CancellationPendingApproval {
+		h.hub.BroadcastToKitchen(gin.H{
+			"type": "cancellation_requested",
+			"data": cancellation,
+		})
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":      "Cancellation is waiting for manager approval",
+			"cancellation": cancellation,
+		})
+		return
+	}
+
+	order := h.notifyCancelled(cancellation)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Order cancelled successfully",
+		"order":        order,
+		"cancellation": cancellation,
+	})
+}
+
+func (h *CancellationHandler) ListCancellations(c *gin.Context) {
+	cancellations, err := h.cancellationService.ListCancellations(models.CancellationStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cancellations": cancellations})
+}
+
+func (h *CancellationHandler) ApproveCancellation(c *gin.Context) {
+	cancellation, err := h.cancellationService.ApproveCancellation(c.Param("id"), middleware.CurrentActor(c))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	order := h.notifyCancelled(cancellation)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Cancellation approved successfully",
+		"order":        order,
+		"cancellation": cancellation,
+	})
+}
+
+func (h *CancellationHandler) RejectCancellation(c *gin.Context) {
+	cancellation, err := h.cancellationService.RejectCancellation(c.Param("id"), middleware.CurrentActor(c))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	h.hub.BroadcastToKitchen(gin.H{
+		"type": "cancellation_rejected",
+		"data": cancellation,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Cancellation rejected successfully",
+		"cancellation": cancellation,
+	})
+}
+
+// notifyCancelled tells the kitchen to stop work on a cancelled order, and front of
+// house and the customer that its status changed. It returns the cancelled order, or nil if it could
+// not be reloaded.
+func (h *CancellationHandler) notifyCancelled(cancellation *models.OrderCancellation) *models.Order {
+	h.tableService.SyncOrderTable(cancellation.OrderID)
+
+	order, err := h.orderService.GetOrder(cancellation.OrderID)
+	if err != nil {
+		return nil
+	}
+
+	h.hub.BroadcastToKitchen(gin.H{
+		"type":         "order_cancelled",
+		"data":         order.KitchenView(),
+		"order_number": order.DisplayNumber,
+		"reason":       cancellation.Reason,
+		"note":         cancellation.Note,
+	})
+	broadcastTickets(h.hub, "ticket_cancelled", order)
+	notifyOrderStatus(h.hub, order)
+	return order
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeviceHandler struct {
+	deviceService *services.DeviceService
+}
+
+func NewDeviceHandler(deviceService *services.DeviceService) *DeviceHandler {
+	return &DeviceHandler{deviceService: deviceService}
+}
+
+func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
+	var req models.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.deviceService.RegisterDevice(&req, middleware.CurrentAccount(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Device registered successfully",
+		"device":  device,
+	})
+}
+
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	devices, err := h.deviceService.ListDevices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
+	deviceID := c.Param("id")
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device ID is required"})
+		return
+	}
+
+	var req models.UpdateDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.deviceService.UpdateDevice(deviceID, &req)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"device": device})
+}
+
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	deviceID := c.Param("id")
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device ID is required"})
+		return
+	}
+
+	if err := h.deviceService.DeleteDevice(deviceID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device deleted successfully"})
+}
+
+// StartPairing is called by an unregistered device; it returns the code to show a manager.
+func (h *DeviceHandler) StartPairing(c *gin.Context) {
+	var req models.StartPairingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pairing, err := h.deviceService.StartPairing(&req, c.ClientIP())
+	if errors.Is(err, services.ErrPairingThrottled) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pairing": pairing})
+}
+
+// GetPairingStatus lets a device poll whether its pairing code has been approved.
+func (h *DeviceHandler) GetPairingStatus(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "device ID is required"})
+		return
+	}
+
+	pairing, err := h.deviceService.GetPairingStatus(deviceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The code is only for the device's own screen; don't echo it to pollers
+	pairing.Code = ""
+	c.JSON(http.StatusOK, gin.H{"pairing": pairing})
+}
+
+func (h *DeviceHandler) ListPendingPairings(c *gin.Context) {
+	pairings, err := h.deviceService.ListPendingPairings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pairings": pairings})
+}
+
+func (h *DeviceHandler) ApprovePairing(c *gin.Context) {
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pairing code is required"})
+		return
+	}
+
+	var req models.ApprovePairingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	device, err := h.deviceService.ApprovePairing(code, &req, middleware.CurrentAccount(c).ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device approved",
+		"device":  device,
+	})
+}
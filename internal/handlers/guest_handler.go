@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GuestHandler serves guests who order from their table by scanning its QR code.
+type GuestHandler struct {
+	guestService *services.GuestService
+	tableService *services.TableService
+	hub          *websocket.Hub
+}
+
+func NewGuestHandler(guestService *services.GuestService, tableService *services.TableService, hub *websocket.Hub) *GuestHandler {
+	return &GuestHandler{
+		guestService: guestService,
+		tableService: tableService,
+		hub:          hub,
+	}
+}
+
+// StartSession trades the table ID and key from a QR code for a table token.
+func (h *GuestHandler) StartSession(c *gin.Context) {
+	var req models.StartGuestSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.guestService.StartSession(req.TableID, req.Key)
+	switch {
+	case errors.Is(err, services.ErrTableNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidTableKey):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "invalid_table_key"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start table session"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Table session started successfully",
+		"session": session,
+	})
+}
+
+// CreateOrder places an order for the guest's table and asks front of house staff
+// to confirm it. The kitchen only sees it once it is confirmed.
+func (h *GuestHandler) CreateOrder(c *gin.Context) {
+	var req models.GuestOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := middleware.CurrentTableToken(c)
+	order, err := h.guestService.CreateOrder(token, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.tableService.SyncOrderTable(order.ID)
+
+	order.BuildAlerts()
+	h.hub.BroadcastToStaff(gin.H{
+		"type":          "guest_order_placed",
+		"data":          order,
+		"order_number":  order.DisplayNumber,
+		"table_id":      order.TableID,
+		"alerts":        order.Alerts,
+		"allergy_alert": order.AllergyAlert,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order created successfully",
+		"order":   order,
+	})
+}
+
+// GetOrders lists the orders placed with the guest's table token.
+func (h *GuestHandler) GetOrders(c *gin.Context) {
+	orders, err := h.guestService.ListOrders(middleware.CurrentTableToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+func (h *GuestHandler) GetOrder(c *gin.Context) {
+	order, err := h.guestService.GetOrder(middleware.CurrentTableToken(c), c.Param("id"))
+	if errors.Is(err, services.ErrOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
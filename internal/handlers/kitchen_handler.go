@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"restaurant-system/internal/middleware"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 	"restaurant-system/internal/websocket"
@@ -11,12 +13,14 @@ import (
 
 type KitchenHandler struct {
 	kitchenService *services.KitchenService
+	tableService   *services.TableService
 	hub            *websocket.Hub
 }
 
-func NewKitchenHandler(kitchenService *services.KitchenService, hub *websocket.Hub) *KitchenHandler {
+func NewKitchenHandler(kitchenService *services.KitchenService, tableService *services.TableService, hub *websocket.Hub) *KitchenHandler {
 	return &KitchenHandler{
 		kitchenService: kitchenService,
+		tableService:   tableService,
 		hub:            hub,
 	}
 }
@@ -28,9 +32,33 @@ func (h *KitchenHandler) GetPendingOrders(c *gin.Context) {
 		return
 	}
 
+	// The pass can split the queue into dine-in, takeaway and delivery lanes
+	if c.Query("group_by") == "type" {
+		c.JSON(http.StatusOK, gin.H{"groups": models.GroupOrdersByType(orders)})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"orders": orders})
 }
 
+// GetStationTickets lists one station's share of the orders in the kitchen.
+func (h *KitchenHandler) GetStationTickets(c *gin.Context) {
+	tickets, err := h.kitchenService.GetStationTickets(models.Station(c.Param("station")))
+	if errors.Is(err, services.ErrInvalidStation) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"station": c.Param("station"),
+		"tickets": tickets,
+	})
+}
+
 func (h *KitchenHandler) UpdateOrderStatus(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -44,12 +72,14 @@ func (h *KitchenHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	err := h.kitchenService.UpdateOrderStatus(orderID, req.Status)
+	err := h.kitchenService.UpdateOrderStatus(orderID, req.Status, middleware.CurrentActor(c), req.Reason)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
+	h.tableService.SyncOrderTable(orderID)
+
 	// Get updated order
 	order, err := h.kitchenService.GetOrderDetails(orderID)
 	if err != nil {
@@ -58,10 +88,7 @@ func (h *KitchenHandler) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	// Notify all connected clients about status update
-	h.hub.Broadcast(gin.H{
-		"type": "order_status_updated",
-		"data": order,
-	})
+	broadcastOrderStatus(h.hub, order)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Order status updated successfully",
@@ -69,6 +96,60 @@ func (h *KitchenHandler) UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
+// BumpItem moves an order item on: queued to cooking, cooking to done and done to
+// served. The order becomes ready once every item is done.
+func (h *KitchenHandler) BumpItem(c *gin.Context) {
+	change, err := h.kitchenService.BumpItem(c.Param("id"), middleware.CurrentActor(c))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	h.notifyItemChanged(change)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item bumped successfully",
+		"item":    change.Item,
+		"order":   change.Order,
+	})
+}
+
+// RecallItem moves an order item back a step, e.g. when a dish has to be redone.
+func (h *KitchenHandler) RecallItem(c *gin.Context) {
+	change, err := h.kitchenService.RecallItem(c.Param("id"), middleware.CurrentActor(c))
+	if err != nil {
+		respondOrderError(c, err)
+		return
+	}
+
+	h.notifyItemChanged(change)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item recalled successfully",
+		"item":    change.Item,
+		"order":   change.Order,
+	})
+}
+
+// notifyItemChanged tells the kitchen and front of house about the item, its station
+// about its ticket and, when the item moved the order on, everyone about the order.
+func (h *KitchenHandler) notifyItemChanged(change *models.ItemPrepChange) {
+	order := change.Order
+	message := gin.H{
+		"type":         "order_item_updated",
+		"data":         change.Item,
+		"order_id":     order.ID,
+		"order_number": order.DisplayNumber,
+		"order_status": order.Status,
+	}
+	h.hub.BroadcastToRoles(message, string(models.RoleKitchen), "staff")
+	h.hub.BroadcastToStation(string(change.Item.StationOf()), message)
+
+	if change.PreviousOrderStatus == "" {
+		return
+	}
+	h.tableService.SyncOrderTable(order.ID)
+	broadcastOrderStatus(h.hub, order)
+}
+
 func (h *KitchenHandler) GetOrderDetails(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -82,5 +163,59 @@ func (h *KitchenHandler) GetOrderDetails(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"order": order})
+	c.JSON(http.StatusOK, gin.H{"order": order.KitchenView()})
+}
+
+// NotifyOrderLate alerts the kitchen and front of house that an order has gone past
+// its target ready time. It is called by the late order monitor.
+func (h *KitchenHandler) NotifyOrderLate(late *models.LateOrder) {
+	h.hub.BroadcastToRoles(gin.H{
+		"type":         "order_late",
+		"data":         late,
+		"order_number": late.DisplayNumber,
+	}, string(models.RoleKitchen), "staff")
+}
+
+// broadcastOrderStatus tells the kitchen, front of house and the customer about an
+// order's new status, and each station screen about its ticket. The kitchen gets the
+// order without its held courses. Stations get their tickets as new once the order is
+// confirmed, since it only goes to the line then.
+func broadcastOrderStatus(hub *websocket.Hub, order *models.Order) {
+	hub.BroadcastToKitchen(gin.H{
+		"type":         "order_status_updated",
+		"data":         order.KitchenView(),
+		"order_number": order.DisplayNumber,
+	})
+	notifyOrderStatus(hub, order)
+	if order.Status == models.OrderStatusConfirmed {
+		broadcastTickets(hub, "new_ticket", order)
+	} else {
+		broadcastTickets(hub, "ticket_updated", order)
+	}
+}
+
+// notifyOrderStatus sends front of house the whole order and its customer, if any,
+// only the new status: delivery details and allergies stay with staff.
+func notifyOrderStatus(hub *websocket.Hub, order *models.Order) {
+	hub.BroadcastToStaff(gin.H{
+		"type":         "order_status_updated",
+		"data":         order,
+		"order_number": order.DisplayNumber,
+	})
+	hub.SendToAccount(order.CustomerID, gin.H{
+		"type":         "order_status_updated",
+		"data":         order.StatusUpdate(),
+		"order_number": order.DisplayNumber,
+	})
+}
+
+// broadcastTickets sends each station screen its share of the order.
+func broadcastTickets(hub *websocket.Hub, eventType string, order *models.Order) {
+	for _, ticket := range order.StationTickets() {
+		hub.BroadcastToStation(string(ticket.Station), gin.H{
+			"type":         eventType,
+			"data":         ticket,
+			"order_number": order.DisplayNumber,
+		})
+	}
 }
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MenuHandler struct {
+	menuService *services.MenuService
+	hub         *websocket.Hub
+}
+
+func NewMenuHandler(menuService *services.MenuService, hub *websocket.Hub) *MenuHandler {
+	return &MenuHandler{
+		menuService: menuService,
+		hub:         hub,
+	}
+}
+
+// GetMenu returns the menu grouped by category. ?available=false lists sold-out
+// items and ?available=all lists everything; by default only available items are shown.
+func (h *MenuHandler) GetMenu(c *gin.Context) {
+	availability := services.MenuAvailable
+	switch c.Query("available") {
+	case "", "true":
+	case "false":
+		availability = services.MenuUnavailable
+	case "all":
+		availability = services.MenuAll
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "available must be true, false or all"})
+		return
+	}
+
+	categories, err := h.menuService.GetMenu(availability)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+func (h *MenuHandler) GetMenuItem(c *gin.Context) {
+	item, err := h.menuService.GetMenuItem(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"menu_item": item})
+}
+
+func (h *MenuHandler) CreateMenuItem(c *gin.Context) {
+	var req models.CreateMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.menuService.CreateMenuItem(&req)
+	if errors.Is(err, services.ErrInvalidStation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("created", item.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Menu item created successfully",
+		"menu_item": item,
+	})
+}
+
+func (h *MenuHandler) UpdateMenuItem(c *gin.Context) {
+	itemID := c.Param("id")
+
+	var req models.UpdateMenuItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.menuService.UpdateMenuItem(itemID, &req)
+	if errors.Is(err, services.ErrInvalidStation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("updated", item.ID)
+
+	c.JSON(http.StatusOK, gin.H{"menu_item": item})
+}
+
+func (h *MenuHandler) SetAvailability(c *gin.Context) {
+	itemID := c.Param("id")
+
+	var req models.SetAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.menuService.SetAvailability(itemID, *req.Available)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("availability_changed", item.ID)
+
+	c.JSON(http.StatusOK, gin.H{"menu_item": item})
+}
+
+func (h *MenuHandler) DeleteMenuItem(c *gin.Context) {
+	itemID := c.Param("id")
+
+	if err := h.menuService.DeleteMenuItem(itemID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("deleted", itemID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Menu item deleted successfully"})
+}
+
+func (h *MenuHandler) CreateModifierGroup(c *gin.Context) {
+	itemID := c.Param("id")
+
+	var req models.ModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.menuService.CreateModifierGroup(itemID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("updated", itemID)
+
+	c.JSON(http.StatusCreated, gin.H{"modifier_group": group})
+}
+
+func (h *MenuHandler) UpdateModifierGroup(c *gin.Context) {
+	itemID := c.Param("id")
+
+	var req models.ModifierGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := h.menuService.UpdateModifierGroup(itemID, c.Param("group_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("updated", itemID)
+
+	c.JSON(http.StatusOK, gin.H{"modifier_group": group})
+}
+
+func (h *MenuHandler) DeleteModifierGroup(c *gin.Context) {
+	itemID := c.Param("id")
+
+	if err := h.menuService.DeleteModifierGroup(itemID, c.Param("group_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.notifyMenuUpdated("updated", itemID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Modifier group deleted successfully"})
+}
+
+// notifyMenuUpdated tells open clients to refetch the menu.
+func (h *MenuHandler) notifyMenuUpdated(action, itemID string) {
+	h.hub.Broadcast(gin.H{
+		"type": "menu_updated",
+		"data": gin.H{
+			"action":       action,
+			"menu_item_id": itemID,
+		},
+	})
+}
@@ -1,23 +1,34 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/middleware"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 	"restaurant-system/internal/websocket"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type OrderHandler struct {
-	orderService *services.OrderService
-	hub          *websocket.Hub
+	orderService   *services.OrderService
+	tableService   *services.TableService
+	kitchenService *services.KitchenService
+	hub            *websocket.Hub
 }
 
-func NewOrderHandler(orderService *services.OrderService, hub *websocket.Hub) *OrderHandler {
+func NewOrderHandler(orderService *services.OrderService, tableService *services.TableService, kitchenService *services.KitchenService, hub *websocket.Hub) *OrderHandler {
 	return &OrderHandler{
-		orderService: orderService,
-		hub:          hub,
+		orderService:   orderService,
+		tableService:   tableService,
+		kitchenService: kitchenService,
+		hub:            hub,
 	}
 }
 
@@ -27,17 +38,30 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.CustomerID = middleware.CurrentAccount(c).ID
+	// Delivery drivers call the customer unless another number is given
+	if req.Type == models.OrderTypeDelivery && req.ContactPhone == "" {
+		req.ContactPhone = middleware.CurrentAccount(c).PhoneNumber
+	}
 
-	order, err := h.orderService.CreateOrder(&req)
+	order, err := h.orderService.CreateOrder(&req, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Notify kitchen dashboard via WebSocket
+	h.tableService.SyncOrderTable(order.ID)
+
+	// Notify kitchen dashboard via WebSocket, with notes and allergies up front and
+	// held courses left off. Station screens get their tickets once the order is
+	// confirmed; see broadcastOrderStatus.
+	kitchenOrder := order.KitchenView()
 	h.hub.BroadcastToKitchen(gin.H{
-		"type": "new_order",
-		"data": order,
+		"type":          "new_order",
+		"data":          kitchenOrder,
+		"order_number":  order.DisplayNumber,
+		"alerts":        kitchenOrder.Alerts,
+		"allergy_alert": kitchenOrder.AllergyAlert,
 	})
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -54,24 +78,97 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 	}
 
 	order, err := h.orderService.GetOrder(orderID)
-	if err != nil {
+	if err != nil || !canViewOrder(c, order) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
 		return
 	}
 
+	// An estimate is nice to have; the order is still shown without one
+	if order.Estimate, err = h.kitchenService.EstimateReady(order); err != nil {
+		log.Println("estimate ready time:", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"order": order})
 }
 
 func (h *OrderHandler) GetOrders(c *gin.Context) {
-	customerID := c.Query("customer_id")
+	query := models.OrderListQuery{
+		Statuses:     models.ParseOrderStatuses(c.Query("status")),
+		Types:        models.ParseOrderTypes(c.Query("type")),
+		Source:       models.OrderSource(c.Query("source")),
+		PaymentState: models.PaymentState(c.Query("payment_state")),
+		Sort:         models.OrderSort(c.Query("sort")),
+		Cursor:       c.Query("cursor"),
+	}
+
+	// Staff may look at anyone's orders; everyone else only sees their own
+	query.CustomerID = middleware.CurrentAccount(c).ID
+	if middleware.CurrentActor(c).Can(models.PermOrdersViewAll) {
+		query.CustomerID = c.Query("customer_id")
+	}
+
+	var err error
+	if number := c.Query("number"); number != "" {
+		if query.Number, err = models.ParseOrderNumber(number); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// Numbers restart every business day, so look in today's unless told otherwise
+		query.BusinessDay = c.DefaultQuery("day", config.Branch().BusinessDay(time.Now()))
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+			return
+		}
+	}
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	orders, err := h.orderService.GetOrders(customerID)
+	if err := query.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.orderService.ListOrders(&query)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	order, err := h.orderService.GetOrder(orderID)
+	if err != nil || !canViewOrder(c, order) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		return
+	}
+
+	events, err := h.orderService.GetOrderHistory(orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_id": orderID, "history": events})
 }
 
 func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
@@ -87,12 +184,14 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	err := h.orderService.UpdateOrderStatus(orderID, req.Status)
+	err := h.orderService.UpdateOrderStatus(orderID, req.Status, middleware.CurrentActor(c), req.Reason)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
+	h.tableService.SyncOrderTable(orderID)
+
 	// Get updated order
 	order, err := h.orderService.GetOrder(orderID)
 	if err != nil {
@@ -101,10 +200,7 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	// Notify all connected clients about status update
-	h.hub.Broadcast(gin.H{
-		"type": "order_status_updated",
-		"data": order,
-	})
+	broadcastOrderStatus(h.hub, order)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Order status updated successfully",
@@ -112,12 +208,81 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
-func (h *OrderHandler) GetMenuItems(c *gin.Context) {
-	items, err := h.orderService.GetMenuItems()
+// FireCourse sends a course held back from the kitchen to the line, e.g. the mains
+// once the starters are cleared.
+func (h *OrderHandler) FireCourse(c *gin.Context) {
+	course, err := strconv.Atoi(c.Param("course"))
+	if err != nil || course < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "course must be a positive number"})
+		return
+	}
+
+	order, err := h.orderService.FireCourse(c.Param("id"), course)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"menu_items": items})
+	h.tableService.SyncOrderTable(order.ID)
+
+	kitchenOrder := order.KitchenView()
+	h.hub.BroadcastToKitchen(gin.H{
+		"type":          "course_fired",
+		"data":          kitchenOrder,
+		"order_number":  order.DisplayNumber,
+		"course":        course,
+		"alerts":        kitchenOrder.Alerts,
+		"allergy_alert": kitchenOrder.AllergyAlert,
+	})
+	// A pending order's tickets reach the stations when it is confirmed
+	if order.Status != models.OrderStatusPending {
+		broadcastTickets(h.hub, "ticket_updated", order)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Course fired successfully",
+		"order":   order,
+	})
+}
+
+// respondOrderError maps order service errors to HTTP responses. Moves the order
+// state machine refuses are conflicts with the order's current state.
+func respondOrderError(c *gin.Context, err error) {
+	var transitionErr *services.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "from": transitionErr.From, "to": transitionErr.To})
+	case errors.Is(err, services.ErrCancellationDecided), errors.Is(err, services.ErrItemNotInKitchen),
+		errors.Is(err, services.ErrItemPrepStatus), errors.Is(err, services.ErrCourseNotHeld):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOrderNotFound), errors.Is(err, services.ErrCancellationNotFound),
+		errors.Is(err, services.ErrOrderItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp or YYYY-MM-DD date query parameter.
+// A date means midnight at the start of that day, server time.
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+	}
+	return &t, nil
+}
+
+// canViewOrder reports whether the caller owns the order or may view all orders.
+func canViewOrder(c *gin.Context, order *models.Order) bool {
+	return order.CustomerID == middleware.CurrentAccount(c).ID || middleware.CurrentActor(c).Can(models.PermOrdersViewAll)
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"restaurant-system/internal/middleware"
 	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
 	"restaurant-system/internal/websocket"
@@ -11,12 +12,16 @@ import (
 
 type PaymentHandler struct {
 	paymentService *services.PaymentService
+	orderService   *services.OrderService
+	tableService   *services.TableService
 	hub            *websocket.Hub
 }
 
-func NewPaymentHandler(paymentService *services.PaymentService, hub *websocket.Hub) *PaymentHandler {
+func NewPaymentHandler(paymentService *services.PaymentService, orderService *services.OrderService, tableService *services.TableService, hub *websocket.Hub) *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: paymentService,
+		orderService:   orderService,
+		tableService:   tableService,
 		hub:            hub,
 	}
 }
@@ -28,17 +33,27 @@ func (h *PaymentHandler) ProcessPayment(c *gin.Context) {
 		return
 	}
 
-	response, err := h.paymentService.ProcessPayment(&req)
+	response, err := h.paymentService.ProcessPayment(&req, middleware.CurrentActor(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondOrderError(c, err)
 		return
 	}
 
-	// Notify all connected clients about payment status
-	h.hub.Broadcast(gin.H{
+	h.tableService.SyncOrderTable(response.OrderID)
+
+	// Tell front of house and the customer about the payment, and everyone about the
+	// order if the payment sent it to the kitchen
+	message := gin.H{
 		"type": "payment_processed",
 		"data": response,
-	})
+	}
+	h.hub.BroadcastToStaff(message)
+	if order, err := h.orderService.GetOrder(response.OrderID); err == nil {
+		h.hub.SendToAccount(order.CustomerID, message)
+		if response.OrderConfirmed {
+			broadcastOrderStatus(h.hub, order)
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Payment initiated",
@@ -53,9 +68,13 @@ func (h *PaymentHandler) GetPaymentStatus(c *gin.Context) {
 		return
 	}
 
-	payment, err := h.paymentService.GetPaymentStatus(paymentID)
+	payment, err := h.paymentService.GetPayment(paymentID, middleware.CurrentActor(c))
+	if err == services.ErrPaymentNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "payment not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
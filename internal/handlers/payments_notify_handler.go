@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,16 +17,36 @@ func TelebirrNotifyHandler(c *gin.Context) {
 	}
 
 	// PaymentService is stored in context by DI in main, or we can resolve via a package var.
-	svc, ok := c.MustGet("paymentService").(*services.PaymentService)
+	value, _ := c.Get("paymentService")
+	svc, ok := value.(*services.PaymentService)
 	if !ok || svc == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "service not available"})
 		return
 	}
 
-	if err := svc.HandleTelebirrCallback(payload); err != nil {
+	payment, confirmed, err := svc.HandleTelebirrCallback(payload)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	value, _ = c.Get("tableService")
+	if tables, ok := value.(*services.TableService); ok && tables != nil {
+		tables.SyncOrderTable(payment.OrderID)
+	}
+
+	// The payment sent the order to the kitchen
+	if confirmed {
+		value, _ = c.Get("orderService")
+		orders, _ := value.(*services.OrderService)
+		value, _ = c.Get("hub")
+		hub, _ := value.(*websocket.Hub)
+		if orders != nil && hub != nil {
+			if order, err := orders.GetOrder(payment.OrderID); err == nil {
+				broadcastOrderStatus(hub, order)
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
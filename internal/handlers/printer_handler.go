@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/printing"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrinterHandler struct {
+	printerService *services.PrinterService
+}
+
+func NewPrinterHandler(printerService *services.PrinterService) *PrinterHandler {
+	return &PrinterHandler{printerService: printerService}
+}
+
+func (h *PrinterHandler) ListPrinters(c *gin.Context) {
+	printers, err := h.printerService.ListPrinters()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"printers": printers})
+}
+
+func (h *PrinterHandler) CreatePrinter(c *gin.Context) {
+	var req models.CreatePrinterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	printer, err := h.printerService.CreatePrinter(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Printer added successfully",
+		"printer": printer,
+	})
+}
+
+func (h *PrinterHandler) UpdatePrinter(c *gin.Context) {
+	var req models.UpdatePrinterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	printer, err := h.printerService.UpdatePrinter(c.Param("id"), &req)
+	if err != nil {
+		respondPrinterError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Printer updated successfully",
+		"printer": printer,
+	})
+}
+
+func (h *PrinterHandler) DeletePrinter(c *gin.Context) {
+	if err := h.printerService.DeletePrinter(c.Param("id")); err != nil {
+		respondPrinterError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Printer removed successfully"})
+}
+
+// GetStatus returns each printer's queue: whether it is online, how many jobs are
+// waiting and the last error.
+func (h *PrinterHandler) GetStatus(c *gin.Context) {
+	statuses, err := h.printerService.Statuses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"printers": statuses})
+}
+
+func (h *PrinterHandler) PrintTest(c *gin.Context) {
+	if err := h.printerService.PrintTest(c.Param("id")); err != nil {
+		respondPrinterError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Test page queued successfully"})
+}
+
+// ReprintTickets prints an order's kitchen tickets again, e.g. when a ticket was
+// lost or the printer jammed.
+func (h *PrinterHandler) ReprintTickets(c *gin.Context) {
+	if err := h.printerService.ReprintTickets(c.Param("id")); err != nil {
+		respondPrinterError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Tickets queued successfully"})
+}
+
+func (h *PrinterHandler) PrintReceipt(c *gin.Context) {
+	if err := h.printerService.PrintReceipt(c.Param("id")); err != nil {
+		respondPrinterError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Receipt queued successfully"})
+}
+
+// respondPrinterError maps printing errors to responses, using fallback for
+// anything else.
+func respondPrinterError(c *gin.Context, err error, fallback int) {
+	status := fallback
+	switch {
+	case errors.Is(err, services.ErrPrinterNotFound), errors.Is(err, services.ErrOrderNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrNoPrinter), errors.Is(err, printing.ErrNoSuchQueue), errors.Is(err, printing.ErrQueueClosed):
+		status = http.StatusConflict
+	case errors.Is(err, printing.ErrQueueFull):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReservationHandler struct {
+	reservationService *services.ReservationService
+	hub                *websocket.Hub
+}
+
+func NewReservationHandler(reservationService *services.ReservationService, hub *websocket.Hub) *ReservationHandler {
+	return &ReservationHandler{
+		reservationService: reservationService,
+		hub:                hub,
+	}
+}
+
+// GetAvailability lists the tables free for party_size at the time at (RFC 3339),
+// with alternative times when there are none.
+func (h *ReservationHandler) GetAvailability(c *gin.Context) {
+	partySize, err := strconv.Atoi(c.Query("party_size"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a number"})
+		return
+	}
+	at, err := time.Parse(time.RFC3339, c.Query("at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 timestamp"})
+		return
+	}
+
+	availability, err := h.reservationService.Availability(partySize, at)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"availability": availability})
+}
+
+// GetReservations lists the reservations of a day (date=YYYY-MM-DD, today by default).
+func (h *ReservationHandler) GetReservations(c *gin.Context) {
+	day := c.DefaultQuery("date", time.Now().In(config.Branch().Location).Format("2006-01-02"))
+	reservations, err := h.reservationService.ListReservations(day, models.ReservationStatus(c.Query("status")))
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reservations": reservations})
+}
+
+func (h *ReservationHandler) GetReservation(c *gin.Context) {
+	reservation, err := h.reservationService.GetReservation(c.Param("id"))
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reservation": reservation})
+}
+
+func (h *ReservationHandler) CreateReservation(c *gin.Context) {
+	var req models.CreateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := h.reservationService.CreateReservation(&req, middleware.CurrentActor(c))
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyReservationUpdated(reservation)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Reservation created successfully",
+		"reservation": reservation,
+	})
+}
+
+func (h *ReservationHandler) UpdateReservation(c *gin.Context) {
+	var req models.UpdateReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := h.reservationService.UpdateReservation(c.Param("id"), &req)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyReservationUpdated(reservation)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Reservation updated successfully",
+		"reservation": reservation,
+	})
+}
+
+// SetReservationStatus seats, completes, cancels or marks a reservation as a no-show.
+func (h *ReservationHandler) SetReservationStatus(c *gin.Context) {
+	var req models.SetReservationStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation, err := h.reservationService.SetStatus(c.Param("id"), req.Status)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyReservationUpdated(reservation)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Reservation status updated successfully",
+		"reservation": reservation,
+	})
+}
+
+// Guests' phone numbers are in the payload, so only staff hear about reservations
+func (h *ReservationHandler) notifyReservationUpdated(reservation *models.Reservation) {
+	h.hub.BroadcastToStaff(gin.H{
+		"type": "reservation_updated",
+		"data": reservation,
+	})
+}
+
+// respondReservationError maps reservation and waitlist errors to HTTP responses.
+func respondReservationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReservationNotFound), errors.Is(err, services.ErrWaitlistEntryNotFound),
+		errors.Is(err, services.ErrTableNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoTableAvailable), errors.Is(err, services.ErrTableOccupied),
+		errors.Is(err, services.ErrReservationStatus), errors.Is(err, services.ErrWaitlistEntryClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSMSFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandler struct {
+	roleService *services.RoleService
+}
+
+func NewRoleHandler(roleService *services.RoleService) *RoleHandler {
+	return &RoleHandler{roleService: roleService}
+}
+
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *RoleHandler) GetAccountRoles(c *gin.Context) {
+	accountID := c.Param("id")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account ID is required"})
+		return
+	}
+
+	roles, err := h.roleService.GetAccountRoles(accountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+func (h *RoleHandler) GrantRole(c *gin.Context) {
+	accountID := c.Param("id")
+	if accountID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account ID is required"})
+		return
+	}
+
+	var req models.GrantRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.roleService.GrantRole(accountID, req.Role, middleware.CurrentAccount(c).ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role granted successfully"})
+}
+
+func (h *RoleHandler) RevokeRole(c *gin.Context) {
+	accountID := c.Param("id")
+	role := models.Role(c.Param("role"))
+	if accountID == "" || role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account ID and role are required"})
+		return
+	}
+
+	if err := h.roleService.RevokeRole(accountID, role, middleware.CurrentAccount(c).ID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role revoked successfully"})
+}
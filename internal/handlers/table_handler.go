@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/qrcode"
+	"restaurant-system/internal/services"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TableHandler struct {
+	tableService *services.TableService
+}
+
+func NewTableHandler(tableService *services.TableService) *TableHandler {
+	return &TableHandler{tableService: tableService}
+}
+
+// GetFloorPlan returns every area with its tables and their live state.
+func (h *TableHandler) GetFloorPlan(c *gin.Context) {
+	areas, err := h.tableService.GetFloorPlan()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"areas": areas})
+}
+
+func (h *TableHandler) GetTable(c *gin.Context) {
+	table, err := h.tableService.GetTable(c.Param("id"))
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"table": table})
+}
+
+func (h *TableHandler) SetTableState(c *gin.Context) {
+	var req models.SetTableStateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := h.tableService.SetState(c.Param("id"), req.State)
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table state updated successfully",
+		"table":   table,
+	})
+}
+
+func (h *TableHandler) CreateArea(c *gin.Context) {
+	var req models.CreateAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	area, err := h.tableService.CreateArea(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Area created successfully",
+		"area":    area,
+	})
+}
+
+func (h *TableHandler) UpdateArea(c *gin.Context) {
+	var req models.UpdateAreaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	area, err := h.tableService.UpdateArea(c.Param("id"), &req)
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"area": area})
+}
+
+func (h *TableHandler) DeleteArea(c *gin.Context) {
+	if err := h.tableService.DeleteArea(c.Param("id")); err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Area deleted successfully"})
+}
+
+func (h *TableHandler) CreateTable(c *gin.Context) {
+	var req models.CreateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := h.tableService.CreateTable(&req)
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Table created successfully",
+		"table":   table,
+	})
+}
+
+func (h *TableHandler) UpdateTable(c *gin.Context) {
+	var req models.UpdateTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	table, err := h.tableService.UpdateTable(c.Param("id"), &req)
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"table": table})
+}
+
+func (h *TableHandler) DeleteTable(c *gin.Context) {
+	if err := h.tableService.DeleteTable(c.Param("id")); err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Table deleted successfully"})
+}
+
+func respondTableError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTableNotFound), errors.Is(err, services.ErrAreaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTableBusy), errors.Is(err, services.ErrAreaNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// GetTableQR renders the QR code guests scan to order at a table, as a PNG (default)
+// or SVG. scale sets the PNG pixels per module.
+func (h *TableHandler) GetTableQR(c *gin.Context) {
+	table, err := h.tableService.GetTable(c.Param("id"))
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	code, err := qrcode.Encode(services.QRLink(table))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "png") {
+	case "png":
+		scale, err := strconv.Atoi(c.DefaultQuery("scale", strconv.Itoa(defaultQRScale)))
+		if err != nil || scale < 1 || scale > maxQRScale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("scale must be between 1 and %d", maxQRScale)})
+			return
+		}
+		data, err := code.PNG(scale)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "image/png", data)
+	case "svg":
+		c.Data(http.StatusOK, "image/svg+xml", []byte(code.SVG(qrSheetCell)))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+	}
+}
+
+// GetTableQRSheet renders the QR codes of every table for printing: a labelled SVG
+// sheet (default), or a zip with one PNG per table.
+func (h *TableHandler) GetTableQRSheet(c *gin.Context) {
+	areas, err := h.tableService.GetFloorPlan()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var labels []string
+	var codes []*qrcode.Code
+	for _, area := range areas {
+		for _, table := range area.Tables {
+			code, err := qrcode.Encode(services.QRLink(table))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			labels = append(labels, area.Name+" · "+table.Name)
+			codes = append(codes, code)
+		}
+	}
+
+	switch c.DefaultQuery("format", "svg") {
+	case "svg":
+		c.Data(http.StatusOK, "image/svg+xml", []byte(renderQRSheet(labels, codes)))
+	case "png":
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		used := map[string]int{}
+		for i, code := range codes {
+			data, err := code.PNG(defaultQRScale)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			name := qrFileName(labels[i])
+			if used[name]++; used[name] > 1 {
+				name = fmt.Sprintf("%s-%d", name, used[name])
+			}
+			w, err := archive.Create(name + ".png")
+			if err == nil {
+				_, err = w.Write(data)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if err := archive.Close(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="table-qr-codes.zip"`)
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be svg or png"})
+	}
+}
+
+// RotateTableQR invalidates a table's printed QR code and returns the table; print
+// a new code afterwards.
+func (h *TableHandler) RotateTableQR(c *gin.Context) {
+	table, err := h.tableService.RotateQRKey(c.Param("id"))
+	if err != nil {
+		respondTableError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Table QR code rotated successfully",
+		"table":   table,
+	})
+}
+
+const (
+	defaultQRScale = 8
+	maxQRScale     = 40
+
+	// Layout of the printable sheet, in SVG user units
+	qrSheetColumns = 3
+	qrSheetCell    = 240
+	qrSheetLabel   = 40
+)
+
+// renderQRSheet lays the codes out in a grid with a label under each.
+func renderQRSheet(labels []string, codes []*qrcode.Code) string {
+	rows := (len(codes) + qrSheetColumns - 1) / qrSheetColumns
+	width := qrSheetColumns * qrSheetCell
+	height := rows * (qrSheetCell + qrSheetLabel)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#fff"/>`)
+	for i, code := range codes {
+		x := i % qrSheetColumns * qrSheetCell
+		y := i / qrSheetColumns * (qrSheetCell + qrSheetLabel)
+		scale := float64(qrSheetCell) / float64(code.Size+2*qrcode.QuietZone)
+		fmt.Fprintf(&b, `<path fill="#000" transform="translate(%d %d) scale(%.4f)" d="%s"/>`, x, y, scale, code.SVGPath())
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="16" text-anchor="middle">%s</text>`,
+			x+qrSheetCell/2, y+qrSheetCell+qrSheetLabel/2, html.EscapeString(labels[i]))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// qrFileName turns a table label into a safe file name.
+func qrFileName(label string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, label)
+	name = strings.Trim(name, "-")
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	if name == "" {
+		name = "table"
+	}
+	return name
+}
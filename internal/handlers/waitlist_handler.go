@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+	"restaurant-system/internal/websocket"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistHandler struct {
+	waitlistService *services.WaitlistService
+	hub             *websocket.Hub
+}
+
+func NewWaitlistHandler(waitlistService *services.WaitlistService, hub *websocket.Hub) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: waitlistService,
+		hub:             hub,
+	}
+}
+
+// GetWaitlist returns the parties in the queue with their current estimated waits.
+func (h *WaitlistHandler) GetWaitlist(c *gin.Context) {
+	entries, err := h.waitlistService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"waitlist": entries})
+}
+
+// GetQuote estimates the wait for a party of party_size before they join.
+func (h *WaitlistHandler) GetQuote(c *gin.Context) {
+	partySize, err := strconv.Atoi(c.Query("party_size"))
+	if err != nil || partySize < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "party_size must be a positive number"})
+		return
+	}
+
+	quote, err := h.waitlistService.Quote(partySize)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"party_size":             partySize,
+		"position":               quote.Position,
+		"estimated_wait_minutes": quote.EstimatedWaitMinutes,
+	})
+}
+
+func (h *WaitlistHandler) JoinWaitlist(c *gin.Context) {
+	var req models.JoinWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.waitlistService.Join(&req)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyWaitlistUpdated(entry)
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Party added to waitlist successfully",
+		"entry":   entry,
+	})
+}
+
+// NotifyTableReady texts the party that their table is ready.
+func (h *WaitlistHandler) NotifyTableReady(c *gin.Context) {
+	var req models.WaitlistTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.waitlistService.NotifyTableReady(c.Param("id"), req.TableID)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyWaitlistUpdated(entry)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Party notified successfully",
+		"entry":   entry,
+	})
+}
+
+func (h *WaitlistHandler) SeatParty(c *gin.Context) {
+	var req models.WaitlistTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := h.waitlistService.Seat(c.Param("id"), req.TableID)
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyWaitlistUpdated(entry)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Party seated successfully",
+		"entry":   entry,
+	})
+}
+
+func (h *WaitlistHandler) RemoveParty(c *gin.Context) {
+	entry, err := h.waitlistService.Remove(c.Param("id"))
+	if err != nil {
+		respondReservationError(c, err)
+		return
+	}
+
+	h.notifyWaitlistUpdated(entry)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Party removed from waitlist successfully",
+		"entry":   entry,
+	})
+}
+
+func (h *WaitlistHandler) notifyWaitlistUpdated(entry *models.WaitlistEntry) {
+	h.hub.BroadcastToStaff(gin.H{
+		"type": "waitlist_updated",
+		"data": entry,
+	})
+}
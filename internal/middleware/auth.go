@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DeviceIDHeader carries the device id the session was issued to.
+	DeviceIDHeader = "X-Device-ID"
+
+	accountKey = "account"
+	sessionKey = "session"
+)
+
+// RequireAuth resolves the Bearer token against the sessions table and stores the
+// session and its account in the context. Requests without a valid session are rejected.
+func RequireAuth(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		deviceID := c.GetHeader(DeviceIDHeader)
+		// Browsers cannot set headers on WebSocket handshakes, so accept query params there
+		if token == "" && c.IsWebsocket() {
+			token = c.Query("token")
+			deviceID = c.Query("device_id")
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+
+		session, account, err := authService.ResolveSession(token, deviceID)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidSession) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve session"})
+			return
+		}
+
+		c.Set(sessionKey, session)
+		c.Set(accountKey, account)
+		c.Next()
+	}
+}
+
+// RequirePermission rejects requests whose session does not grant perm.
+// It must run after RequireAuth.
+func RequirePermission(perm models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentActor(c).Can(perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentActor returns the authenticated actor, or nil outside RequireAuth.
+func CurrentActor(c *gin.Context) *models.Actor {
+	if session := CurrentSession(c); session != nil {
+		return session.Actor()
+	}
+	return nil
+}
+
+// CurrentAccount returns the authenticated account, or nil outside RequireAuth.
+func CurrentAccount(c *gin.Context) *models.Account {
+	if v, ok := c.Get(accountKey); ok {
+		if account, ok := v.(*models.Account); ok {
+			return account
+		}
+	}
+	return nil
+}
+
+// CurrentSession returns the authenticated session, or nil outside RequireAuth.
+func CurrentSession(c *gin.Context) *models.Session {
+	if v, ok := c.Get(sessionKey); ok {
+		if session, ok := v.(*models.Session); ok {
+			return session
+		}
+	}
+	return nil
+}
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
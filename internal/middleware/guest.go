@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tableTokenKey = "tableToken"
+
+// RequireTableToken resolves the Bearer token against the table tokens handed out
+// when guests scan a table's QR code, and stores it in the context.
+func RequireTableToken(guestService *services.GuestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "table token required"})
+			return
+		}
+
+		tableToken, err := guestService.ResolveToken(token)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTableToken) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "code": "table_token_expired"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve table token"})
+			return
+		}
+
+		c.Set(tableTokenKey, tableToken)
+		c.Next()
+	}
+}
+
+// CurrentTableToken returns the guest's table token, or nil outside RequireTableToken.
+func CurrentTableToken(c *gin.Context) *models.TableToken {
+	if v, ok := c.Get(tableTokenKey); ok {
+		if token, ok := v.(*models.TableToken); ok {
+			return token
+		}
+	}
+	return nil
+}
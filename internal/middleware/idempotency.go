@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"restaurant-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes a handler safe to retry. When the request carries an
+// Idempotency-Key header, the first response is stored and replayed for retries with
+// the same key and body; reusing the key with a different body is rejected.
+// Keys are scoped per account, or per table token for guests, and per scope name.
+// It must run after RequireAuth or RequireTableToken.
+func Idempotency(idempotencyService *services.IdempotencyService, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		requestHash := hex.EncodeToString(hash[:])
+		accountID := idempotencyOwner(c)
+
+		record, err := idempotencyService.Begin(accountID, scope, key, requestHash)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "idempotency_key_reused"})
+			return
+		case errors.Is(err, services.ErrIdempotencyKeyInFlight):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "idempotency_key_in_flight"})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check idempotency key"})
+			return
+		case record != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not remembered so the client can retry with the same key
+		if status := recorder.Status(); status >= http.StatusInternalServerError {
+			err = idempotencyService.Release(accountID, scope, key)
+		} else {
+			err = idempotencyService.Complete(accountID, scope, key, status, recorder.body.Bytes())
+		}
+		if err != nil {
+			log.Println("idempotency:", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body as it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyOwner returns who the keys of a request belong to. Guests have no
+// account, so their keys are kept per table token.
+func idempotencyOwner(c *gin.Context) string {
+	if token := CurrentTableToken(c); token != nil {
+		return "table_token:" + token.ID
+	}
+	return CurrentAccount(c).ID
+}
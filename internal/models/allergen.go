@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Allergen string
+
+const (
+	AllergenGluten    Allergen = "gluten"
+	AllergenDairy     Allergen = "dairy"
+	AllergenEggs      Allergen = "eggs"
+	AllergenPeanuts   Allergen = "peanuts"
+	AllergenTreeNuts  Allergen = "tree_nuts"
+	AllergenSoy       Allergen = "soy"
+	AllergenFish      Allergen = "fish"
+	AllergenShellfish Allergen = "shellfish"
+	AllergenSesame    Allergen = "sesame"
+)
+
+var AllAllergens = []Allergen{
+	AllergenGluten, AllergenDairy, AllergenEggs, AllergenPeanuts, AllergenTreeNuts,
+	AllergenSoy, AllergenFish, AllergenShellfish, AllergenSesame,
+}
+
+// NormalizeAllergens rejects unknown allergens and returns the set sorted and de-duplicated.
+func NormalizeAllergens(allergens []Allergen) ([]Allergen, error) {
+	known := map[Allergen]bool{}
+	for _, a := range AllAllergens {
+		known[a] = true
+	}
+
+	seen := map[Allergen]bool{}
+	var out []Allergen
+	for _, a := range allergens {
+		a = Allergen(strings.ToLower(strings.TrimSpace(string(a))))
+		if !known[a] {
+			return nil, fmt.Errorf("unknown allergen: %s", a)
+		}
+		if !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out, nil
+}
+
+// AllergenStrings converts allergens for storage in a TEXT[] column.
+func AllergenStrings(allergens []Allergen) []string {
+	out := make([]string, len(allergens))
+	for i, a := range allergens {
+		out[i] = string(a)
+	}
+	return out
+}
+
+// AllergensFromStrings converts a TEXT[] column back to allergens.
+func AllergensFromStrings(values []string) []Allergen {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]Allergen, len(values))
+	for i, v := range values {
+		out[i] = Allergen(v)
+	}
+	return out
+}
+
+func joinAllergens(allergens []Allergen) string {
+	return strings.ReplaceAll(strings.Join(AllergenStrings(allergens), ", "), "_", " ")
+}
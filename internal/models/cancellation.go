@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+type CancellationReason string
+
+const (
+	CancelReasonCustomerRequest CancellationReason = "customer_request"
+	CancelReasonOutOfStock      CancellationReason = "out_of_stock"
+	CancelReasonKitchenError    CancellationReason = "kitchen_error"
+	CancelReasonDuplicateOrder  CancellationReason = "duplicate_order"
+	CancelReasonPaymentIssue    CancellationReason = "payment_issue"
+	CancelReasonOther           CancellationReason = "other"
+)
+
+var AllCancellationReasons = []CancellationReason{
+	CancelReasonCustomerRequest, CancelReasonOutOfStock, CancelReasonKitchenError,
+	CancelReasonDuplicateOrder, CancelReasonPaymentIssue, CancelReasonOther,
+}
+
+func (r CancellationReason) Valid() bool {
+	for _, reason := range AllCancellationReasons {
+		if reason == r {
+			return true
+		}
+	}
+	return false
+}
+
+type CancellationStatus string
+
+const (
+	// CancellationPendingApproval is used when the kitchen has started on the order
+	// and a manager has to approve the cancellation.
+	CancellationPendingApproval CancellationStatus = "pending_approval"
+	CancellationApproved        CancellationStatus = "approved"
+	CancellationRejected        CancellationStatus = "rejected"
+)
+
+type OrderCancellation struct {
+	ID          string             `json:"id" db:"id"`
+	OrderID     string             `json:"order_id" db:"order_id"`
+	Reason      CancellationReason `json:"reason" db:"reason"`
+	Note        string             `json:"note,omitempty" db:"note"`
+	Status      CancellationStatus `json:"status" db:"status"`
+	RequestedBy string             `json:"requested_by" db:"requested_by"`
+	DecidedBy   string             `json:"decided_by,omitempty" db:"decided_by"`
+	CreatedAt   time.Time          `json:"created_at" db:"created_at"`
+	DecidedAt   *time.Time         `json:"decided_at,omitempty" db:"decided_at"`
+
+	// Refunds are the payments refunded because of the cancellation.
+	Refunds []*Payment `json:"refunds,omitempty"`
+}
+
+type CancelOrderRequest struct {
+	Reason CancellationReason `json:"reason" binding:"required"`
+	Note   string             `json:"note" binding:"max=200"`
+}
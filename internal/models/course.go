@@ -0,0 +1,35 @@
+package models
+
+import "sort"
+
+// HideHeldItems drops the items on hold from the order, as the kitchen sees it,
+// and lists their courses in HeldCourses.
+func (o *Order) HideHeldItems() {
+	var items []OrderItem
+	held := map[int]bool{}
+	for _, item := range o.Items {
+		if item.Held {
+			held[item.Course] = true
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(held) == 0 {
+		return
+	}
+
+	o.Items = items
+	o.HeldCourses = nil
+	for course := range held {
+		o.HeldCourses = append(o.HeldCourses, course)
+	}
+	sort.Ints(o.HeldCourses)
+}
+
+// KitchenView returns a copy of the order without its held items, for the kitchen.
+func (o *Order) KitchenView() *Order {
+	view := *o
+	view.HideHeldItems()
+	view.BuildAlerts()
+	return &view
+}
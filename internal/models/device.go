@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+type Device struct {
+	DeviceID     string     `json:"device_id" db:"device_id"`
+	Name         string     `json:"name" db:"name"`
+	Disabled     bool       `json:"disabled" db:"disabled"`
+	RegisteredBy string     `json:"registered_by,omitempty" db:"registered_by"`
+	RegisteredAt time.Time  `json:"registered_at" db:"registered_at"`
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty" db:"last_seen_at"`
+}
+
+type RegisterDeviceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Name     string `json:"name"`
+}
+
+type UpdateDeviceRequest struct {
+	Name     *string `json:"name"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type PairingStatus string
+
+const (
+	PairingStatusPending  PairingStatus = "pending"
+	PairingStatusApproved PairingStatus = "approved"
+	PairingStatusExpired  PairingStatus = "expired"
+)
+
+// DevicePairing is a request from an unregistered device to be authorized.
+// The device shows Code to a manager, who approves it.
+type DevicePairing struct {
+	Code       string        `json:"code" db:"code"`
+	DeviceID   string        `json:"device_id" db:"device_id"`
+	Name       string        `json:"name" db:"name"`
+	Status     PairingStatus `json:"status" db:"status"`
+	ExpiresAt  time.Time     `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+	ApprovedBy string        `json:"approved_by,omitempty" db:"approved_by"`
+	ApprovedAt *time.Time    `json:"approved_at,omitempty" db:"approved_at"`
+}
+
+type StartPairingRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Name     string `json:"name"`
+}
+
+type ApprovePairingRequest struct {
+	// Name overrides the name the device suggested for itself.
+	Name string `json:"name"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultPrepMinutes is the prep time of menu items that were not given one.
+const DefaultPrepMinutes = 10
+
+// ReadyEstimate is when the kitchen expects an order to be ready, given the work
+// queued ahead of it at each station.
+type ReadyEstimate struct {
+	ReadyAt        time.Time `json:"ready_at"`
+	ReadyInMinutes int       `json:"ready_in_minutes"`
+	// Message is the estimate as customers see it, e.g. "ready in ~12 min".
+	Message string `json:"message"`
+}
+
+// NewReadyEstimate rounds the time until readyAt up to whole minutes, and to at
+// least one.
+func NewReadyEstimate(readyAt, now time.Time) *ReadyEstimate {
+	minutes := int((readyAt.Sub(now) + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return &ReadyEstimate{
+		ReadyAt:        readyAt,
+		ReadyInMinutes: minutes,
+		Message:        fmt.Sprintf("ready in ~%d min", minutes),
+	}
+}
+
+// LateOrder is an order the kitchen has kept past its target ready time.
+type LateOrder struct {
+	OrderID       string      `json:"order_id"`
+	DisplayNumber string      `json:"display_number"`
+	Type          OrderType   `json:"type"`
+	TableID       string      `json:"table_id,omitempty"`
+	Status        OrderStatus `json:"status"`
+	// TargetAt is when the order was confirmed, or its last course fired, plus its
+	// longest prep time and the configured grace.
+	TargetAt    time.Time `json:"target_at"`
+	MinutesLate int       `json:"minutes_late"`
+}
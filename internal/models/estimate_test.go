@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewReadyEstimateRounding(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   time.Duration
+		want int
+	}{
+		{0, 1},
+		{-5 * time.Minute, 1},
+		{time.Second, 1},
+		{time.Minute, 1},
+		{time.Minute + time.Nanosecond, 2},
+		{12*time.Minute + 30*time.Second, 13},
+		{20 * time.Minute, 20},
+	}
+	for _, tt := range tests {
+		e := NewReadyEstimate(now.Add(tt.in), now)
+		if e.ReadyInMinutes != tt.want {
+			t.Errorf("%v ahead: ReadyInMinutes = %d, want %d", tt.in, e.ReadyInMinutes, tt.want)
+		}
+		if !e.ReadyAt.Equal(now.Add(tt.in)) {
+			t.Errorf("%v ahead: ReadyAt = %v", tt.in, e.ReadyAt)
+		}
+	}
+
+	if e := NewReadyEstimate(now.Add(11*time.Minute+time.Second), now); e.Message != "ready in ~12 min" {
+		t.Errorf("Message = %q", e.Message)
+	}
+}
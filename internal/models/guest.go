@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// OrderSource records how an order reached the system.
+type OrderSource string
+
+const (
+	// OrderSourceApp orders were placed by a signed in account.
+	OrderSourceApp OrderSource = "app"
+	// OrderSourceQR orders were placed by a guest who scanned a table's QR code.
+	OrderSourceQR OrderSource = "qr"
+)
+
+func (s OrderSource) Valid() bool {
+	return s == OrderSourceApp || s == OrderSourceQR
+}
+
+// TableToken lets a guest who scanned a table's QR code order for that table
+// without signing in. It expires after config.Guest().TableTokenTTL.
+type TableToken struct {
+	ID        string    `json:"id" db:"id"`
+	Token     string    `json:"token,omitempty" db:"token"`
+	TableID   string    `json:"table_id" db:"table_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// StartGuestSessionRequest carries what the QR code encodes.
+type StartGuestSessionRequest struct {
+	TableID string `json:"table_id" binding:"required"`
+	Key     string `json:"key" binding:"required"`
+}
+
+// GuestSession is returned when a guest scans a table's QR code.
+type GuestSession struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	Table     *DiningTable `json:"table"`
+}
+
+// GuestOrderRequest is a dine-in order placed with a table token; the table comes
+// from the token.
+type GuestOrderRequest struct {
+	Items      []CreateOrderItem `json:"items" binding:"required,min=1,dive"`
+	Notes      string            `json:"notes" binding:"max=500"`
+	Allergens  []Allergen        `json:"allergens"`
+	GuestCount int               `json:"guest_count" binding:"required,min=1"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusProcessing IdempotencyStatus = "processing"
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an Idempotency-Key
+// so retries of the same request get the same response.
+type IdempotencyRecord struct {
+	Key            string            `json:"key" db:"key"`
+	AccountID      string            `json:"account_id" db:"account_id"`
+	Scope          string            `json:"scope" db:"scope"`
+	RequestHash    string            `json:"request_hash" db:"request_hash"`
+	Status         IdempotencyStatus `json:"status" db:"status"`
+	ResponseStatus int               `json:"response_status" db:"response_status"`
+	ResponseBody   []byte            `json:"-" db:"response_body"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+}
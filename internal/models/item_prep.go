@@ -0,0 +1,72 @@
+package models
+
+// ItemPrepStatus tracks one order item through the kitchen.
+type ItemPrepStatus string
+
+const (
+	ItemPrepQueued  ItemPrepStatus = "queued"
+	ItemPrepCooking ItemPrepStatus = "cooking"
+	ItemPrepDone    ItemPrepStatus = "done"
+	ItemPrepServed  ItemPrepStatus = "served"
+)
+
+// AllItemPrepStatuses lists the item statuses in the order an item goes through them.
+// Bumping moves an item one step forward and recalling one step back.
+var AllItemPrepStatuses = []ItemPrepStatus{ItemPrepQueued, ItemPrepCooking, ItemPrepDone, ItemPrepServed}
+
+func (s ItemPrepStatus) Valid() bool {
+	return s.index() >= 0
+}
+
+func (s ItemPrepStatus) index() int {
+	for i, status := range AllItemPrepStatuses {
+		if status == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Bumped returns the status an item in status s moves to when bumped, and false
+// when it has already been served.
+func (s ItemPrepStatus) Bumped() (ItemPrepStatus, bool) {
+	i := s.index()
+	if i < 0 || i == len(AllItemPrepStatuses)-1 {
+		return s, false
+	}
+	return AllItemPrepStatuses[i+1], true
+}
+
+// Recalled returns the status an item in status s goes back to when recalled, and
+// false when it is still queued.
+func (s ItemPrepStatus) Recalled() (ItemPrepStatus, bool) {
+	i := s.index()
+	if i <= 0 {
+		return s, false
+	}
+	return AllItemPrepStatuses[i-1], true
+}
+
+// Finished reports whether the kitchen is done with an item in status s.
+func (s ItemPrepStatus) Finished() bool {
+	return s == ItemPrepDone || s == ItemPrepServed
+}
+
+// ItemPrepChange is the outcome of bumping or recalling an order item.
+type ItemPrepChange struct {
+	Item  OrderItem `json:"item"`
+	Order *Order    `json:"order"`
+	// PreviousOrderStatus is set when the change moved the order on, e.g. to ready
+	// once every item is done.
+	PreviousOrderStatus OrderStatus `json:"previous_order_status,omitempty"`
+}
+
+// Item returns the order's item with the given ID, or nil.
+func (o *Order) Item(itemID string) *OrderItem {
+	for i := range o.Items {
+		if o.Items[i].ID == itemID {
+			return &o.Items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+// MenuCategory groups menu items for display.
+type MenuCategory struct {
+	Category string      `json:"category"`
+	Items    []*MenuItem `json:"items"`
+}
+
+type CreateMenuItemRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price" binding:"required,gt=0"`
+	Category    string  `json:"category" binding:"required"`
+	// Station defaults to the one for the category; see StationForCategory.
+	Station Station `json:"station"`
+	// PrepMinutes defaults to DefaultPrepMinutes.
+	PrepMinutes *int  `json:"prep_minutes" binding:"omitempty,min=0,max=240"`
+	Available   *bool `json:"available"`
+}
+
+// UpdateMenuItemRequest changes only the fields that are set.
+type UpdateMenuItemRequest struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price" binding:"omitempty,gt=0"`
+	Category    *string  `json:"category"`
+	Station     *Station `json:"station"`
+	PrepMinutes *int     `json:"prep_minutes" binding:"omitempty,min=0,max=240"`
+	Available   *bool    `json:"available"`
+}
+
+type SetAvailabilityRequest struct {
+	Available *bool `json:"available" binding:"required"`
+}
@@ -0,0 +1,52 @@
+package models
+
+// ModifierGroup is a set of choices attached to a menu item, e.g. "Fruit" for a juice
+// or "Extras" for a burger. MaxSelect of 0 means no upper limit.
+type ModifierGroup struct {
+	ID         string            `json:"id" db:"id"`
+	MenuItemID string            `json:"menu_item_id" db:"menu_item_id"`
+	Name       string            `json:"name" db:"name"`
+	Required   bool              `json:"required" db:"required"`
+	MinSelect  int               `json:"min_select" db:"min_select"`
+	MaxSelect  int               `json:"max_select" db:"max_select"`
+	Position   int               `json:"position" db:"position"`
+	Options    []*ModifierOption `json:"options"`
+}
+
+type ModifierOption struct {
+	ID         string  `json:"id" db:"id"`
+	GroupID    string  `json:"group_id" db:"group_id"`
+	Name       string  `json:"name" db:"name"`
+	PriceDelta float64 `json:"price_delta" db:"price_delta"`
+	Available  bool    `json:"available" db:"available"`
+	Position   int     `json:"position" db:"position"`
+}
+
+// OrderItemModifier is a modifier chosen for an order item. Names and price are
+// copied at order time so later menu edits don't rewrite past orders.
+type OrderItemModifier struct {
+	ID               string  `json:"id" db:"id"`
+	OrderItemID      string  `json:"order_item_id" db:"order_item_id"`
+	ModifierOptionID string  `json:"modifier_option_id" db:"modifier_option_id"`
+	GroupName        string  `json:"group_name" db:"group_name"`
+	OptionName       string  `json:"option_name" db:"option_name"`
+	PriceDelta       float64 `json:"price_delta" db:"price_delta"`
+}
+
+type ModifierGroupRequest struct {
+	Name      string                  `json:"name" binding:"required"`
+	Required  bool                    `json:"required"`
+	MinSelect int                     `json:"min_select" binding:"min=0"`
+	MaxSelect int                     `json:"max_select" binding:"min=0"`
+	Position  int                     `json:"position"`
+	Options   []ModifierOptionRequest `json:"options" binding:"required,min=1,dive"`
+}
+
+// ModifierOptionRequest updates the option with ID when set, otherwise adds a new one.
+type ModifierOptionRequest struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name" binding:"required"`
+	PriceDelta float64 `json:"price_delta"`
+	Available  *bool   `json:"available"`
+	Position   int     `json:"position"`
+}
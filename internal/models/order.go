@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,23 +19,83 @@ const (
 )
 
 type Order struct {
-	ID          string      `json:"id" db:"id"`
-	CustomerID  string      `json:"customer_id" db:"customer_id"`
-	Items       []OrderItem `json:"items" db:"items"`
-	TotalAmount float64     `json:"total_amount" db:"total_amount"`
-	Status      OrderStatus `json:"status" db:"status"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+	ID string `json:"id" db:"id"`
+	// OrderNumber counts up from 1 each business day at the branch; DisplayNumber is
+	// the form shown to people, e.g. "#0042".
+	OrderNumber   int    `json:"order_number" db:"order_number"`
+	DisplayNumber string `json:"display_number"`
+	BranchID      string `json:"branch_id" db:"branch_id"`
+	BusinessDay   string `json:"business_day" db:"business_day"`
+	// CustomerID is empty for orders placed by guests at a table; see Source.
+	CustomerID string      `json:"customer_id" db:"customer_id"`
+	Source     OrderSource `json:"source" db:"source"`
+	Type       OrderType   `json:"type" db:"order_type"`
+	// TableID and GuestCount are set for dine-in orders.
+	TableID    string `json:"table_id,omitempty" db:"table_id"`
+	GuestCount int    `json:"guest_count,omitempty" db:"guest_count"`
+	// PickupAt is when a takeaway customer wants to collect, if they said.
+	PickupAt *time.Time `json:"pickup_at,omitempty" db:"pickup_at"`
+	// DeliveryAddress and the contact are set for delivery orders.
+	DeliveryAddress string      `json:"delivery_address,omitempty" db:"delivery_address"`
+	ContactName     string      `json:"contact_name,omitempty" db:"contact_name"`
+	ContactPhone    string      `json:"contact_phone,omitempty" db:"contact_phone"`
+	Items           []OrderItem `json:"items" db:"items"`
+	TotalAmount     float64     `json:"total_amount" db:"total_amount"`
+	Status          OrderStatus `json:"status" db:"status"`
+	Notes           string      `json:"notes,omitempty" db:"notes"`
+	Allergens       []Allergen  `json:"allergens,omitempty" db:"allergens"`
+	// PayOnDelivery lets the order be confirmed before it is paid.
+	PayOnDelivery bool      `json:"pay_on_delivery" db:"pay_on_delivery"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+
+	// Alerts summarizes notes and allergies for the kitchen; see BuildAlerts.
+	Alerts       []string `json:"alerts,omitempty"`
+	AllergyAlert bool     `json:"allergy_alert,omitempty"`
+	// Estimate is when the kitchen expects the order to be ready, while it waits for
+	// or is in the kitchen.
+	Estimate *ReadyEstimate `json:"estimate,omitempty"`
+	// HeldCourses lists the courses still on hold in kitchen views; see HideHeldItems.
+	HeldCourses []int `json:"held_courses,omitempty"`
+}
+
+// OrderStatusUpdate is what a customer is told when one of their orders changes
+// status; the full order goes to staff only.
+type OrderStatusUpdate struct {
+	ID            string      `json:"id"`
+	DisplayNumber string      `json:"display_number"`
+	Status        OrderStatus `json:"status"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
+
+// StatusUpdate returns the order's status as its customer sees it.
+func (o *Order) StatusUpdate() *OrderStatusUpdate {
+	return &OrderStatusUpdate{ID: o.ID, DisplayNumber: o.DisplayNumber, Status: o.Status, UpdatedAt: o.UpdatedAt}
 }
 
 type OrderItem struct {
-	ID         string  `json:"id" db:"id"`
-	OrderID    string  `json:"order_id" db:"order_id"`
-	MenuItemID string  `json:"menu_item_id" db:"menu_item_id"`
-	Name       string  `json:"name" db:"name"`
-	Price      float64 `json:"price" db:"price"`
-	Quantity   int     `json:"quantity" db:"quantity"`
-	TotalPrice float64 `json:"total_price" db:"total_price"`
+	ID         string     `json:"id" db:"id"`
+	OrderID    string     `json:"order_id" db:"order_id"`
+	MenuItemID string     `json:"menu_item_id" db:"menu_item_id"`
+	Name       string     `json:"name" db:"name"`
+	Price      float64    `json:"price" db:"price"`
+	Quantity   int        `json:"quantity" db:"quantity"`
+	TotalPrice float64    `json:"total_price" db:"total_price"`
+	Notes      string     `json:"notes,omitempty" db:"notes"`
+	Allergens  []Allergen `json:"allergens,omitempty" db:"allergens"`
+	// Station is copied from the menu item when the order is placed.
+	Station    Station        `json:"station" db:"station"`
+	PrepStatus ItemPrepStatus `json:"prep_status" db:"prep_status"`
+	// PrepMinutes is copied from the menu item too, so estimates don't shift under
+	// orders already placed when the menu changes.
+	PrepMinutes int `json:"prep_minutes" db:"prep_minutes"`
+	// Course numbers the courses of a dine-in meal from 1. Held items are kept off
+	// the kitchen screens until their course is fired.
+	Course  int        `json:"course" db:"course"`
+	Held    bool       `json:"held" db:"held"`
+	FiredAt *time.Time `json:"fired_at,omitempty" db:"fired_at"`
+
+	Modifiers []OrderItemModifier `json:"modifiers,omitempty"`
 }
 
 type MenuItem struct {
@@ -41,19 +104,99 @@ type MenuItem struct {
 	Description string  `json:"description" db:"description"`
 	Price       float64 `json:"price" db:"price"`
 	Category    string  `json:"category" db:"category"`
+	Station     Station `json:"station" db:"station"`
+	PrepMinutes int     `json:"prep_minutes" db:"prep_minutes"`
 	Available   bool    `json:"available" db:"available"`
+
+	ModifierGroups []*ModifierGroup `json:"modifier_groups,omitempty"`
 }
 
 type CreateOrderRequest struct {
-	CustomerID string            `json:"customer_id" binding:"required"`
-	Items      []CreateOrderItem `json:"items" binding:"required"`
+	// Source and TableTokenID are set by the server; TableTokenID only for guest orders.
+	CustomerID   string      `json:"-"`
+	Source       OrderSource `json:"-"`
+	TableTokenID string      `json:"-"`
+
+	Items     []CreateOrderItem `json:"items" binding:"required,min=1,dive"`
+	Notes     string            `json:"notes" binding:"max=500"`
+	Allergens []Allergen        `json:"allergens"`
+	// PayOnDelivery asks to settle in cash when the food is handed over. Only delivery
+	// orders may ask, and only when the branch takes cash on delivery.
+	PayOnDelivery bool `json:"pay_on_delivery"`
+
+	// Type defaults to takeaway; see ValidateType for the fields each type needs.
+	Type            OrderType  `json:"type"`
+	TableID         string     `json:"table_id"`
+	GuestCount      int        `json:"guest_count"`
+	PickupAt        *time.Time `json:"pickup_at"`
+	DeliveryAddress string     `json:"delivery_address" binding:"max=500"`
+	ContactName     string     `json:"contact_name" binding:"max=100"`
+	ContactPhone    string     `json:"contact_phone" binding:"max=20"`
 }
 
 type CreateOrderItem struct {
 	MenuItemID string `json:"menu_item_id" binding:"required"`
 	Quantity   int    `json:"quantity" binding:"required,min=1"`
+	// Modifiers are the IDs of the chosen modifier options.
+	Modifiers []string   `json:"modifiers"`
+	Notes     string     `json:"notes" binding:"max=200"`
+	Allergens []Allergen `json:"allergens"`
+	// Course defaults to 1. Hold keeps the item from the kitchen until its course
+	// is fired; only dine-in orders can hold items.
+	Course int  `json:"course" binding:"omitempty,min=1,max=9"`
+	Hold   bool `json:"hold"`
 }
 
 type UpdateOrderStatusRequest struct {
 	Status OrderStatus `json:"status" binding:"required"`
+	Reason string      `json:"reason" binding:"max=200"`
+}
+
+// OrderEvent records one status change of an order. FromStatus is empty for the
+// event recorded when the order is created.
+type OrderEvent struct {
+	ID             string      `json:"id" db:"id"`
+	OrderID        string      `json:"order_id" db:"order_id"`
+	FromStatus     OrderStatus `json:"from_status,omitempty" db:"from_status"`
+	ToStatus       OrderStatus `json:"to_status" db:"to_status"`
+	ActorAccountID string      `json:"actor_account_id,omitempty" db:"actor_account_id"`
+	DeviceID       string      `json:"device_id,omitempty" db:"device_id"`
+	Reason         string      `json:"reason,omitempty" db:"reason"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+}
+
+// FormatOrderNumber returns the display form of a daily order number, e.g. "#0042".
+func FormatOrderNumber(number int) string {
+	return fmt.Sprintf("#%04d", number)
+}
+
+// ParseOrderNumber accepts a daily order number as typed by staff: "42", "0042" or "#0042".
+func ParseOrderNumber(s string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid order number: %s", s)
+	}
+	return number, nil
+}
+
+// BuildAlerts fills Alerts with one line per allergy or note on the order and its
+// items, allergies first, so the line cooks can't miss them.
+func (o *Order) BuildAlerts() {
+	var allergies, notes []string
+	if len(o.Allergens) > 0 {
+		allergies = append(allergies, "ALLERGY (whole order): "+joinAllergens(o.Allergens))
+	}
+	if note := strings.TrimSpace(o.Notes); note != "" {
+		notes = append(notes, "NOTE: "+note)
+	}
+	for _, item := range o.Items {
+		if len(item.Allergens) > 0 {
+			allergies = append(allergies, "ALLERGY ("+item.Name+"): "+joinAllergens(item.Allergens))
+		}
+		if note := strings.TrimSpace(item.Notes); note != "" {
+			notes = append(notes, item.Name+": "+note)
+		}
+	}
+	o.Alerts = append(allergies, notes...)
+	o.AllergyAlert = len(allergies) > 0
 }
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type OrderSort string
+
+const (
+	OrderSortNewest    OrderSort = "newest"
+	OrderSortOldest    OrderSort = "oldest"
+	OrderSortTotalDesc OrderSort = "total_desc"
+	OrderSortTotalAsc  OrderSort = "total_asc"
+)
+
+const (
+	DefaultOrderPageSize = 50
+	MaxOrderPageSize     = 200
+)
+
+// PaymentState summarizes the payments of an order for filtering.
+type PaymentState string
+
+const (
+	// PaymentStatePaid orders have a completed payment.
+	PaymentStatePaid PaymentState = "paid"
+	// PaymentStateUnpaid orders have no completed or refunded payment.
+	PaymentStateUnpaid PaymentState = "unpaid"
+	// PaymentStateRefunded orders have a payment that was, or is being, refunded.
+	PaymentStateRefunded PaymentState = "refunded"
+)
+
+// OrderListQuery filters, sorts and pages an order listing. Every filter is optional.
+type OrderListQuery struct {
+	// Number looks up a daily order number on BusinessDay (YYYY-MM-DD); see Order.OrderNumber.
+	Number       int
+	BusinessDay  string
+	Statuses     []OrderStatus
+	Types        []OrderType
+	Source       OrderSource
+	CustomerID   string
+	From         *time.Time
+	To           *time.Time
+	PaymentState PaymentState
+	Sort         OrderSort
+	Limit        int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// Normalize validates the query and fills in defaults.
+func (q *OrderListQuery) Normalize() error {
+	for _, status := range q.Statuses {
+		if !status.Valid() {
+			return fmt.Errorf("invalid order status: %s", status)
+		}
+	}
+	for _, orderType := range q.Types {
+		if !orderType.Valid() {
+			return fmt.Errorf("invalid order type: %s", orderType)
+		}
+	}
+	if q.Source != "" && !q.Source.Valid() {
+		return fmt.Errorf("invalid order source: %s", q.Source)
+	}
+	switch q.PaymentState {
+	case "", PaymentStatePaid, PaymentStateUnpaid, PaymentStateRefunded:
+	default:
+		return fmt.Errorf("invalid payment state: %s", q.PaymentState)
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = OrderSortNewest
+	case OrderSortNewest, OrderSortOldest, OrderSortTotalDesc, OrderSortTotalAsc:
+	default:
+		return fmt.Errorf("invalid sort: %s", q.Sort)
+	}
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		return fmt.Errorf("to must not be before from")
+	}
+	if q.Number > 0 {
+		if _, err := time.Parse("2006-01-02", q.BusinessDay); err != nil {
+			return fmt.Errorf("invalid business day: %s", q.BusinessDay)
+		}
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultOrderPageSize
+	}
+	if q.Limit > MaxOrderPageSize {
+		q.Limit = MaxOrderPageSize
+	}
+	return nil
+}
+
+// ParseOrderStatuses splits a comma separated status list such as "confirmed,preparing".
+func ParseOrderStatuses(list string) []OrderStatus {
+	var statuses []OrderStatus
+	for _, s := range splitList(list) {
+		statuses = append(statuses, OrderStatus(s))
+	}
+	return statuses
+}
+
+// ParseOrderTypes splits a comma separated order type list such as "takeaway,delivery".
+func ParseOrderTypes(list string) []OrderType {
+	var types []OrderType
+	for _, s := range splitList(list) {
+		types = append(types, OrderType(s))
+	}
+	return types
+}
+
+func splitList(list string) []string {
+	var out []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,87 @@
+package models
+
+// AllOrderStatuses lists every order status in lifecycle order.
+var AllOrderStatuses = []OrderStatus{
+	OrderStatusPending, OrderStatusConfirmed, OrderStatusPreparing,
+	OrderStatusReady, OrderStatusCompleted, OrderStatusCancelled,
+}
+
+func (s OrderStatus) Valid() bool {
+	for _, status := range AllOrderStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal reports whether no further transitions are possible from s.
+func (s OrderStatus) Terminal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// orderTransitions is the order state machine: each status maps to the statuses it
+// may move to, and each move to the roles allowed to make it.
+// Customers may only make a move on their own orders. Their only move, cancelling a
+// pending order, is made through POST /orders/:id/cancel: PUT /orders/:id/status is
+// staff only and never cancels.
+var orderTransitions = map[OrderStatus]map[OrderStatus][]Role{
+	OrderStatusPending: {
+		OrderStatusConfirmed: {RoleWaiter, RoleCashier, RoleManager, RoleAdmin},
+		OrderStatusCancelled: {RoleCustomer, RoleWaiter, RoleCashier, RoleManager, RoleAdmin},
+	},
+	OrderStatusConfirmed: {
+		OrderStatusPreparing: {RoleKitchen, RoleManager, RoleAdmin},
+		OrderStatusCancelled: {RoleCashier, RoleManager, RoleAdmin},
+	},
+	OrderStatusPreparing: {
+		OrderStatusReady:     {RoleKitchen, RoleManager, RoleAdmin},
+		OrderStatusCancelled: {RoleManager, RoleAdmin},
+	},
+	OrderStatusReady: {
+		// Back to preparing when the kitchen recalls an item
+		OrderStatusPreparing: {RoleKitchen, RoleManager, RoleAdmin},
+		OrderStatusCompleted: {RoleWaiter, RoleCashier, RoleKitchen, RoleManager, RoleAdmin},
+	},
+	OrderStatusCompleted: {},
+	OrderStatusCancelled: {},
+}
+
+// CanTransition reports whether the state machine has a move from one status to another.
+func CanTransition(from, to OrderStatus) bool {
+	_, ok := orderTransitions[from][to]
+	return ok
+}
+
+// NextStatuses returns the statuses an order in status s may move to.
+func NextStatuses(s OrderStatus) []OrderStatus {
+	var next []OrderStatus
+	for _, status := range AllOrderStatuses {
+		if CanTransition(s, status) {
+			next = append(next, status)
+		}
+	}
+	return next
+}
+
+// MayTransition reports whether actor is allowed to move an order from one status to
+// another. isOwner tells whether the actor placed the order. The system actor may make
+// any move the state machine allows.
+func (a *Actor) MayTransition(from, to OrderStatus, isOwner bool) bool {
+	roles, ok := orderTransitions[from][to]
+	if !ok || a == nil {
+		return false
+	}
+	if a.System {
+		return true
+	}
+	for _, role := range roles {
+		if role == RoleCustomer && !isOwner {
+			continue
+		}
+		if a.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderType is how the food leaves the kitchen.
+type OrderType string
+
+const (
+	OrderTypeDineIn   OrderType = "dine_in"
+	OrderTypeTakeaway OrderType = "takeaway"
+	OrderTypeDelivery OrderType = "delivery"
+)
+
+var AllOrderTypes = []OrderType{OrderTypeDineIn, OrderTypeTakeaway, OrderTypeDelivery}
+
+func (t OrderType) Valid() bool {
+	for _, orderType := range AllOrderTypes {
+		if orderType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// SettledAtTable reports whether orders of the type are paid at the end of the meal,
+// so they go to the kitchen before any payment.
+func (t OrderType) SettledAtTable() bool {
+	return t == OrderTypeDineIn
+}
+
+const (
+	maxGuestCount = 100
+	// pickupSlack tolerates clock skew between the client and the server.
+	pickupSlack = 5 * time.Minute
+)
+
+// ValidateType defaults the order type to takeaway and checks that exactly the fields
+// belonging to the type are set: a table and guest count for dine-in, an optional
+// pickup time for takeaway and an address and contact phone for delivery.
+func (r *CreateOrderRequest) ValidateType(now time.Time) error {
+	if r.Type == "" {
+		r.Type = OrderTypeTakeaway
+	}
+	r.TableID = strings.TrimSpace(r.TableID)
+	r.DeliveryAddress = strings.TrimSpace(r.DeliveryAddress)
+	r.ContactName = strings.TrimSpace(r.ContactName)
+	r.ContactPhone = strings.TrimSpace(r.ContactPhone)
+
+	dineIn := r.TableID != "" || r.GuestCount != 0
+	delivery := r.DeliveryAddress != "" || r.ContactName != ""
+
+	switch r.Type {
+	case OrderTypeDineIn:
+		if r.TableID == "" {
+			return fmt.Errorf("dine-in orders need a table_id")
+		}
+		if r.GuestCount < 1 || r.GuestCount > maxGuestCount {
+			return fmt.Errorf("dine-in orders need a guest_count between 1 and %d", maxGuestCount)
+		}
+		if r.PickupAt != nil || delivery {
+			return fmt.Errorf("dine-in orders cannot have a pickup time or delivery details")
+		}
+	case OrderTypeTakeaway:
+		if r.PickupAt != nil && r.PickupAt.Before(now.Add(-pickupSlack)) {
+			return fmt.Errorf("pickup_at must not be in the past")
+		}
+		if dineIn || delivery {
+			return fmt.Errorf("takeaway orders cannot have a table or delivery details")
+		}
+	case OrderTypeDelivery:
+		if r.DeliveryAddress == "" {
+			return fmt.Errorf("delivery orders need a delivery_address")
+		}
+		if r.ContactPhone == "" {
+			return fmt.Errorf("delivery orders need a contact_phone")
+		}
+		if dineIn || r.PickupAt != nil {
+			return fmt.Errorf("delivery orders cannot have a table or pickup time")
+		}
+	default:
+		return fmt.Errorf("invalid order type: %s", r.Type)
+	}
+	return nil
+}
+
+// OrderGroup is a set of orders of one type, as shown on the kitchen screen.
+type OrderGroup struct {
+	Type   OrderType `json:"type"`
+	Orders []*Order  `json:"orders"`
+}
+
+// GroupOrdersByType splits orders by type, keeping their order within each group.
+// Every type gets a group, even an empty one.
+func GroupOrdersByType(orders []*Order) []OrderGroup {
+	groups := make([]OrderGroup, len(AllOrderTypes))
+	for i, orderType := range AllOrderTypes {
+		groups[i] = OrderGroup{Type: orderType, Orders: []*Order{}}
+		for _, order := range orders {
+			if order.Type == orderType {
+				groups[i].Orders = append(groups[i].Orders, order)
+			}
+		}
+	}
+	return groups
+}
@@ -5,14 +5,18 @@ import "time"
 type OTP struct {
     ID          string    `json:"id"`
     PhoneNumber string    `json:"phone_number"`
-    Code        string    `json:"code"`
+    CodeHash    string    `json:"-"`
+    Attempts    int       `json:"attempts"`
     ExpiresAt   time.Time `json:"expires_at"`
+    UsedAt      *time.Time `json:"used_at,omitempty"`
     CreatedAt   time.Time `json:"created_at"`
 }
 
 type RequestOTPRequest struct {
     PhoneNumber string `json:"phone_number" binding:"required"`
     DeviceID    string `json:"device_id" binding:"required"`
+    // Locale selects the SMS language ("en" or "am"); empty uses the configured default.
+    Locale      string `json:"locale,omitempty"`
 }
 
 type VerifyOTPRequest struct {
@@ -12,6 +12,8 @@ const (
 	PaymentStatusCompleted PaymentStatus = "completed"
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
+	PaymentStatusRefundPending PaymentStatus = "refund_pending"
+	PaymentStatusRefunded  PaymentStatus = "refunded"
 )
 
 type PaymentMethod string
@@ -49,5 +51,7 @@ type PaymentResponse struct {
 	TransactionID string        `json:"transaction_id,omitempty"`
 	Message       string        `json:"message,omitempty"`
 	CheckoutURL   string        `json:"checkout_url,omitempty"`
+	// OrderConfirmed is set when the payment sent its order to the kitchen.
+	OrderConfirmed bool `json:"order_confirmed,omitempty"`
 }
 
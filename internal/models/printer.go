@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+type PrinterKind string
+
+const (
+	// PrinterKindNetwork printers take raw ESC/POS over TCP, usually on port 9100.
+	PrinterKindNetwork PrinterKind = "network"
+	// PrinterKindFile printers write each job to a file, for tests and setups
+	// without a printer.
+	PrinterKindFile PrinterKind = "file"
+)
+
+func (k PrinterKind) Valid() bool {
+	return k == PrinterKindNetwork || k == PrinterKindFile
+}
+
+// DefaultPrinterColumns fits 80 mm paper at the normal font.
+const DefaultPrinterColumns = 48
+
+// Printer is a thermal printer. It prints the kitchen tickets of its Stations and,
+// when Receipts is set, customer receipts.
+type Printer struct {
+	ID   string      `json:"id" db:"id"`
+	Name string      `json:"name" db:"name"`
+	Kind PrinterKind `json:"kind" db:"kind"`
+	// Address is host or host:port for network printers, and the spool directory
+	// for file printers, under the configured print spool directory.
+	Address  string    `json:"address" db:"address"`
+	Stations []Station `json:"stations" db:"stations"`
+	Receipts bool      `json:"receipts" db:"receipts"`
+	// Columns is how many characters fit on a line: 48 for 80 mm paper, 32 for 58 mm.
+	Columns   int       `json:"columns" db:"line_width"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PrintsStation reports whether the printer prints tickets for station.
+func (p *Printer) PrintsStation(station Station) bool {
+	for _, s := range p.Stations {
+		if s == station {
+			return true
+		}
+	}
+	return false
+}
+
+type CreatePrinterRequest struct {
+	Name     string      `json:"name" binding:"required,max=100"`
+	Kind     PrinterKind `json:"kind" binding:"required"`
+	Address  string      `json:"address" binding:"max=200"`
+	Stations []Station   `json:"stations"`
+	Receipts bool        `json:"receipts"`
+	Columns  int         `json:"columns" binding:"omitempty,min=24,max=64"`
+	Enabled  *bool       `json:"enabled"`
+}
+
+// UpdatePrinterRequest changes only the fields that are set.
+type UpdatePrinterRequest struct {
+	Name     *string      `json:"name" binding:"omitempty,max=100"`
+	Kind     *PrinterKind `json:"kind"`
+	Address  *string      `json:"address" binding:"omitempty,max=200"`
+	Stations *[]Station   `json:"stations"`
+	Receipts *bool        `json:"receipts"`
+	Columns  *int         `json:"columns" binding:"omitempty,min=24,max=64"`
+	Enabled  *bool        `json:"enabled"`
+}
+
+// PrinterStatus is how a printer's queue is doing. Online is false from the first
+// failed attempt until the next job prints. LostJob names the last job given up on.
+type PrinterStatus struct {
+	PrinterID     string     `json:"printer_id"`
+	Name          string     `json:"name"`
+	Enabled       bool       `json:"enabled"`
+	Online        bool       `json:"online"`
+	Queued        int        `json:"queued"`
+	Printed       int        `json:"printed"`
+	Failed        int        `json:"failed"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LostJob       string     `json:"lost_job,omitempty"`
+	LastPrintedAt *time.Time `json:"last_printed_at,omitempty"`
+}
@@ -0,0 +1,158 @@
+package models
+
+import "time"
+
+type ReservationStatus string
+
+const (
+	ReservationStatusBooked    ReservationStatus = "booked"
+	ReservationStatusSeated    ReservationStatus = "seated"
+	ReservationStatusCompleted ReservationStatus = "completed"
+	ReservationStatusCancelled ReservationStatus = "cancelled"
+	ReservationStatusNoShow    ReservationStatus = "no_show"
+)
+
+var AllReservationStatuses = []ReservationStatus{
+	ReservationStatusBooked, ReservationStatusSeated, ReservationStatusCompleted,
+	ReservationStatusCancelled, ReservationStatusNoShow,
+}
+
+func (s ReservationStatus) Valid() bool {
+	for _, status := range AllReservationStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// reservationTransitions lists the statuses each reservation status may move to.
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	ReservationStatusBooked: {ReservationStatusSeated, ReservationStatusCancelled, ReservationStatusNoShow},
+	ReservationStatusSeated: {ReservationStatusCompleted},
+}
+
+// CanMoveTo reports whether a reservation in status s may move to status to.
+func (s ReservationStatus) CanMoveTo(to ReservationStatus) bool {
+	for _, status := range reservationTransitions[s] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Reservation books a table for a party. The table is held from ReservedAt until
+// EndsAt, which allows for the party's turn time.
+type Reservation struct {
+	ID        string `json:"id" db:"id"`
+	AccountID string `json:"account_id" db:"account_id"`
+	// PreviousVisits is how many times the guest had visited when they booked;
+	// ReturningGuest is set when that is at least once.
+	PreviousVisits int               `json:"previous_visits" db:"previous_visits"`
+	ReturningGuest bool              `json:"returning_guest"`
+	PhoneNumber    string            `json:"phone_number" db:"phone_number"`
+	Name           string            `json:"name,omitempty" db:"name"`
+	PartySize      int               `json:"party_size" db:"party_size"`
+	ReservedAt     time.Time         `json:"reserved_at" db:"reserved_at"`
+	EndsAt         time.Time         `json:"ends_at" db:"ends_at"`
+	TableID        string            `json:"table_id" db:"table_id"`
+	Notes          string            `json:"notes,omitempty" db:"notes"`
+	Status         ReservationStatus `json:"status" db:"status"`
+	Locale         string            `json:"locale,omitempty" db:"locale"`
+	CreatedBy      string            `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// CreateReservationRequest books a table. Without a TableID the smallest free table
+// that seats the party is chosen.
+type CreateReservationRequest struct {
+	PhoneNumber string    `json:"phone_number" binding:"required,max=20"`
+	Name        string    `json:"name" binding:"max=100"`
+	PartySize   int       `json:"party_size" binding:"required,min=1"`
+	ReservedAt  time.Time `json:"reserved_at" binding:"required"`
+	TableID     string    `json:"table_id"`
+	Notes       string    `json:"notes" binding:"max=500"`
+	// Locale selects the SMS language ("en" or "am"); empty uses the configured default.
+	Locale string `json:"locale"`
+}
+
+// UpdateReservationRequest changes only the fields that are set. Changing the time,
+// party size or table checks availability again.
+type UpdateReservationRequest struct {
+	Name       *string    `json:"name" binding:"omitempty,max=100"`
+	PartySize  *int       `json:"party_size" binding:"omitempty,min=1"`
+	ReservedAt *time.Time `json:"reserved_at"`
+	TableID    *string    `json:"table_id" binding:"omitempty,min=1"`
+	Notes      *string    `json:"notes" binding:"omitempty,max=500"`
+}
+
+type SetReservationStatusRequest struct {
+	Status ReservationStatus `json:"status" binding:"required"`
+}
+
+// Availability lists the tables that can take a party at a time. When none can,
+// Alternatives suggests nearby times that work.
+type Availability struct {
+	PartySize    int            `json:"party_size"`
+	At           time.Time      `json:"at"`
+	Until        time.Time      `json:"until"`
+	Tables       []*DiningTable `json:"tables"`
+	Alternatives []time.Time    `json:"alternatives,omitempty"`
+}
+
+type WaitlistStatus string
+
+const (
+	// WaitlistStatusWaiting parties are in the queue.
+	WaitlistStatusWaiting WaitlistStatus = "waiting"
+	// WaitlistStatusNotified parties were told their table is ready.
+	WaitlistStatusNotified WaitlistStatus = "notified"
+	WaitlistStatusSeated   WaitlistStatus = "seated"
+	// WaitlistStatusLeft parties gave up or were removed.
+	WaitlistStatusLeft WaitlistStatus = "left"
+)
+
+// Open reports whether a party in status s is still in the queue.
+func (s WaitlistStatus) Open() bool {
+	return s == WaitlistStatusWaiting || s == WaitlistStatusNotified
+}
+
+// WaitlistEntry is a walk-in party waiting for a table.
+type WaitlistEntry struct {
+	ID             string         `json:"id" db:"id"`
+	AccountID      string         `json:"account_id" db:"account_id"`
+	PreviousVisits int            `json:"previous_visits" db:"previous_visits"`
+	ReturningGuest bool           `json:"returning_guest"`
+	PhoneNumber    string         `json:"phone_number" db:"phone_number"`
+	Name           string         `json:"name,omitempty" db:"name"`
+	PartySize      int            `json:"party_size" db:"party_size"`
+	Notes          string         `json:"notes,omitempty" db:"notes"`
+	Status         WaitlistStatus `json:"status" db:"status"`
+	Locale         string         `json:"locale,omitempty" db:"locale"`
+	// QuotedWaitMinutes is the wait the party was told when they joined.
+	QuotedWaitMinutes int `json:"quoted_wait_minutes" db:"quoted_wait_minutes"`
+	// Position and EstimatedWaitMinutes are worked out afresh on every read while
+	// the party is waiting.
+	Position             int        `json:"position,omitempty"`
+	EstimatedWaitMinutes int        `json:"estimated_wait_minutes"`
+	TableID              string     `json:"table_id,omitempty" db:"table_id"`
+	NotifiedAt           *time.Time `json:"notified_at,omitempty" db:"notified_at"`
+	SeatedAt             *time.Time `json:"seated_at,omitempty" db:"seated_at"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type JoinWaitlistRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,max=20"`
+	Name        string `json:"name" binding:"max=100"`
+	PartySize   int    `json:"party_size" binding:"required,min=1"`
+	Notes       string `json:"notes" binding:"max=500"`
+	Locale      string `json:"locale"`
+}
+
+// WaitlistTableRequest names the table a waiting party is offered or seated at.
+type WaitlistTableRequest struct {
+	TableID string `json:"table_id" binding:"required"`
+}
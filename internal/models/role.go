@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleCashier  Role = "cashier"
+	RoleWaiter   Role = "waiter"
+	RoleKitchen  Role = "kitchen"
+	RoleManager  Role = "manager"
+	RoleAdmin    Role = "admin"
+)
+
+// AllRoles lists every role known to the system, least privileged first.
+var AllRoles = []Role{RoleCustomer, RoleWaiter, RoleCashier, RoleKitchen, RoleManager, RoleAdmin}
+
+func (r Role) Valid() bool {
+	for _, role := range AllRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+type Permission string
+
+const (
+	PermOrdersCreate        Permission = "orders:create"
+	PermOrdersViewAll       Permission = "orders:view_all"
+	PermOrdersUpdateStatus  Permission = "orders:update_status"
+	PermKitchenAccess       Permission = "kitchen:access"
+	PermPaymentsRecordCash  Permission = "payments:record_cash"
+	PermRolesManage         Permission = "roles:manage"
+	PermDevicesManage       Permission = "devices:manage"
+	PermMenuManage          Permission = "menu:manage"
+	PermOrdersApproveCancel Permission = "orders:approve_cancel"
+	// PermTablesOperate covers seating and clearing tables; PermTablesManage editing the floor plan.
+	PermTablesOperate Permission = "tables:operate"
+	PermTablesManage  Permission = "tables:manage"
+	// PermReservationsManage covers taking reservations and running the waitlist.
+	PermReservationsManage Permission = "reservations:manage"
+)
+
+// DefaultRolePermissions is the permission set seeded for each role.
+// Admin is granted every permission.
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleCustomer: {PermOrdersCreate},
+	RoleCashier: {PermOrdersCreate, PermOrdersViewAll, PermOrdersUpdateStatus, PermPaymentsRecordCash, PermTablesOperate,
+		PermReservationsManage},
+	RoleWaiter:  {PermOrdersCreate, PermOrdersViewAll, PermOrdersUpdateStatus, PermTablesOperate, PermReservationsManage},
+	RoleKitchen: {PermOrdersViewAll, PermKitchenAccess},
+	RoleManager: {PermOrdersCreate, PermOrdersViewAll, PermOrdersUpdateStatus, PermKitchenAccess, PermPaymentsRecordCash,
+		PermDevicesManage, PermMenuManage, PermOrdersApproveCancel, PermTablesOperate, PermTablesManage, PermReservationsManage},
+	RoleAdmin: {PermOrdersCreate, PermOrdersViewAll, PermOrdersUpdateStatus, PermKitchenAccess,
+		PermPaymentsRecordCash, PermRolesManage, PermDevicesManage, PermMenuManage, PermOrdersApproveCancel,
+		PermTablesOperate, PermTablesManage, PermReservationsManage},
+}
+
+type RoleInfo struct {
+	Name        Role         `json:"name" db:"name"`
+	Description string       `json:"description" db:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+type AccountRole struct {
+	AccountID string    `json:"account_id" db:"account_id"`
+	Role      Role      `json:"role" db:"role"`
+	GrantedBy string    `json:"granted_by,omitempty" db:"granted_by"`
+	GrantedAt time.Time `json:"granted_at" db:"granted_at"`
+}
+
+type GrantRoleRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+// Actor identifies who is performing an operation and what they are allowed to do.
+type Actor struct {
+	AccountID   string       `json:"account_id"`
+	DeviceID    string       `json:"device_id,omitempty"`
+	Roles       []Role       `json:"roles"`
+	Permissions []Permission `json:"permissions"`
+	// System is set for actions the server takes on its own, e.g. after a payment completes.
+	System bool `json:"system,omitempty"`
+}
+
+// SystemActor returns the actor used for changes the server makes on its own behalf.
+func SystemActor() *Actor {
+	return &Actor{System: true}
+}
+
+func (a *Actor) HasRole(role Role) bool {
+	if a == nil {
+		return false
+	}
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Actor) Can(perm Permission) bool {
+	if a == nil {
+		return false
+	}
+	for _, p := range a.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
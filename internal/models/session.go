@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type Session struct {
+	ID        string    `json:"id" db:"id"`
+	AccountID string    `json:"account_id" db:"account_id"`
+	Token     string    `json:"-" db:"token"`
+	DeviceID  string    `json:"device_id" db:"device_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Current marks the session making the request when sessions are listed.
+	Current bool `json:"current"`
+
+	Roles       []Role       `json:"roles,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// Actor returns the identity this session acts as.
+func (s *Session) Actor() *Actor {
+	return &Actor{
+		AccountID:   s.AccountID,
+		DeviceID:    s.DeviceID,
+		Roles:       s.Roles,
+		Permissions: s.Permissions,
+	}
+}
+
+type RefreshSessionResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// Station is the part of the kitchen a menu item is prepared at.
+type Station string
+
+const (
+	StationGrill Station = "grill"
+	StationFryer Station = "fryer"
+	StationCold  Station = "cold"
+	StationBar   Station = "bar"
+)
+
+// AllStations lists the stations in the order their tickets are shown.
+var AllStations = []Station{StationGrill, StationFryer, StationCold, StationBar}
+
+// DefaultStation takes items that were never given a station.
+const DefaultStation = StationGrill
+
+func (s Station) Valid() bool {
+	for _, station := range AllStations {
+		if station == s {
+			return true
+		}
+	}
+	return false
+}
+
+// StationForCategory is the station new menu items of a category go to when none
+// is given. Migrated items are assigned the same way.
+func StationForCategory(category string) Station {
+	switch category {
+	case "Beverage":
+		return StationBar
+	case "Salad", "Dessert":
+		return StationCold
+	default:
+		return DefaultStation
+	}
+}
+
+// StationTicket is the part of an order that one station prepares.
+type StationTicket struct {
+	Station       Station     `json:"station"`
+	OrderID       string      `json:"order_id"`
+	DisplayNumber string      `json:"display_number"`
+	Type          OrderType   `json:"type"`
+	TableID       string      `json:"table_id,omitempty"`
+	Status        OrderStatus `json:"status"`
+	Items         []OrderItem `json:"items"`
+	// Notes and Allergens are the whole order's, since they apply at every station.
+	Notes     string     `json:"notes,omitempty"`
+	Allergens []Allergen `json:"allergens,omitempty"`
+	// Alerts covers only the ticket's own items and the whole order.
+	Alerts       []string  `json:"alerts,omitempty"`
+	AllergyAlert bool      `json:"allergy_alert,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// StationOf returns the station the item is prepared at.
+func (i OrderItem) StationOf() Station {
+	if i.Station == "" {
+		return DefaultStation
+	}
+	return i.Station
+}
+
+// StationTickets splits the order into one ticket per station that has items on
+// it, in AllStations order.
+func (o *Order) StationTickets() []*StationTicket {
+	var tickets []*StationTicket
+	for _, station := range AllStations {
+		if ticket := o.StationTicket(station); ticket != nil {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets
+}
+
+// StationTicket returns the station's ticket for the order, or nil when none of
+// the order's items are prepared there. Held items are left off.
+func (o *Order) StationTicket(station Station) *StationTicket {
+	part := Order{Notes: o.Notes, Allergens: o.Allergens}
+	for _, item := range o.Items {
+		if item.StationOf() == station && !item.Held {
+			part.Items = append(part.Items, item)
+		}
+	}
+	if len(part.Items) == 0 {
+		return nil
+	}
+	part.BuildAlerts()
+
+	return &StationTicket{
+		Station:       station,
+		OrderID:       o.ID,
+		DisplayNumber: o.DisplayNumber,
+		Type:          o.Type,
+		TableID:       o.TableID,
+		Status:        o.Status,
+		Items:         part.Items,
+		Notes:         o.Notes,
+		Allergens:     o.Allergens,
+		Alerts:        part.Alerts,
+		AllergyAlert:  part.AllergyAlert,
+		CreatedAt:     o.CreatedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+type TableState string
+
+const (
+	TableStateFree            TableState = "free"
+	TableStateSeated          TableState = "seated"
+	TableStateOrdered         TableState = "ordered"
+	TableStateAwaitingPayment TableState = "awaiting_payment"
+	TableStateDirty           TableState = "dirty"
+)
+
+var AllTableStates = []TableState{TableStateFree, TableStateSeated, TableStateOrdered, TableStateAwaitingPayment, TableStateDirty}
+
+func (s TableState) Valid() bool {
+	for _, state := range AllTableStates {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Manual reports whether staff may put a table in state s by hand. Ordered and
+// awaiting payment follow from the table's orders and are only set automatically.
+func (s TableState) Manual() bool {
+	return s == TableStateFree || s == TableStateSeated || s == TableStateDirty
+}
+
+// Area is a part of the floor plan, such as the main room or the terrace.
+type Area struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	Tables []*DiningTable `json:"tables,omitempty"`
+}
+
+// DiningTable is a table on the floor plan. Position and size are in floor plan
+// units, with the origin in the top left corner of the area.
+type DiningTable struct {
+	ID       string     `json:"id" db:"id"`
+	AreaID   string     `json:"area_id" db:"area_id"`
+	Name     string     `json:"name" db:"name"`
+	Capacity int        `json:"capacity" db:"capacity"`
+	PosX     float64    `json:"pos_x" db:"pos_x"`
+	PosY     float64    `json:"pos_y" db:"pos_y"`
+	Width    float64    `json:"width" db:"width"`
+	Height   float64    `json:"height" db:"height"`
+	State    TableState `json:"state" db:"state"`
+	// OccupiedSince is when the current party sat down; only orders placed since
+	// then count towards the table's state.
+	OccupiedSince  *time.Time `json:"occupied_since,omitempty" db:"occupied_since"`
+	StateChangedAt time.Time  `json:"state_changed_at" db:"state_changed_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	// QRKey is printed in the table's QR code and proves a guest is at the table.
+	QRKey string `json:"-" db:"qr_key"`
+}
+
+type CreateAreaRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// UpdateAreaRequest changes only the fields that are set.
+type UpdateAreaRequest struct {
+	Name      *string `json:"name" binding:"omitempty,min=1,max=100"`
+	SortOrder *int    `json:"sort_order"`
+}
+
+type CreateTableRequest struct {
+	AreaID   string  `json:"area_id" binding:"required"`
+	Name     string  `json:"name" binding:"required,max=50"`
+	Capacity int     `json:"capacity" binding:"required,min=1"`
+	PosX     float64 `json:"pos_x"`
+	PosY     float64 `json:"pos_y"`
+	Width    float64 `json:"width" binding:"omitempty,gt=0"`
+	Height   float64 `json:"height" binding:"omitempty,gt=0"`
+}
+
+// UpdateTableRequest changes only the fields that are set.
+type UpdateTableRequest struct {
+	AreaID   *string  `json:"area_id" binding:"omitempty,min=1"`
+	Name     *string  `json:"name" binding:"omitempty,min=1,max=50"`
+	Capacity *int     `json:"capacity" binding:"omitempty,min=1"`
+	PosX     *float64 `json:"pos_x"`
+	PosY     *float64 `json:"pos_y"`
+	Width    *float64 `json:"width" binding:"omitempty,gt=0"`
+	Height   *float64 `json:"height" binding:"omitempty,gt=0"`
+}
+
+type SetTableStateRequest struct {
+	State TableState `json:"state" binding:"required"`
+}
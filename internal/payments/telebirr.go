@@ -16,6 +16,10 @@ import (
 	"restaurant-system/internal/config"
 )
 
+// gatewayClient bounds calls to Telebirr, so a stalled gateway cannot hold up a
+// request, or a cancellation's refunds, indefinitely.
+var gatewayClient = &http.Client{Timeout: 30 * time.Second}
+
 type InitiateRequest struct {
 	OutTradeNo  string  `json:"outTradeNo"`
 	Subject     string  `json:"subject"`
@@ -62,7 +66,7 @@ func InitiatePayment(req *InitiateRequest) (*InitiateResponse, error) {
 	httpReq, _ := http.NewRequest(http.MethodPost, apiBase+"/payment/v1/merchantPay", bytes.NewReader(bodyBytes))
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := gatewayClient.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +94,78 @@ func InitiatePayment(req *InitiateRequest) (*InitiateResponse, error) {
 	}, nil
 }
 
+type RefundRequest struct {
+	OutTradeNo   string  `json:"outTradeNo"`
+	TradeNo      string  `json:"tradeNo"`
+	RefundNo     string  `json:"refundRequestNo"`
+	RefundAmount float64 `json:"refundAmount"`
+	Reason       string  `json:"refundReason"`
+}
+
+type RefundResponse struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	RefundID string `json:"refundId,omitempty"`
+}
+
+// RefundPayment refunds a completed Telebirr payment in full.
+func RefundPayment(req *RefundRequest) (*RefundResponse, error) {
+	cfg := config.Payments()
+	apiBase := os.Getenv("TELEBIRR_API_BASE")
+	if apiBase == "" {
+		apiBase = "https://api.telebirr.com"
+	}
+
+	params := map[string]string{
+		"appId":           cfg.MerchantAppID,
+		"outTradeNo":      req.OutTradeNo,
+		"tradeNo":         req.TradeNo,
+		"refundRequestNo": req.RefundNo,
+		"refundAmount":    strconv.FormatFloat(req.RefundAmount, 'f', 2, 64),
+		"refundReason":    req.Reason,
+		"shortCode":       cfg.ShortCode,
+		"nonceStr":        strconv.FormatInt(time.Now().UnixNano(), 10),
+		"timestamp":       strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	params["sign"] = signParams(params, cfg.AppSecret)
+
+	bodyBytes, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, apiBase+"/payment/v1/refund", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := gatewayClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var gatewayResp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			RefundID string `json:"refundOrderId"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gatewayResp); err != nil {
+		return nil, err
+	}
+	if gatewayResp.Code != "SUCCESS" && gatewayResp.Code != "200" {
+		return nil, errors.New(gatewayResp.Msg)
+	}
+	return &RefundResponse{
+		Code:     gatewayResp.Code,
+		Message:  gatewayResp.Msg,
+		RefundID: gatewayResp.Data.RefundID,
+	}, nil
+}
+
 // VerifyCallback verifies Telebirr callback signature.
 func VerifyCallback(m map[string]string) bool {
 	cfg := config.Payments()
@@ -0,0 +1,196 @@
+// Package printing renders kitchen tickets and receipts as ESC/POS byte streams
+// and sends them to thermal printers.
+package printing
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	esc = 0x1b
+	gs  = 0x1d
+)
+
+// Align is a horizontal text alignment.
+type Align byte
+
+const (
+	AlignLeft   Align = 0
+	AlignCenter Align = 1
+	AlignRight  Align = 2
+)
+
+// Doc builds an ESC/POS byte stream. Text is printed with the printer's default
+// code page, so anything outside ASCII is replaced with '?'.
+type Doc struct {
+	buf bytes.Buffer
+	// Columns is how many characters fit on a line at normal size.
+	Columns int
+}
+
+// NewDoc starts a document for a printer with the given line width and resets the
+// printer to its defaults.
+func NewDoc(columns int) *Doc {
+	d := &Doc{Columns: columns}
+	d.buf.Write([]byte{esc, '@'})
+	return d
+}
+
+func (d *Doc) Align(a Align) *Doc {
+	d.buf.Write([]byte{esc, 'a', byte(a)})
+	return d
+}
+
+func (d *Doc) Bold(on bool) *Doc {
+	d.buf.Write([]byte{esc, 'E', boolByte(on)})
+	return d
+}
+
+// Size sets the character size as multiples of normal, from 1 to 8 each way.
+func (d *Doc) Size(width, height int) *Doc {
+	d.buf.Write([]byte{gs, '!', byte(clamp(width, 1, 8)-1)<<4 | byte(clamp(height, 1, 8)-1)})
+	return d
+}
+
+// Line prints s followed by a line feed.
+func (d *Doc) Line(s string) *Doc {
+	d.buf.WriteString(ascii(s))
+	d.buf.WriteByte('\n')
+	return d
+}
+
+// Wrapped prints s over as many lines as it takes at the given character width,
+// with every line after the first indented.
+func (d *Doc) Wrapped(s string, width int, indent string) *Doc {
+	for i, line := range wrap(ascii(s), width-len(indent)) {
+		if i > 0 {
+			line = indent + line
+		}
+		d.Line(line)
+	}
+	return d
+}
+
+// LeftRight prints left and right on one line, right aligned to the line width.
+// A left part too long to fit is wrapped above.
+func (d *Doc) LeftRight(left, right string, width int) *Doc {
+	left, right = ascii(left), ascii(right)
+	lines := wrap(left, width-len(right)-1)
+	for _, line := range lines[:len(lines)-1] {
+		d.Line(line)
+	}
+	last := lines[len(lines)-1]
+	gap := width - len(last) - len(right)
+	if gap < 1 {
+		gap = 1
+	}
+	return d.Line(last + strings.Repeat(" ", gap) + right)
+}
+
+// Rule prints a line of dashes across the paper.
+func (d *Doc) Rule() *Doc {
+	return d.Line(strings.Repeat("-", d.Columns))
+}
+
+// Feed advances the paper n lines.
+func (d *Doc) Feed(n int) *Doc {
+	d.buf.Write([]byte{esc, 'd', byte(clamp(n, 0, 255))})
+	return d
+}
+
+// Cut feeds the paper past the cutter and makes a partial cut.
+func (d *Doc) Cut() *Doc {
+	d.buf.Write([]byte{gs, 'V', 66, 3})
+	return d
+}
+
+func (d *Doc) Bytes() []byte {
+	return d.buf.Bytes()
+}
+
+// ascii replaces runes the printer can't show with '?'.
+func ascii(s string) string {
+	if isASCII(s) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r >= utf8.RuneSelf:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// wrap splits s into lines of at most width characters, breaking at spaces where
+// it can. Leading spaces indent the first line.
+func wrap(s string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " "))]
+	if lead == "" || len(lead) >= width {
+		return wrapWords(s, width)
+	}
+	lines := wrapWords(s, width-len(lead))
+	lines[0] = lead + lines[0]
+	return lines
+}
+
+func wrapWords(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for len(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, word[:width])
+			word = word[width:]
+		}
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	return append(lines, line)
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+	if n > hi {
+		return hi
+	}
+	return n
+}
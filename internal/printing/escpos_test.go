@@ -0,0 +1,84 @@
+package printing
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"short", 10, []string{"short"}},
+		{"exactly ten", 11, []string{"exactly ten"}},
+		{"two words", 5, []string{"two", "words"}},
+		{"a  lot   of\tspace", 20, []string{"a lot of space"}},
+		{"overlongword", 5, []string{"overl", "ongwo", "rd"}},
+		{"hi overlongword", 5, []string{"hi", "overl", "ongwo", "rd"}},
+		{"abc", 0, []string{"a", "b", "c"}},
+		{"  + Extra: cheese", 12, []string{"  + Extra:", "cheese"}},
+		{"      indent", 4, []string{"inde", "nt"}},
+	}
+	for _, tt := range tests {
+		if got := wrap(tt.s, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
+
+// text returns the document's bytes after the initialize command.
+func text(d *Doc) string {
+	return string(bytes.TrimPrefix(d.Bytes(), []byte{esc, '@'}))
+}
+
+func TestLeftRight(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right string
+		width       int
+		want        string
+	}{
+		{"fits", "1 x Tibs", "250.00", 20, "1 x Tibs      250.00\n"},
+		{"exact fit", "abcdefghijklm", "250.00", 20, "abcdefghijklm 250.00\n"},
+		{"left wraps above", "2 x Special kitfo with extra mitmita", "480.00", 20, "2 x Special\nkitfo with\nextra mitmita 480.00\n"},
+		{"right wider than the line", "Tea", "1234567890", 8, "T\ne\na 1234567890\n"},
+	}
+	for _, tt := range tests {
+		if got := text(NewDoc(tt.width).LeftRight(tt.left, tt.right, tt.width)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWrappedIndentsFollowingLines(t *testing.T) {
+	got := text(NewDoc(16).Wrapped("1 x Shiro with injera and salad", 16, "    "))
+	if want := "1 x Shiro\n    with injera\n    and salad\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	if got := text(NewDoc(32).Line("Café\tdoro ወጥ\x07")); got != "Caf? doro ???\n" {
+		t.Errorf("got %q, want %q", got, "Caf? doro ???\n")
+	}
+}
+
+func TestDocCommands(t *testing.T) {
+	got := NewDoc(32).Align(AlignCenter).Bold(true).Size(2, 3).Size(0, 9).Feed(300).Cut().Bytes()
+	want := []byte{
+		esc, '@',
+		esc, 'a', 1,
+		esc, 'E', 1,
+		gs, '!', 0x12,
+		gs, '!', 0x07,
+		esc, 'd', 255,
+		gs, 'V', 66, 3,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
@@ -0,0 +1,79 @@
+package printing
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPort is the raw printing port of network thermal printers.
+const DefaultPort = "9100"
+
+// Printer takes one complete ESC/POS job.
+type Printer interface {
+	Print(data []byte) error
+}
+
+// NetworkPrinter sends jobs over raw TCP, one connection per job.
+type NetworkPrinter struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewNetworkPrinter connects to addr, adding DefaultPort when it has no port.
+func NewNetworkPrinter(addr string, timeout time.Duration) (*NetworkPrinter, error) {
+	if addr == "" {
+		return nil, errors.New("printer address is required")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, DefaultPort)
+	}
+	return &NetworkPrinter{addr: addr, timeout: timeout}, nil
+}
+
+func (p *NetworkPrinter) Print(data []byte) error {
+	conn, err := net.DialTimeout("tcp", p.addr, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("write to %s: %w", p.addr, err)
+	}
+	return nil
+}
+
+// FilePrinter writes each job to its own .bin file in a directory, so tests can
+// read what would have been printed.
+type FilePrinter struct {
+	dir string
+}
+
+func NewFilePrinter(dir string) (*FilePrinter, error) {
+	if dir == "" {
+		return nil, errors.New("print spool directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FilePrinter{dir: dir}, nil
+}
+
+func (p *FilePrinter) Print(data []byte) error {
+	// Write to a temp name first so readers never see a partial job
+	name := fmt.Sprintf("%d-%s.bin", time.Now().UnixNano(), uuid.New().String())
+	tmp := filepath.Join(p.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(p.dir, name))
+}
@@ -0,0 +1,200 @@
+package printing
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+var (
+	ErrQueueFull    = errors.New("printer queue is full")
+	ErrQueueClosed  = errors.New("printer was removed")
+	ErrNoSuchQueue  = errors.New("printer is not set up for printing")
+	errPrintAborted = errors.New("print aborted")
+)
+
+// Job is one document waiting to print.
+type Job struct {
+	// Title says what the job is in logs, e.g. "#0042 grill".
+	Title string
+	Data  []byte
+}
+
+// QueueOptions control how hard a queue tries to print each job.
+type QueueOptions struct {
+	Size int
+	// MaxAttempts is how many times a job is tried before it is given up on.
+	MaxAttempts int
+	// RetryDelay is the wait after the first failed attempt; it doubles after each
+	// further one.
+	RetryDelay time.Duration
+}
+
+// Queue prints jobs on one printer, one at a time and in the order they came.
+// A job that fails is retried before the next one is started, so tickets never
+// come out of order.
+type Queue struct {
+	opts     QueueOptions
+	jobs     chan Job
+	stop     chan struct{}
+	onChange func(models.PrinterStatus)
+
+	mu      sync.Mutex
+	printer Printer
+	status  models.PrinterStatus
+	closed  bool
+}
+
+// NewQueue starts a queue for printer. onChange, if set, is called whenever the
+// printer goes offline or comes back, and whenever a job is given up on.
+func NewQueue(printer Printer, status models.PrinterStatus, opts QueueOptions, onChange func(models.PrinterStatus)) *Queue {
+	if opts.Size < 1 {
+		opts.Size = 1
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = 1
+	}
+	status.Online = true
+	q := &Queue{
+		opts:     opts,
+		jobs:     make(chan Job, opts.Size),
+		stop:     make(chan struct{}),
+		onChange: onChange,
+		printer:  printer,
+		status:   status,
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue adds a job to the queue without waiting for it to print.
+func (q *Queue) Enqueue(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- job:
+		q.status.Queued++
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Status returns a snapshot of the queue's status.
+func (q *Queue) Status() models.PrinterStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.status
+}
+
+// Update points the queue at a new printer connection and name. Jobs already
+// queued print on the new connection.
+func (q *Queue) Update(printer Printer, name string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.printer = printer
+	q.status.Name = name
+}
+
+// Close stops the queue. Jobs still waiting are dropped.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.stop)
+	}
+}
+
+func (q *Queue) run() {
+	for {
+		select {
+		case <-q.stop:
+			return
+		case job := <-q.jobs:
+			err := q.print(job)
+			q.mu.Lock()
+			q.status.Queued--
+			if err == nil {
+				q.mu.Unlock()
+				continue
+			}
+			q.status.Failed++
+			if err == errPrintAborted {
+				q.mu.Unlock()
+				continue
+			}
+			q.status.LostJob = job.Title
+			status := q.status
+			q.mu.Unlock()
+
+			log.Printf("printer %s: gave up on %s: %v", status.Name, job.Title, err)
+			if q.onChange != nil {
+				q.onChange(status)
+			}
+		}
+	}
+}
+
+// print tries a job until it prints, runs out of attempts or the queue is closed.
+func (q *Queue) print(job Job) error {
+	delay := q.opts.RetryDelay
+	var err error
+	for attempt := 1; attempt <= q.opts.MaxAttempts; attempt++ {
+		q.mu.Lock()
+		printer := q.printer
+		q.mu.Unlock()
+
+		if err = printer.Print(job.Data); err == nil {
+			q.recordSuccess()
+			return nil
+		}
+		q.recordFailure(err)
+
+		if attempt < q.opts.MaxAttempts {
+			select {
+			case <-q.stop:
+				return errPrintAborted
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+	}
+	return err
+}
+
+func (q *Queue) recordSuccess() {
+	now := time.Now()
+	q.mu.Lock()
+	changed := !q.status.Online
+	q.status.Online = true
+	q.status.Printed++
+	q.status.LastPrintedAt = &now
+	status := q.status
+	q.mu.Unlock()
+
+	if changed && q.onChange != nil {
+		q.onChange(status)
+	}
+}
+
+func (q *Queue) recordFailure(err error) {
+	now := time.Now()
+	q.mu.Lock()
+	changed := q.status.Online
+	q.status.Online = false
+	q.status.LastError = err.Error()
+	q.status.LastErrorAt = &now
+	status := q.status
+	q.mu.Unlock()
+
+	if changed && q.onChange != nil {
+		q.onChange(status)
+	}
+}
@@ -0,0 +1,270 @@
+package printing
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/models"
+)
+
+// flakyPrinter fails its first failures jobs, then prints into jobs.
+type flakyPrinter struct {
+	mu       sync.Mutex
+	failures int
+	attempts []time.Time
+	jobs     [][]byte
+}
+
+func (p *flakyPrinter) Print(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts = append(p.attempts, time.Now())
+	if len(p.attempts) <= p.failures {
+		return errors.New("paper out")
+	}
+	p.jobs = append(p.jobs, data)
+	return nil
+}
+
+func (p *flakyPrinter) snapshot() ([]time.Time, [][]byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]time.Time(nil), p.attempts...), append([][]byte(nil), p.jobs...)
+}
+
+// statusRecorder collects the statuses a queue reports through onChange.
+type statusRecorder struct {
+	ch chan models.PrinterStatus
+}
+
+func newStatusRecorder() *statusRecorder {
+	return &statusRecorder{ch: make(chan models.PrinterStatus, 16)}
+}
+
+func (r *statusRecorder) onChange(status models.PrinterStatus) {
+	r.ch <- status
+}
+
+func (r *statusRecorder) next(t *testing.T) models.PrinterStatus {
+	t.Helper()
+	select {
+	case status := <-r.ch:
+		return status
+	case <-time.After(2 * time.Second):
+		t.Fatal("no status change reported")
+		return models.PrinterStatus{}
+	}
+}
+
+// waitFor polls the queue until its status satisfies done.
+func waitFor(t *testing.T, q *Queue, done func(models.PrinterStatus) bool) models.PrinterStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := q.Status()
+		if done(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue stuck at %+v", status)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueRetriesWithBackoff(t *testing.T) {
+	printer := &flakyPrinter{failures: 2}
+	events := newStatusRecorder()
+	delay := 20 * time.Millisecond
+	q := NewQueue(printer, models.PrinterStatus{Name: "grill"}, QueueOptions{Size: 4, MaxAttempts: 3, RetryDelay: delay}, events.onChange)
+	defer q.Close()
+
+	if err := q.Enqueue(Job{Title: "#0001 grill", Data: []byte("ticket")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := events.next(t); status.Online || status.LastError != "paper out" {
+		t.Errorf("after the first failure: %+v, want offline with the error", status)
+	}
+	if status := events.next(t); !status.Online {
+		t.Errorf("after printing: %+v, want online", status)
+	}
+	status := waitFor(t, q, func(s models.PrinterStatus) bool { return s.Queued == 0 })
+	if status.Printed != 1 || status.Failed != 0 || status.LostJob != "" || status.LastPrintedAt == nil {
+		t.Errorf("status = %+v, want one printed", status)
+	}
+
+	attempts, jobs := printer.snapshot()
+	if len(attempts) != 3 {
+		t.Fatalf("%d attempts, want 3", len(attempts))
+	}
+	if gap := attempts[1].Sub(attempts[0]); gap < delay {
+		t.Errorf("first retry after %v, want at least %v", gap, delay)
+	}
+	if gap := attempts[2].Sub(attempts[1]); gap < 2*delay {
+		t.Errorf("second retry after %v, want the delay doubled to at least %v", gap, 2*delay)
+	}
+	if len(jobs) != 1 || string(jobs[0]) != "ticket" {
+		t.Errorf("printed %q", jobs)
+	}
+}
+
+func TestQueueReportsJobsItGivesUpOn(t *testing.T) {
+	printer := &flakyPrinter{failures: 2}
+	events := newStatusRecorder()
+	q := NewQueue(printer, models.PrinterStatus{Name: "grill"}, QueueOptions{Size: 4, MaxAttempts: 2, RetryDelay: time.Millisecond}, events.onChange)
+	defer q.Close()
+
+	if err := q.Enqueue(Job{Title: "#0001 grill", Data: []byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(Job{Title: "#0002 grill", Data: []byte("second")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := events.next(t); status.Online {
+		t.Errorf("after the first failure: %+v, want offline", status)
+	}
+	if status := events.next(t); status.LostJob != "#0001 grill" || status.Failed != 1 {
+		t.Errorf("after giving up: %+v, want #0001 reported lost", status)
+	}
+	if status := events.next(t); !status.Online {
+		t.Errorf("after the next job printed: %+v, want online", status)
+	}
+
+	status := waitFor(t, q, func(s models.PrinterStatus) bool { return s.Queued == 0 })
+	if status.Printed != 1 || status.Failed != 1 {
+		t.Errorf("status = %+v, want one printed and one failed", status)
+	}
+	// The second job is printed only after the first is given up on
+	if _, jobs := printer.snapshot(); len(jobs) != 1 || string(jobs[0]) != "second" {
+		t.Errorf("printed %q, want only the second job", jobs)
+	}
+}
+
+// blockingPrinter holds each job until release is closed.
+type blockingPrinter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPrinter) Print([]byte) error {
+	p.started <- struct{}{}
+	<-p.release
+	return nil
+}
+
+func TestQueueFullAndClosed(t *testing.T) {
+	printer := &blockingPrinter{started: make(chan struct{}, 1), release: make(chan struct{})}
+	q := NewQueue(printer, models.PrinterStatus{}, QueueOptions{Size: 1}, nil)
+
+	if err := q.Enqueue(Job{Title: "printing"}); err != nil {
+		t.Fatal(err)
+	}
+	<-printer.started
+	if err := q.Enqueue(Job{Title: "waiting"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(Job{Title: "one too many"}); err != ErrQueueFull {
+		t.Errorf("Enqueue on a full queue = %v, want ErrQueueFull", err)
+	}
+	if got := q.Status().Queued; got != 2 {
+		t.Errorf("Queued = %d, want 2", got)
+	}
+
+	q.Close()
+	close(printer.release)
+	if err := q.Enqueue(Job{Title: "late"}); err != ErrQueueClosed {
+		t.Errorf("Enqueue on a closed queue = %v, want ErrQueueClosed", err)
+	}
+}
+
+func TestFilePrinterWritesEachJob(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	printer, err := NewFilePrinter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{[]byte("one"), []byte("two")} {
+		if err := printer.Print(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("spooled %d files, want 2", len(files))
+	}
+	var got []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(data))
+	}
+	sort.Strings(got)
+	if got[0] != "one" || got[1] != "two" {
+		t.Errorf("spooled %q", got)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("spool directory has %d entries, want no temp files left", len(entries))
+	}
+	if _, err := NewFilePrinter(""); err == nil {
+		t.Error("NewFilePrinter accepted an empty directory")
+	}
+}
+
+func TestSpoolerPrintsTicketsToFiles(t *testing.T) {
+	spoolDir := t.TempDir()
+	spooler := NewSpooler(config.PrintingConfig{SpoolDir: spoolDir, QueueSize: 4, MaxAttempts: 1}, nil)
+	err := spooler.Configure([]*models.Printer{
+		// The address can't point outside the spool directory
+		{ID: "p1", Name: "Grill", Kind: models.PrinterKindFile, Address: "../../grill", Enabled: true},
+		{ID: "p2", Name: "Bar", Kind: models.PrinterKindFile, Address: "bar", Enabled: false},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket := stationTicket(t, testOrder(), models.StationGrill)
+	data := KitchenTicket(ticket, TicketInfo{PrintedAt: printedAt}, 32)
+	if err := spooler.Print("p1", Job{Title: "#0042 grill", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if err := spooler.Print("p2", Job{Title: "#0042 bar"}); err != ErrNoSuchQueue {
+		t.Errorf("Print on a disabled printer = %v, want ErrNoSuchQueue", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	var files []string
+	for len(files) == 0 && time.Now().Before(deadline) {
+		files, _ = filepath.Glob(filepath.Join(spoolDir, "grill", "*.bin"))
+		time.Sleep(time.Millisecond)
+	}
+	if len(files) != 1 {
+		t.Fatalf("spooled %d grill tickets, want 1", len(files))
+	}
+	if got, _ := os.ReadFile(files[0]); !bytes.Equal(got, data) {
+		t.Error("spooled ticket differs from the rendered one")
+	}
+
+	// Removing the printer closes its queue
+	if err := spooler.Configure(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spooler.Status("p1"); ok {
+		t.Error("removed printer still has a queue")
+	}
+}
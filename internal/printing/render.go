@@ -0,0 +1,155 @@
+package printing
+
+import (
+	"fmt"
+	"restaurant-system/internal/models"
+	"strings"
+	"time"
+)
+
+// TicketInfo is what a kitchen ticket shows besides the ticket itself.
+type TicketInfo struct {
+	// TableName is shown for dine-in orders.
+	TableName string
+	PrintedAt time.Time
+	// Reprint marks copies printed on request rather than when the items came in.
+	Reprint bool
+}
+
+// KitchenTicket renders a station ticket for the line: the order number large,
+// then each item with its modifiers, notes and allergies, grouped by course.
+func KitchenTicket(ticket *models.StationTicket, info TicketInfo, columns int) []byte {
+	d := NewDoc(columns)
+	d.Align(AlignCenter).Size(2, 2).Bold(true)
+	d.Line(strings.ToUpper(string(ticket.Station)))
+	d.Line(ticket.DisplayNumber)
+	d.Size(1, 1).Bold(false)
+	if info.Reprint {
+		d.Line("*** REPRINT ***")
+	}
+	d.Line(orderTypeLine(ticket.Type, info.TableName))
+	d.Line(info.PrintedAt.Format("02 Jan 15:04"))
+	d.Align(AlignLeft).Rule()
+
+	// Course headings only matter once a meal has more than one course
+	showCourses := false
+	for _, item := range ticket.Items {
+		showCourses = showCourses || item.Course > 1
+	}
+	course := 0
+	for _, item := range ticket.Items {
+		if showCourses && item.Course != course {
+			d.Bold(true).Line(fmt.Sprintf("-- Course %d --", item.Course)).Bold(false)
+		}
+		course = item.Course
+
+		// Items are printed double height so they can be read from across the pass
+		d.Size(1, 2).Bold(true)
+		d.Wrapped(fmt.Sprintf("%d x %s", item.Quantity, item.Name), columns, "    ")
+		d.Size(1, 1).Bold(false)
+		for _, mod := range item.Modifiers {
+			d.Wrapped("  + "+mod.GroupName+": "+mod.OptionName, columns, "    ")
+		}
+		if note := strings.TrimSpace(item.Notes); note != "" {
+			d.Wrapped("  NOTE: "+note, columns, "    ")
+		}
+		if len(item.Allergens) > 0 {
+			d.Bold(true).Wrapped("  ALLERGY: "+joinAllergens(item.Allergens), columns, "    ").Bold(false)
+		}
+	}
+
+	if len(ticket.Allergens) > 0 || strings.TrimSpace(ticket.Notes) != "" {
+		d.Rule()
+	}
+	if len(ticket.Allergens) > 0 {
+		d.Bold(true).Wrapped("ALLERGY (whole order): "+joinAllergens(ticket.Allergens), columns, "  ").Bold(false)
+	}
+	if note := strings.TrimSpace(ticket.Notes); note != "" {
+		d.Wrapped("NOTE: "+note, columns, "  ")
+	}
+
+	return d.Feed(2).Cut().Bytes()
+}
+
+// ReceiptInfo is what a customer receipt shows besides the order.
+type ReceiptInfo struct {
+	// Header is printed at the top, usually the restaurant's name.
+	Header    string
+	TableName string
+	// PaidWith is the method of the completed payment, empty when unpaid.
+	PaidWith  string
+	PrintedAt time.Time
+}
+
+// Receipt renders a customer receipt listing the order's items and its total.
+func Receipt(order *models.Order, info ReceiptInfo, columns int) []byte {
+	d := NewDoc(columns)
+	d.Align(AlignCenter)
+	if info.Header != "" {
+		d.Size(2, 2).Bold(true).Wrapped(info.Header, columns/2, "").Size(1, 1).Bold(false)
+	}
+	d.Line("Order " + order.DisplayNumber)
+	d.Line(info.PrintedAt.Format("02 Jan 2006 15:04"))
+	d.Line(orderTypeLine(order.Type, info.TableName))
+	d.Align(AlignLeft).Rule()
+
+	for _, item := range order.Items {
+		d.LeftRight(fmt.Sprintf("%d x %s", item.Quantity, item.Name), money(item.TotalPrice), columns)
+		for _, mod := range item.Modifiers {
+			line := "    " + mod.OptionName
+			if mod.PriceDelta != 0 {
+				d.LeftRight(line, "+"+money(mod.PriceDelta), columns)
+				continue
+			}
+			d.Line(line)
+		}
+	}
+
+	d.Rule().Bold(true)
+	d.LeftRight("TOTAL", money(order.TotalAmount), columns)
+	d.Bold(false)
+	switch {
+	case info.PaidWith != "":
+		d.Line("Paid with " + info.PaidWith)
+	case order.PayOnDelivery:
+		d.Line("To pay on delivery")
+	default:
+		d.Line("Not paid")
+	}
+
+	d.Feed(1).Align(AlignCenter).Line("Thank you!")
+	return d.Feed(3).Cut().Bytes()
+}
+
+// TestPage renders a short page to check a printer is set up right.
+func TestPage(name string, columns int, at time.Time) []byte {
+	d := NewDoc(columns)
+	d.Align(AlignCenter).Size(2, 2).Bold(true).Line("TEST").Size(1, 1).Bold(false)
+	d.Line(name)
+	d.Line(at.Format("02 Jan 2006 15:04:05"))
+	d.Align(AlignLeft).Rule()
+	d.Line(strings.Repeat("0123456789", columns/10+1)[:columns])
+	return d.Feed(3).Cut().Bytes()
+}
+
+func orderTypeLine(orderType models.OrderType, tableName string) string {
+	switch orderType {
+	case models.OrderTypeDineIn:
+		if tableName != "" {
+			return "DINE IN - " + tableName
+		}
+		return "DINE IN"
+	case models.OrderTypeDelivery:
+		return "DELIVERY"
+	default:
+		return "TAKEAWAY"
+	}
+}
+
+func joinAllergens(allergens []models.Allergen) string {
+	return strings.Join(models.AllergenStrings(allergens), ", ")
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,153 @@
+package printing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+var printedAt = time.Date(2024, 5, 1, 19, 30, 0, 0, time.UTC)
+
+func testOrder() *models.Order {
+	return &models.Order{
+		ID:            "order-1",
+		DisplayNumber: "#0042",
+		Type:          models.OrderTypeDineIn,
+		Status:        models.OrderStatusConfirmed,
+		Allergens:     []models.Allergen{models.AllergenPeanuts},
+		TotalAmount:   735,
+		Items: []models.OrderItem{
+			{
+				ID: "i1", Name: "Tibs", Quantity: 2, TotalPrice: 500, Station: models.StationGrill, Course: 1,
+				Modifiers: []models.OrderItemModifier{{GroupName: "Doneness", OptionName: "Well done"}},
+				Notes:     "no onions",
+			},
+			{
+				ID: "i2", Name: "Burger", Quantity: 1, TotalPrice: 235, Station: models.StationGrill, Course: 2,
+				Modifiers: []models.OrderItemModifier{{GroupName: "Extras", OptionName: "Cheese", PriceDelta: 15}},
+				Allergens: []models.Allergen{models.AllergenDairy},
+			},
+			{ID: "i3", Name: "Steak", Quantity: 1, Station: models.StationGrill, Course: 3, Held: true},
+			{ID: "i4", Name: "Juice", Quantity: 1, Station: models.StationBar, Course: 1},
+		},
+	}
+}
+
+// stationTicket returns the order's ticket for station, failing if it has none.
+func stationTicket(t *testing.T, order *models.Order, station models.Station) *models.StationTicket {
+	t.Helper()
+	for _, ticket := range order.StationTickets() {
+		if ticket.Station == station {
+			return ticket
+		}
+	}
+	t.Fatalf("no %s ticket", station)
+	return nil
+}
+
+func TestKitchenTicket(t *testing.T) {
+	ticket := stationTicket(t, testOrder(), models.StationGrill)
+	data := KitchenTicket(ticket, TicketInfo{TableName: "T4", PrintedAt: printedAt}, 32)
+
+	if !bytes.HasPrefix(data, []byte{esc, '@'}) {
+		t.Errorf("ticket does not start by resetting the printer: % x", data[:4])
+	}
+	if !bytes.HasSuffix(data, []byte{esc, 'd', 2, gs, 'V', 66, 3}) {
+		t.Errorf("ticket does not end with a feed and cut: % x", data[len(data)-7:])
+	}
+	// Items are printed double height and bold
+	if !bytes.Contains(data, append([]byte{gs, '!', 0x01, esc, 'E', 1}, "2 x Tibs\n"...)) {
+		t.Error("items are not printed double height and bold")
+	}
+
+	out := string(data)
+	for _, want := range []string{
+		"GRILL\n",
+		"#0042\n",
+		"DINE IN - T4\n",
+		"01 May 19:30\n",
+		"-- Course 1 --\n",
+		"2 x Tibs\n",
+		"  + Doneness: Well done\n",
+		"  NOTE: no onions\n",
+		"-- Course 2 --\n",
+		"1 x Burger\n",
+		"  ALLERGY: dairy\n",
+		"ALLERGY (whole order): peanuts\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ticket is missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Steak", "Course 3", "Juice", "REPRINT"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("ticket shows %q", unwanted)
+		}
+	}
+}
+
+func TestKitchenTicketReprintAndSingleCourse(t *testing.T) {
+	ticket := stationTicket(t, testOrder(), models.StationBar)
+	out := string(KitchenTicket(ticket, TicketInfo{PrintedAt: printedAt, Reprint: true}, 32))
+
+	for _, want := range []string{"BAR\n", "*** REPRINT ***\n", "DINE IN\n", "1 x Juice\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("ticket is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "Course") {
+		t.Error("a single-course ticket shows course headings")
+	}
+}
+
+func TestStationTicketsLeaveOutHeldCourses(t *testing.T) {
+	order := testOrder()
+	for i := range order.Items {
+		if order.Items[i].Station == models.StationGrill {
+			order.Items[i].Held = true
+		}
+	}
+	for _, ticket := range order.StationTickets() {
+		if ticket.Station == models.StationGrill {
+			t.Fatal("a station with every item held still gets a ticket")
+		}
+	}
+}
+
+func TestReceipt(t *testing.T) {
+	order := testOrder()
+	data := Receipt(order, ReceiptInfo{Header: "Habesha Grill", TableName: "T4", PaidWith: "telebirr", PrintedAt: printedAt}, 32)
+
+	if !bytes.HasSuffix(data, []byte{esc, 'd', 3, gs, 'V', 66, 3}) {
+		t.Errorf("receipt does not end with a feed and cut: % x", data[len(data)-7:])
+	}
+
+	out := string(data)
+	for _, want := range []string{
+		"Habesha Grill\n",
+		"Order #0042\n",
+		"01 May 2024 19:30\n",
+		"2 x Tibs                  500.00\n",
+		"    Well done\n",
+		"    Cheese                +15.00\n",
+		"TOTAL                     735.00\n",
+		"Paid with telebirr\n",
+		"Thank you!\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("receipt is missing %q", want)
+		}
+	}
+
+	order.PayOnDelivery = true
+	if out := string(Receipt(order, ReceiptInfo{PrintedAt: printedAt}, 32)); !strings.Contains(out, "To pay on delivery\n") {
+		t.Error("unpaid pay-on-delivery receipt does not say so")
+	}
+	order.PayOnDelivery = false
+	if out := string(Receipt(order, ReceiptInfo{PrintedAt: printedAt}, 32)); !strings.Contains(out, "Not paid\n") {
+		t.Error("unpaid receipt does not say so")
+	}
+}
@@ -0,0 +1,104 @@
+package printing
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/models"
+)
+
+// Spooler keeps a queue for each enabled printer.
+type Spooler struct {
+	cfg      config.PrintingConfig
+	onChange func(models.PrinterStatus)
+
+	mu     sync.Mutex
+	queues map[string]*Queue
+}
+
+// NewSpooler creates a spooler with no printers; see Configure. onChange is passed
+// on to every queue.
+func NewSpooler(cfg config.PrintingConfig, onChange func(models.PrinterStatus)) *Spooler {
+	return &Spooler{cfg: cfg, onChange: onChange, queues: map[string]*Queue{}}
+}
+
+// Configure makes the queues match printers. New printers get a queue, changed
+// ones keep theirs with the new connection, and removed or disabled ones are
+// closed. Printers that can't be set up are left out and reported in the error.
+func (s *Spooler) Configure(printers []*models.Printer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	seen := map[string]bool{}
+	for _, p := range printers {
+		if !p.Enabled {
+			continue
+		}
+		conn, err := s.connect(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("printer %s: %w", p.Name, err))
+			continue
+		}
+		seen[p.ID] = true
+		if q, ok := s.queues[p.ID]; ok {
+			q.Update(conn, p.Name)
+			continue
+		}
+		s.queues[p.ID] = NewQueue(conn, models.PrinterStatus{PrinterID: p.ID, Name: p.Name, Enabled: true}, QueueOptions{
+			Size:        s.cfg.QueueSize,
+			MaxAttempts: s.cfg.MaxAttempts,
+			RetryDelay:  s.cfg.RetryDelay,
+		}, s.onChange)
+	}
+
+	for id, q := range s.queues {
+		if !seen[id] {
+			q.Close()
+			delete(s.queues, id)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Spooler) connect(p *models.Printer) (Printer, error) {
+	switch p.Kind {
+	case models.PrinterKindNetwork:
+		return NewNetworkPrinter(p.Address, s.cfg.Timeout)
+	case models.PrinterKindFile:
+		// File printers stay inside the spool directory whatever their address says
+		name := filepath.Base(filepath.Clean("/" + p.Address))
+		if name == "/" || name == "." {
+			name = p.ID
+		}
+		return NewFilePrinter(filepath.Join(s.cfg.SpoolDir, name))
+	default:
+		return nil, fmt.Errorf("unknown printer kind: %s", p.Kind)
+	}
+}
+
+// Print queues a job on a printer.
+func (s *Spooler) Print(printerID string, job Job) error {
+	s.mu.Lock()
+	q, ok := s.queues[printerID]
+	s.mu.Unlock()
+	if !ok {
+		return ErrNoSuchQueue
+	}
+	return q.Enqueue(job)
+}
+
+// Status returns the status of a printer's queue, and false when the printer has
+// no queue because it is disabled or could not be set up.
+func (s *Spooler) Status(printerID string) (models.PrinterStatus, bool) {
+	s.mu.Lock()
+	q, ok := s.queues[printerID]
+	s.mu.Unlock()
+	if !ok {
+		return models.PrinterStatus{}, false
+	}
+	return q.Status(), true
+}
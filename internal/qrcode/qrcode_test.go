@@ -0,0 +1,306 @@
+package qrcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The expected symbols below come from an independent encoder, rsc.io/qr/coding,
+// asked for the same version and mask: the mask is a free choice, so it is read
+// back from the format bits before comparing. '#' is a dark module.
+var knownSymbols = []struct {
+	data    string
+	version int
+	mask    int
+	rows    []string
+}{
+	{
+		data:    "HELLO",
+		version: 1,
+		mask:    3,
+		rows: []string{
+			"#######.#..#..#######",
+			"#.....#.####..#.....#",
+			"#.###.#...#.#.#.###.#",
+			"#.###.#.#.#.#.#.###.#",
+			"#.###.#....#..#.###.#",
+			"#.....#....##.#.....#",
+			"#######.#.#.#.#######",
+			"........#..##........",
+			"#.##.###.#.##.#..#.##",
+			".##.##.#.######..##..",
+			"#...#.#..#.#.......##",
+			"#.##...#...#..####.#.",
+			".#.######...#..#..#.#",
+			"........####..#...#.#",
+			"#######.#..##..#.....",
+			"#.....#.#.#....#####.",
+			"#.###.#.....######.##",
+			"#.###.#.#.##..#.####.",
+			"#.###.#.##..#.##..#..",
+			"#.....#...#..#.##...#",
+			"#######.#.#..#.#.....",
+		},
+	},
+	{
+		data:    "https://qr.example/t/42",
+		version: 2,
+		mask:    6,
+		rows: []string{
+			"#######.##...##...#######",
+			"#.....#.##.#.#....#.....#",
+			"#.###.#.#.#.####..#.###.#",
+			"#.###.#....######.#.###.#",
+			"#.###.#.#...###.#.#.###.#",
+			"#.....#...#.###.#.#.....#",
+			"#######.#.#.#.#.#.#######",
+			"...........#.#...........",
+			"#..########...##.#..#.###",
+			".####...##..#..#.#.#####.",
+			"###..##.#.#.#..#######..#",
+			"#..###..#####....#...####",
+			".##.#.###.#.#.#.###.....#",
+			"######...##...###...#..#.",
+			"########.#.##.##.#..#####",
+			"#...#..#.###....#..#.##.#",
+			"#.#.#.##..#..########.##.",
+			"........##.##.#.#...#.##.",
+			"#######.##.#.#..#.#.#...#",
+			"#.....#.##..#####...#..#.",
+			"#.###.#.#...#..######..##",
+			"#.###.#.###.....###....##",
+			"#.###.#.....######..#####",
+			"#.....#...#....#..###.###",
+			"#######.#.#.###.#.#..#..#",
+		},
+	},
+	{
+		// Version 7 is the first with version information blocks
+		data:    "https://order.example.com/table?key=" + strings.Repeat("0123456789abcdef", 5),
+		version: 7,
+		mask:    2,
+		rows: []string{
+			"#######....#...###...#...#..###.##..#.#######",
+			"#.....#..##...#..##.#####.##...###.#..#.....#",
+			"#.###.#.##.......###.#.#...##..###.#..#.###.#",
+			"#.###.#.#...#...#.#.#.##..#...#..#.##.#.###.#",
+			"#.###.#.#...##.###.#######.#..##..###.#.###.#",
+			"#.....#.###.###.##..#...#.#..#.#......#.....#",
+			"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+			"........#..#.###...##...#########.#.#........",
+			"#.#####...#.####.#..#####....#....##..#####..",
+			"...#....##..#..#..#..#..##.#..#.#...#...##.##",
+			".#....#...#.#.....##....#.##.#.#..######.#.#.",
+			"#..##....#..#...#....#...#.#######..#######..",
+			"###.#.#.#####.######..####........##.#......#",
+			"#.###...#...#...#.....##.#....####..#...#.###",
+			".##.#.#.#...##...######.#.##.#.#..#..###.##..",
+			"..#.##.#.#.#.#..##....#.##.####.##..##.####..",
+			"#..##.#..#.#..###.#..#..#....##..##..#......#",
+			"#.##...##..##..#.#.#..##.#.####.##.###.#....#",
+			"...#..##.##.#..#.####...#.##.#....##..#..###.",
+			"##...#...#...#.......#.##..##..###.#.#..####.",
+			"#.#########..#.#..#########..##....######...#",
+			"#...#...######..##..#...##..###..#.##...#####",
+			"....#.#.###....#..###.#.#.##...#..#.#.#.#.#..",
+			"#.#.#...##.#.#.#.##.#...##..#..###..#...####.",
+			"##..######.##..##.#.######....#..##.######.#.",
+			"#.#.#..##.#.###.#######.#.....##...#...#....#",
+			"....#.#.#.###.#..#...#....#.##.#.##..#...#.#.",
+			".#...#.#..##.#....#.#.####.######..##.#####..",
+			"##....#...###...###..#.#..#..#...#..#.#.#..#.",
+			"#.#.##..#.#..####.#.#.#.#.....#.##..#.#...###",
+			"#########.##..#..###.#...#####.#..#....#.....",
+			".##....#...#.##.##.#######.##..###.##.##.####",
+			"..#####..#...#.##.####.###....##..#....##..#.",
+			".#.....###.###..####..####...###.#..#.....###",
+			"....#.#.#.##.....##.##.#..##...#..#.#..####..",
+			".####..##.##.#.#.###....##.##.####.#####.##..",
+			"#..##.####..###.#...#####....##..##.#####...#",
+			"........##...####.###...##.#######.##...###.#",
+			"#######...#.....##.##.#.#.##......###.#.#.##.",
+			"#.....#.##.###...##.#...#####..##..##...###..",
+			"#.###.#.#......##...#####.#..##..#.#######.##",
+			"#.###.#.#..##..##....#...#...##......#.######",
+			"#.###.#.##......#.##...##.##...#.####.#..###.",
+			"#.....#...#.....#.###.##....#..##..##...###..",
+			"#######.#.#..#.##.##..#.#..#..#....#####.#.#.",
+		},
+	},
+}
+
+func TestEncodeKnownSymbols(t *testing.T) {
+	for _, want := range knownSymbols {
+		c, err := Encode(want.data)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", want.data, err)
+		}
+		if c.Version != want.version || c.Size != len(want.rows) {
+			t.Fatalf("Encode(%q) is version %d, size %d; want version %d, size %d",
+				want.data, c.Version, c.Size, want.version, len(want.rows))
+		}
+		if mask := readMask(t, c); mask != want.mask {
+			t.Errorf("Encode(%q) chose mask %d, want %d", want.data, mask, want.mask)
+			continue
+		}
+		for y, row := range want.rows {
+			if got := symbolRow(c, y); got != row {
+				t.Errorf("Encode(%q) row %d:\n got %s\nwant %s", want.data, y, got, row)
+			}
+		}
+	}
+}
+
+// Format information for level M and each mask, from the table in ISO/IEC 18004
+// Annex C, most significant bit first.
+var formatInfoM = [8]string{
+	"101010000010010",
+	"101000100100101",
+	"101111001111100",
+	"101101101001011",
+	"100010111111001",
+	"100000011001110",
+	"100111110010111",
+	"100101010100000",
+}
+
+func TestFormatBits(t *testing.T) {
+	for mask, want := range formatInfoM {
+		c := &Code{Size: 21, Version: 1, modules: newGrid(21), isFunction: newGrid(21)}
+		c.drawFormatBits(mask)
+
+		// Bit 14 is first in the table; the first copy runs from (8,0) up past the
+		// top left finder and along row 8
+		first := bitsAt(c, [][2]int{
+			{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8},
+			{8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0},
+		})
+		second := bitsAt(c, [][2]int{
+			{8, 20}, {8, 19}, {8, 18}, {8, 17}, {8, 16}, {8, 15}, {8, 14},
+			{13, 8}, {14, 8}, {15, 8}, {16, 8}, {17, 8}, {18, 8}, {19, 8}, {20, 8},
+		})
+		if first != want || second != want {
+			t.Errorf("mask %d: format bits %s and %s, want %s", mask, first, second, want)
+		}
+		if !c.Dark(8, c.Size-8) {
+			t.Errorf("mask %d: the dark module is light", mask)
+		}
+	}
+}
+
+// Version information from ISO/IEC 18004 Annex D, most significant bit first.
+var versionInfo = map[int]string{
+	7:  "000111110010010100",
+	8:  "001000010110111100",
+	9:  "001001101010011001",
+	10: "001010010011010011",
+}
+
+func TestVersionBits(t *testing.T) {
+	for version, want := range versionInfo {
+		size := version*4 + 17
+		c := &Code{Size: size, Version: version, modules: newGrid(size), isFunction: newGrid(size)}
+		c.drawVersion()
+
+		// Bit i sits at (size-11+i%3, i/3) top right and mirrored bottom left
+		var topRight, bottomLeft [][2]int
+		for i := 17; i >= 0; i-- {
+			topRight = append(topRight, [2]int{size - 11 + i%3, i / 3})
+			bottomLeft = append(bottomLeft, [2]int{i / 3, size - 11 + i%3})
+		}
+		if got := bitsAt(c, topRight); got != want {
+			t.Errorf("version %d: top right block %s, want %s", version, got, want)
+		}
+		if got := bitsAt(c, bottomLeft); got != want {
+			t.Errorf("version %d: bottom left block %s, want %s", version, got, want)
+		}
+	}
+
+	c := &Code{Size: 41, Version: 6, modules: newGrid(41), isFunction: newGrid(41)}
+	c.drawVersion()
+	if bitsAt(c, [][2]int{{30, 0}, {31, 0}, {32, 0}, {30, 5}, {32, 5}}) != "00000" {
+		t.Error("version 6 has version information drawn")
+	}
+}
+
+// Byte capacity at level M per version, from ISO/IEC 18004 Table 7.
+var byteCapacityM = [maxVersion + 1]int{0, 14, 26, 42, 62, 84, 106, 122, 152, 180, 213}
+
+func TestEncodeVersionBoundaries(t *testing.T) {
+	for version := minVersion; version <= maxVersion; version++ {
+		fits := byteCapacityM[version]
+		c, err := Encode(strings.Repeat("a", fits))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", fits, err)
+		}
+		if c.Version != version {
+			t.Errorf("%d bytes encoded as version %d, want %d", fits, c.Version, version)
+		}
+		if c.Size != version*4+17 {
+			t.Errorf("version %d has size %d", version, c.Size)
+		}
+		if version < maxVersion {
+			if c, _ := Encode(strings.Repeat("a", fits+1)); c.Version != version+1 {
+				t.Errorf("%d bytes encoded as version %d, want %d", fits+1, c.Version, version+1)
+			}
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", byteCapacityM[maxVersion]+1)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode past the largest version: %v, want ErrTooLong", err)
+	}
+}
+
+func TestDarkOutsideSymbol(t *testing.T) {
+	c, err := Encode("HELLO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][2]int{{-1, 0}, {0, -1}, {c.Size, 0}, {0, c.Size}} {
+		if c.Dark(p[0], p[1]) {
+			t.Errorf("module %v in the quiet zone is dark", p)
+		}
+	}
+}
+
+// readMask reads the mask back from the first copy of the format bits.
+func readMask(t *testing.T, c *Code) int {
+	t.Helper()
+	bits := bitsAt(c, [][2]int{
+		{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8},
+		{8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0},
+	})
+	for mask, info := range formatInfoM {
+		if info == bits {
+			return mask
+		}
+	}
+	t.Fatalf("format bits %s are not level M", bits)
+	return -1
+}
+
+// bitsAt returns the modules at the given x, y positions as a string of 0s and 1s.
+func bitsAt(c *Code, positions [][2]int) string {
+	var b strings.Builder
+	for _, p := range positions {
+		if c.Dark(p[0], p[1]) {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func symbolRow(c *Code, y int) string {
+	var b strings.Builder
+	for x := 0; x < c.Size; x++ {
+		if c.Dark(x, y) {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
@@ -48,6 +48,14 @@ func (s *AccountService) CreateAccount(req *models.CreateAccountRequest) (*model
 		return nil, err
 	}
 
+	// Every account starts out as a customer
+	if err := grantRole(s.db, account.ID, models.RoleCustomer, ""); err != nil {
+		return nil, err
+	}
+	if err := ensureBootstrapRoles(s.db, account); err != nil {
+		return nil, err
+	}
+
 	return account, nil
 }
 
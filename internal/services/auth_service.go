@@ -1,33 +1,63 @@
 package services
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"time"
 
+	"restaurant-system/internal/config"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/models"
+	"restaurant-system/internal/sms"
 
 	"github.com/google/uuid"
 )
 
+// ErrInvalidSession is returned when a bearer token does not resolve to a live session.
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+const otpTTL = 5 * time.Minute
+
 type AuthService struct {
-	db *database.DB
+	db  *database.DB
+	sms sms.Sender
 }
 
-func NewAuthService(db *database.DB) *AuthService {
-	return &AuthService{db: db}
+func NewAuthService(db *database.DB, sender sms.Sender) *AuthService {
+	return &AuthService{db: db, sms: sender}
 }
 
 func (s *AuthService) RequestOTP(req *models.RequestOTPRequest) error {
-	// Check authorized device
-	var exists int
-	err := s.db.Conn().QueryRow("SELECT 1 FROM authorized_devices WHERE device_id = $1", req.DeviceID).Scan(&exists)
+	if err := s.checkDevice(req.DeviceID); err != nil {
+		return err
+	}
+
+	keys := throttleKeys(req.PhoneNumber, req.DeviceID)
+	if err := s.checkLockout(keys); err != nil {
+		return err
+	}
+	claimedAt, err := s.claimOTPRequest(keys)
 	if err != nil {
-		return fmt.Errorf("unauthorized device")
+		return err
 	}
+	if err := s.sendOTP(req); err != nil {
+		if releaseErr := s.releaseOTPRequest(keys, claimedAt); releaseErr != nil {
+			log.Println("release OTP cooldown:", releaseErr)
+		}
+		return err
+	}
+	return nil
+}
 
+// sendOTP replaces the phone number's outstanding code with a new one and texts it.
+func (s *AuthService) sendOTP(req *models.RequestOTPRequest) error {
 	code, err := generateOTPCode(6)
 	if err != nil {
 		return err
@@ -36,39 +66,96 @@ func (s *AuthService) RequestOTP(req *models.RequestOTPRequest) error {
 	otp := &models.OTP{
 		ID:          uuid.New().String(),
 		PhoneNumber: req.PhoneNumber,
-		Code:        code,
-		ExpiresAt:   time.Now().Add(5 * time.Minute),
+		CodeHash:    hashOTPCode(req.PhoneNumber, code),
+		ExpiresAt:   time.Now().Add(otpTTL),
 		CreatedAt:   time.Now(),
 	}
 
+	// Only the newest code for a phone number is ever valid
+	if _, err := s.db.Conn().Exec(
+		"UPDATE otps SET used_at = $1 WHERE phone_number = $2 AND used_at IS NULL",
+		otp.CreatedAt, otp.PhoneNumber,
+	); err != nil {
+		return err
+	}
+
 	_, err = s.db.Conn().Exec(
-		"INSERT INTO otps (id, phone_number, code, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
-		otp.ID, otp.PhoneNumber, otp.Code, otp.ExpiresAt, otp.CreatedAt,
+		"INSERT INTO otps (id, phone_number, code_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		otp.ID, otp.PhoneNumber, otp.CodeHash, otp.ExpiresAt, otp.CreatedAt,
 	)
 	if err != nil {
 		return err
 	}
 
-	// TODO: Integrate SMS provider; for now we just simulate success
+	message, err := sms.Render(req.Locale, config.SMS().DefaultLocale, sms.MessageOTP, map[string]interface{}{
+		"Code":    code,
+		"Minutes": int(otpTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.sms.Send(req.PhoneNumber, message); err != nil {
+		return fmt.Errorf("failed to send OTP: %w", err)
+	}
 	return nil
 }
 
 func (s *AuthService) VerifyOTP(req *models.VerifyOTPRequest) (string, error) {
-	// Check authorized device
-	var exists int
-	err := s.db.Conn().QueryRow("SELECT 1 FROM authorized_devices WHERE device_id = $1", req.DeviceID).Scan(&exists)
+	if err := s.checkDevice(req.DeviceID); err != nil {
+		return "", err
+	}
+
+	keys := throttleKeys(req.PhoneNumber, req.DeviceID)
+	if err := s.checkLockout(keys); err != nil {
+		return "", err
+	}
+
+	// Validate OTP against the newest outstanding code
+	var otpID, codeHash string
+	err := s.db.Conn().QueryRow(
+		"SELECT id, code_hash FROM otps WHERE phone_number = $1 AND used_at IS NULL AND expires_at > NOW() ORDER BY created_at DESC LIMIT 1",
+		req.PhoneNumber,
+	).Scan(&otpID, &codeHash)
+	if err == sql.ErrNoRows {
+		return "", &AuthError{Code: AuthErrOTPExpired, Message: "code expired or not requested; request a new code"}
+	}
 	if err != nil {
-		return "", fmt.Errorf("unauthorized device")
+		return "", err
 	}
 
-	// Validate OTP
-	var otpID string
+	maxAttempts := config.Auth().OTPMaxAttempts
+	var attempts int
 	err = s.db.Conn().QueryRow(
-		"SELECT id FROM otps WHERE phone_number = $1 AND code = $2 AND expires_at > NOW() ORDER BY created_at DESC LIMIT 1",
-		req.PhoneNumber, req.Code,
-	).Scan(&otpID)
+		"UPDATE otps SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2 RETURNING attempts",
+		otpID, maxAttempts,
+	).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return "", &AuthError{Code: AuthErrOTPAttemptsExceeded, Message: "too many attempts for this code; request a new code"}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !hmac.Equal([]byte(codeHash), []byte(hashOTPCode(req.PhoneNumber, req.Code))) {
+		if err := s.recordFailure(keys); err != nil {
+			return "", err
+		}
+		if err := s.checkLockout(keys); err != nil {
+			return "", err
+		}
+		return "", &AuthError{Code: AuthErrOTPInvalid, Message: "invalid code", AttemptsRemaining: maxAttempts - attempts}
+	}
+
+	// Burn the code; a concurrent request that already used it loses
+	result, err := s.db.Conn().Exec("UPDATE otps SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", otpID)
 	if err != nil {
-		return "", fmt.Errorf("invalid or expired code")
+		return "", err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", &AuthError{Code: AuthErrOTPExpired, Message: "code already used; request a new code"}
+	}
+	if err := s.resetFailures(keys); err != nil {
+		return "", err
 	}
 
 	// Ensure account exists
@@ -79,22 +166,63 @@ func (s *AuthService) VerifyOTP(req *models.VerifyOTPRequest) (string, error) {
 		if err != nil {
 			return "", err
 		}
+	} else if err := ensureBootstrapRoles(s.db, account); err != nil {
+		return "", err
 	}
 
-	// Create session
-	sessionID := uuid.New().String()
-	token := uuid.New().String()
-	expiresAt := time.Now().Add(24 * time.Hour)
-
-	_, err = s.db.Conn().Exec(
-		"INSERT INTO sessions (id, account_id, token, device_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
-		sessionID, account.ID, token, req.DeviceID, expiresAt, time.Now(),
-	)
+	session, err := s.createSession(s.db.Conn(), account.ID, req.DeviceID)
 	if err != nil {
 		return "", err
 	}
 
-	return token, nil
+	return session.Token, nil
+}
+
+// ResolveSession looks up the session for token and returns it together with its account.
+// The session must not be expired and must have been issued to deviceID, which must
+// still be an enabled authorized device.
+func (s *AuthService) ResolveSession(token, deviceID string) (*models.Session, *models.Account, error) {
+	if token == "" || deviceID == "" {
+		return nil, nil, ErrInvalidSession
+	}
+
+	var session models.Session
+	var account models.Account
+	err := s.db.Conn().QueryRow(
+		`SELECT s.id, s.account_id, s.token, s.device_id, s.expires_at, s.created_at,
+			a.id, a.phone_number, a.balance, a.created_at, a.updated_at
+		FROM sessions s
+		JOIN accounts a ON a.id = s.account_id
+		JOIN authorized_devices d ON d.device_id = s.device_id AND NOT d.disabled
+		WHERE s.token = $1 AND s.expires_at > NOW()`,
+		token,
+	).Scan(&session.ID, &session.AccountID, &session.Token, &session.DeviceID, &session.ExpiresAt, &session.CreatedAt,
+		&account.ID, &account.PhoneNumber, &account.Balance, &account.CreatedAt, &account.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if session.DeviceID != deviceID {
+		return nil, nil, ErrInvalidSession
+	}
+
+	session.Roles, session.Permissions, err = loadAccessForAccount(s.db, account.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Record device activity, at most once a minute to spare a write per request
+	if _, err := s.db.Conn().Exec(
+		"UPDATE authorized_devices SET last_seen_at = NOW() WHERE device_id = $1 AND (last_seen_at IS NULL OR last_seen_at < NOW() - INTERVAL '1 minute')",
+		session.DeviceID,
+	); err != nil {
+		return nil, nil, err
+	}
+
+	return &session, &account, nil
 }
 
 func generateOTPCode(length int) (string, error) {
@@ -109,3 +237,11 @@ func generateOTPCode(length int) (string, error) {
 	}
 	return string(code), nil
 }
+
+// hashOTPCode returns the keyed hash stored in place of the plaintext code.
+// The phone number is mixed in so equal codes for different phones hash differently.
+func hashOTPCode(phoneNumber, code string) string {
+	mac := hmac.New(sha256.New, []byte(config.Auth().OTPSecret))
+	mac.Write([]byte(phoneNumber + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
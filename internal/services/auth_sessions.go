@@ -0,0 +1,150 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *AuthService) createSession(q database.Queryer, accountID, deviceID string) (*models.Session, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New().String(),
+		AccountID: accountID,
+		Token:     uuid.New().String(),
+		DeviceID:  deviceID,
+		ExpiresAt: now.Add(config.Auth().SessionTTL),
+		CreatedAt: now,
+	}
+
+	_, err := q.Exec(
+		"INSERT INTO sessions (id, account_id, token, device_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		session.ID, session.AccountID, session.Token, session.DeviceID, session.ExpiresAt, session.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Logout revokes the given session.
+func (s *AuthService) Logout(sessionID string) error {
+	_, err := s.db.Conn().Exec("DELETE FROM sessions WHERE id = $1", sessionID)
+	return err
+}
+
+// ListSessions returns the account's unexpired sessions, newest first.
+func (s *AuthService) ListSessions(accountID, currentSessionID string) ([]*models.Session, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT id, account_id, device_id, expires_at, created_at FROM sessions WHERE account_id = $1 AND expires_at > NOW() ORDER BY created_at DESC",
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.AccountID, &session.DeviceID, &session.ExpiresAt, &session.CreatedAt); err != nil {
+			return nil, err
+		}
+		session.Current = session.ID == currentSessionID
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends one of the account's sessions.
+func (s *AuthService) RevokeSession(accountID, sessionID string) error {
+	result, err := s.db.Conn().Exec("DELETE FROM sessions WHERE id = $1 AND account_id = $2", sessionID, accountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("session not found")
+	}
+	return nil
+}
+
+// RefreshSession exchanges a session that is close to expiry for a new one on the
+// same device. The old token stops working immediately.
+func (s *AuthService) RefreshSession(current *models.Session) (*models.Session, error) {
+	if time.Until(current.ExpiresAt) > config.Auth().SessionRefreshWindow {
+		return nil, fmt.Errorf("session is not close enough to expiry to refresh")
+	}
+
+	// Delete first so two concurrent refreshes cannot both mint a token. Both run in
+	// one transaction, so a failed insert leaves the old session working.
+	var session *models.Session
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM sessions WHERE id = $1", current.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrInvalidSession
+		}
+
+		session, err = s.createSession(tx, current.AccountID, current.DeviceID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SweepExpired deletes expired sessions, dead OTPs, stale throttle rows and expired
+// device pairing requests.
+func (s *AuthService) SweepExpired() (int64, error) {
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM sessions WHERE expires_at <= NOW()", nil},
+		{"DELETE FROM otps WHERE expires_at <= NOW()", nil},
+		{"DELETE FROM device_pairings WHERE expires_at <= NOW()", nil},
+		{
+			"DELETE FROM auth_throttles WHERE (locked_until IS NULL OR locked_until <= NOW()) AND updated_at < $1 AND (last_request_at IS NULL OR last_request_at < $1)",
+			[]interface{}{time.Now().Add(-config.Auth().OTPLockout)},
+		},
+	}
+
+	var total int64
+	for _, q := range queries {
+		result, err := s.db.Conn().Exec(q.query, q.args...)
+		if err != nil {
+			return total, err
+		}
+		n, _ := result.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// RunSweeper calls SweepExpired every interval. It never returns.
+func (s *AuthService) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := s.SweepExpired()
+		if err != nil {
+			log.Println("session sweeper:", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("session sweeper: removed %d expired rows", n)
+		}
+	}
+}
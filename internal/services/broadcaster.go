@@ -0,0 +1,12 @@
+package services
+
+// Broadcaster sends an event to every connected client. *websocket.Hub implements it.
+type Broadcaster interface {
+	Broadcast(v interface{})
+}
+
+// RoleBroadcaster sends an event to the connected clients with any of the given
+// roles. *websocket.Hub implements it.
+type RoleBroadcaster interface {
+	BroadcastToRoles(v interface{}, roles ...string)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCancellationNotFound = errors.New("cancellation not found")
+	// ErrCancellationDecided is returned when approving or rejecting a cancellation
+	// that is no longer awaiting approval.
+	ErrCancellationDecided = errors.New("cancellation has already been decided")
+	// ErrUseCancelEndpoint is returned when a plain status update tries to cancel an
+	// order, which would skip the reason and the refund.
+	ErrUseCancelEndpoint = errors.New("orders are cancelled through POST /orders/:id/cancel")
+)
+
+type CancellationService struct {
+	db       *database.DB
+	payments *PaymentService
+}
+
+func NewCancellationService(db *database.DB, paymentService *PaymentService) *CancellationService {
+	return &CancellationService{db: db, payments: paymentService}
+}
+
+// CancelOrder cancels an order and refunds its completed payments. Once the kitchen has
+// started preparing it, staff without approval rights only file a cancellation that a
+// manager has to approve; the returned cancellation is then pending_approval.
+func (s *CancellationService) CancelOrder(orderID string, req *models.CancelOrderRequest, actor *models.Actor) (*models.OrderCancellation, error) {
+	if !req.Reason.Valid() {
+		return nil, fmt.Errorf("invalid cancellation reason: %s", req.Reason)
+	}
+	note := strings.TrimSpace(req.Note)
+	if req.Reason == models.CancelReasonOther && note == "" {
+		return nil, fmt.Errorf("a note is required when the reason is other")
+	}
+
+	cancellation := &models.OrderCancellation{
+		ID:          uuid.New().String(),
+		OrderID:     orderID,
+		Reason:      req.Reason,
+		Note:        note,
+		RequestedBy: actor.AccountID,
+		CreatedAt:   time.Now(),
+	}
+
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var status models.OrderStatus
+		err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if status == models.OrderStatusPreparing && !actor.Can(models.PermOrdersApproveCancel) {
+			if !actor.Can(models.PermOrdersUpdateStatus) && !actor.Can(models.PermKitchenAccess) {
+				return fmt.Errorf("%w: the order is being prepared; ask staff to cancel it", ErrForbidden)
+			}
+			var pending bool
+			if err := tx.QueryRow(
+				"SELECT EXISTS (SELECT 1 FROM order_cancellations WHERE order_id = $1 AND status = $2)",
+				orderID, models.CancellationPendingApproval,
+			).Scan(&pending); err != nil {
+				return err
+			}
+			if pending {
+				return &TransitionError{From: status, To: models.OrderStatusCancelled, Reason: "a cancellation is already awaiting approval"}
+			}
+
+			cancellation.Status = models.CancellationPendingApproval
+			return insertCancellation(tx, cancellation)
+		}
+
+		cancellation.Status = models.CancellationApproved
+		cancellation.DecidedBy = actor.AccountID
+		cancellation.DecidedAt = &cancellation.CreatedAt
+		if err := insertCancellation(tx, cancellation); err != nil {
+			return err
+		}
+		return s.cancel(tx, cancellation, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.payments.CompleteRefunds(cancellation.Refunds, cancellationEventReason(cancellation))
+	return cancellation, nil
+}
+
+// ApproveCancellation cancels the order of a cancellation awaiting approval and refunds it.
+func (s *CancellationService) ApproveCancellation(cancellationID string, actor *models.Actor) (*models.OrderCancellation, error) {
+	var cancellation *models.OrderCancellation
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		cancellation, err = s.decide(tx, cancellationID, models.CancellationApproved, actor)
+		if err != nil {
+			return err
+		}
+		return s.cancel(tx, cancellation, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.payments.CompleteRefunds(cancellation.Refunds, cancellationEventReason(cancellation))
+	return cancellation, nil
+}
+
+// RejectCancellation leaves the order as it is and closes the cancellation.
+func (s *CancellationService) RejectCancellation(cancellationID string, actor *models.Actor) (*models.OrderCancellation, error) {
+	var cancellation *models.OrderCancellation
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		cancellation, err = s.decide(tx, cancellationID, models.CancellationRejected, actor)
+		return err
+	})
+	return cancellation, err
+}
+
+// ListCancellations returns cancellations with the given status, or all of them when
+// status is empty, newest first.
+func (s *CancellationService) ListCancellations(status models.CancellationStatus) ([]*models.OrderCancellation, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT "+cancellationColumns+" FROM order_cancellations WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC",
+		status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cancellations := []*models.OrderCancellation{}
+	for rows.Next() {
+		cancellation, err := scanCancellation(rows)
+		if err != nil {
+			return nil, err
+		}
+		cancellations = append(cancellations, cancellation)
+	}
+	return cancellations, rows.Err()
+}
+
+// cancel moves the order to cancelled and refunds its completed payments.
+func (s *CancellationService) cancel(q database.Queryer, cancellation *models.OrderCancellation, actor *models.Actor) error {
+	if _, err := transitionOrder(q, cancellation.OrderID, models.OrderStatusCancelled, actor, cancellationEventReason(cancellation)); err != nil {
+		return err
+	}
+
+	var err error
+	cancellation.Refunds, err = refundOrderPayments(q, cancellation.OrderID)
+	return err
+}
+
+// decide locks a cancellation awaiting approval and records the decision on it.
+func (s *CancellationService) decide(q database.Queryer, cancellationID string, status models.CancellationStatus, actor *models.Actor) (*models.OrderCancellation, error) {
+	cancellation, err := scanCancellation(q.QueryRow(
+		"SELECT "+cancellationColumns+" FROM order_cancellations WHERE id = $1 FOR UPDATE",
+		cancellationID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrCancellationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cancellation.Status != models.CancellationPendingApproval {
+		return nil, ErrCancellationDecided
+	}
+
+	now := time.Now()
+	cancellation.Status = status
+	cancellation.DecidedBy = actor.AccountID
+	cancellation.DecidedAt = &now
+	_, err = q.Exec(
+		"UPDATE order_cancellations SET status = $1, decided_by = $2, decided_at = $3 WHERE id = $4",
+		cancellation.Status, cancellation.DecidedBy, cancellation.DecidedAt, cancellation.ID,
+	)
+	return cancellation, err
+}
+
+const cancellationColumns = "id, order_id, reason, note, status, requested_by, COALESCE(decided_by, ''), created_at, decided_at"
+
+func scanCancellation(row database.Scanner) (*models.OrderCancellation, error) {
+	var cancellation models.OrderCancellation
+	var decidedAt sql.NullTime
+	err := row.Scan(&cancellation.ID, &cancellation.OrderID, &cancellation.Reason, &cancellation.Note, &cancellation.Status,
+		&cancellation.RequestedBy, &cancellation.DecidedBy, &cancellation.CreatedAt, &decidedAt)
+	if err != nil {
+		return nil, err
+	}
+	if decidedAt.Valid {
+		cancellation.DecidedAt = &decidedAt.Time
+	}
+	return &cancellation, nil
+}
+
+func insertCancellation(q database.Queryer, cancellation *models.OrderCancellation) error {
+	_, err := q.Exec(
+		"INSERT INTO order_cancellations (id, order_id, reason, note, status, requested_by, decided_by, created_at, decided_at) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)",
+		cancellation.ID, cancellation.OrderID, cancellation.Reason, cancellation.Note, cancellation.Status,
+		cancellation.RequestedBy, cancellation.DecidedBy, cancellation.CreatedAt, cancellation.DecidedAt,
+	)
+	return err
+}
+
+// cancellationEventReason is the reason recorded in the order history, e.g. "out_of_stock: no more fish".
+func cancellationEventReason(cancellation *models.OrderCancellation) string {
+	if cancellation.Note == "" {
+		return string(cancellation.Reason)
+	}
+	return string(cancellation.Reason) + ": " + cancellation.Note
+}
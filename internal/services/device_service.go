@@ -0,0 +1,268 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"strings"
+	"time"
+)
+
+const (
+	pairingCodeLength = 6
+	pairingTTL        = 10 * time.Minute
+	// Pairing codes are read aloud and typed by hand, so skip look-alike characters
+	pairingAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// pairingRequestsPerIP is how many new pairing requests one address may make
+	// within pairingTTL.
+	pairingRequestsPerIP = 10
+)
+
+// ErrPairingThrottled is returned when an address asks for too many pairing codes.
+var ErrPairingThrottled = errors.New("too many pairing requests; try again later")
+
+type DeviceService struct {
+	db *database.DB
+}
+
+func NewDeviceService(db *database.DB) *DeviceService {
+	return &DeviceService{db: db}
+}
+
+func (s *DeviceService) RegisterDevice(req *models.RegisterDeviceRequest, registeredBy string) (*models.Device, error) {
+	_, err := s.db.Conn().Exec(
+		`INSERT INTO authorized_devices (device_id, name, registered_by, registered_at, disabled) VALUES ($1, $2, NULLIF($3, ''), $4, FALSE)
+		ON CONFLICT (device_id) DO UPDATE SET name = EXCLUDED.name, disabled = FALSE`,
+		req.DeviceID, req.Name, registeredBy, time.Now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetDevice(req.DeviceID)
+}
+
+func (s *DeviceService) GetDevice(deviceID string) (*models.Device, error) {
+	device, err := scanDevice(s.db.Conn().QueryRow(
+		"SELECT device_id, COALESCE(name, ''), disabled, COALESCE(registered_by, ''), registered_at, last_seen_at FROM authorized_devices WHERE device_id = $1",
+		deviceID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("device not found")
+	}
+	return device, err
+}
+
+func (s *DeviceService) ListDevices() ([]*models.Device, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT device_id, COALESCE(name, ''), disabled, COALESCE(registered_by, ''), registered_at, last_seen_at FROM authorized_devices ORDER BY registered_at DESC",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []*models.Device
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+func (s *DeviceService) UpdateDevice(deviceID string, req *models.UpdateDeviceRequest) (*models.Device, error) {
+	result, err := s.db.Conn().Exec(
+		"UPDATE authorized_devices SET name = COALESCE($1, name), disabled = COALESCE($2, disabled) WHERE device_id = $3",
+		req.Name, req.Disabled, deviceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("device not found")
+	}
+
+	return s.GetDevice(deviceID)
+}
+
+// DeleteDevice removes the device and signs out every session on it.
+func (s *DeviceService) DeleteDevice(deviceID string) error {
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM authorized_devices WHERE device_id = $1", deviceID)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("device not found")
+		}
+
+		_, err = tx.Exec("DELETE FROM sessions WHERE device_id = $1", deviceID)
+		return err
+	})
+}
+
+// StartPairing issues a short code the device displays until a manager approves it.
+// A device that already has a live pairing request gets the same code back. Anyone
+// can call it, so registered devices only learn that they are approved, and each
+// client address may only ask for a few new codes within the pairing TTL.
+func (s *DeviceService) StartPairing(req *models.StartPairingRequest, clientIP string) (*models.DevicePairing, error) {
+	device, err := s.GetDevice(req.DeviceID)
+	if err == nil {
+		if device.Disabled {
+			return nil, fmt.Errorf("device is disabled")
+		}
+		return &models.DevicePairing{DeviceID: device.DeviceID, Status: models.PairingStatusApproved}, nil
+	}
+
+	if pairing, err := s.GetPairingStatus(req.DeviceID); err == nil && pairing.Status == models.PairingStatusPending {
+		return pairing, nil
+	}
+
+	code, err := generatePairingCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pairing := &models.DevicePairing{
+		Code:      code,
+		DeviceID:  req.DeviceID,
+		Name:      req.Name,
+		Status:    models.PairingStatusPending,
+		ExpiresAt: now.Add(pairingTTL),
+		CreatedAt: now,
+	}
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		// Requests from one address are counted one at a time
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('device_pairings:' || $1))", clientIP); err != nil {
+			return err
+		}
+		var recent int
+		if err := tx.QueryRow(
+			"SELECT COUNT(*) FROM device_pairings WHERE requested_ip = $1 AND created_at > $2",
+			clientIP, now.Add(-pairingTTL),
+		).Scan(&recent); err != nil {
+			return err
+		}
+		if recent >= pairingRequestsPerIP {
+			return ErrPairingThrottled
+		}
+
+		_, err := tx.Exec(
+			"INSERT INTO device_pairings (code, device_id, name, status, expires_at, created_at, requested_ip) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			pairing.Code, pairing.DeviceID, pairing.Name, pairing.Status, pairing.ExpiresAt, pairing.CreatedAt, clientIP,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pairing, nil
+}
+
+// GetPairingStatus returns the device's most recent pairing request.
+func (s *DeviceService) GetPairingStatus(deviceID string) (*models.DevicePairing, error) {
+	pairing, err := scanPairing(s.db.Conn().QueryRow(
+		"SELECT code, device_id, COALESCE(name, ''), status, expires_at, created_at, COALESCE(approved_by, ''), approved_at FROM device_pairings WHERE device_id = $1 ORDER BY created_at DESC LIMIT 1",
+		deviceID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no pairing request for this device")
+	}
+	return pairing, err
+}
+
+// ListPendingPairings returns pairing requests waiting for approval.
+func (s *DeviceService) ListPendingPairings() ([]*models.DevicePairing, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT code, device_id, COALESCE(name, ''), status, expires_at, created_at, COALESCE(approved_by, ''), approved_at FROM device_pairings WHERE status = $1 AND expires_at > NOW() ORDER BY created_at",
+		models.PairingStatusPending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairings []*models.DevicePairing
+	for rows.Next() {
+		pairing, err := scanPairing(rows)
+		if err != nil {
+			return nil, err
+		}
+		pairings = append(pairings, pairing)
+	}
+
+	return pairings, rows.Err()
+}
+
+// ApprovePairing authorizes the device behind a pending pairing code.
+func (s *DeviceService) ApprovePairing(code string, req *models.ApprovePairingRequest, approvedBy string) (*models.Device, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var deviceID, name string
+	err := s.db.Conn().QueryRow(
+		`UPDATE device_pairings SET status = $1, approved_by = NULLIF($2, ''), approved_at = $3
+		WHERE code = $4 AND status = $5 AND expires_at > NOW()
+		RETURNING device_id, COALESCE(name, '')`,
+		models.PairingStatusApproved, approvedBy, time.Now(), code, models.PairingStatusPending,
+	).Scan(&deviceID, &name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("pairing code not found or expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req != nil && req.Name != "" {
+		name = req.Name
+	}
+	return s.RegisterDevice(&models.RegisterDeviceRequest{DeviceID: deviceID, Name: name}, approvedBy)
+}
+
+func scanDevice(row database.Scanner) (*models.Device, error) {
+	var device models.Device
+	var lastSeen sql.NullTime
+	if err := row.Scan(&device.DeviceID, &device.Name, &device.Disabled, &device.RegisteredBy, &device.RegisteredAt, &lastSeen); err != nil {
+		return nil, err
+	}
+	if lastSeen.Valid {
+		device.LastSeenAt = &lastSeen.Time
+	}
+	return &device, nil
+}
+
+func scanPairing(row database.Scanner) (*models.DevicePairing, error) {
+	var pairing models.DevicePairing
+	var approvedAt sql.NullTime
+	if err := row.Scan(&pairing.Code, &pairing.DeviceID, &pairing.Name, &pairing.Status, &pairing.ExpiresAt, &pairing.CreatedAt, &pairing.ApprovedBy, &approvedAt); err != nil {
+		return nil, err
+	}
+	if approvedAt.Valid {
+		pairing.ApprovedAt = &approvedAt.Time
+	}
+	if pairing.Status == models.PairingStatusPending && time.Now().After(pairing.ExpiresAt) {
+		pairing.Status = models.PairingStatusExpired
+	}
+	return &pairing, nil
+}
+
+func generatePairingCode() (string, error) {
+	code := make([]byte, pairingCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(pairingAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = pairingAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
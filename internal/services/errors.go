@@ -0,0 +1,6 @@
+package services
+
+import "errors"
+
+// ErrForbidden is returned when the acting account lacks the permission an operation needs.
+var ErrForbidden = errors.New("forbidden")
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidTableKey is returned when a scanned QR code does not match the table,
+	// e.g. because its key was rotated since it was printed.
+	ErrInvalidTableKey = errors.New("this QR code is no longer valid; please ask staff for help")
+	// ErrInvalidTableToken is returned for unknown or expired table tokens.
+	ErrInvalidTableToken = errors.New("table session expired; please scan the QR code again")
+)
+
+// GuestService lets guests order for their table by scanning its QR code, without
+// an account. Scanning trades the table's QR key for a short-lived table token;
+// orders placed with it wait for a waiter to confirm them before the kitchen sees them.
+type GuestService struct {
+	db     *database.DB
+	orders *OrderService
+}
+
+func NewGuestService(db *database.DB, orderService *OrderService) *GuestService {
+	return &GuestService{db: db, orders: orderService}
+}
+
+// StartSession checks the key from a table's QR code and issues a table token.
+func (s *GuestService) StartSession(tableID, key string) (*models.GuestSession, error) {
+	table, err := scanTable(s.db.Conn().QueryRow(
+		"SELECT "+tableColumns+" FROM dining_tables WHERE id = $1 AND deleted_at IS NULL",
+		tableID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrTableNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if table.QRKey == "" || subtle.ConstantTimeCompare([]byte(table.QRKey), []byte(key)) != 1 {
+		return nil, ErrInvalidTableKey
+	}
+
+	now := time.Now()
+	token := &models.TableToken{
+		ID:        uuid.New().String(),
+		Token:     uuid.New().String(),
+		TableID:   table.ID,
+		ExpiresAt: now.Add(config.Guest().TableTokenTTL),
+		CreatedAt: now,
+	}
+	_, err = s.db.Conn().Exec(
+		"INSERT INTO table_tokens (id, token, table_id, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)",
+		token.ID, token.Token, token.TableID, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.GuestSession{Token: token.Token, ExpiresAt: token.ExpiresAt, Table: table}, nil
+}
+
+// ResolveToken returns the unexpired table token for a bearer token.
+func (s *GuestService) ResolveToken(token string) (*models.TableToken, error) {
+	var t models.TableToken
+	err := s.db.Conn().QueryRow(
+		`SELECT tt.id, tt.table_id, tt.expires_at, tt.created_at FROM table_tokens tt
+		JOIN dining_tables dt ON dt.id = tt.table_id AND dt.deleted_at IS NULL
+		WHERE tt.token = $1 AND tt.expires_at > NOW()`,
+		token,
+	).Scan(&t.ID, &t.TableID, &t.ExpiresAt, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidTableToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateOrder places a dine-in order for the token's table. The order stays pending
+// until a waiter confirms it; guests settle up at the table, so it needs no payment first.
+func (s *GuestService) CreateOrder(token *models.TableToken, req *models.GuestOrderRequest) (*models.Order, error) {
+	// Only staff pace courses, so a guest's items all go to the kitchen together
+	items := make([]models.CreateOrderItem, len(req.Items))
+	for i, item := range req.Items {
+		item.Hold = false
+		items[i] = item
+	}
+
+	orderReq := &models.CreateOrderRequest{
+		Source:       models.OrderSourceQR,
+		TableTokenID: token.ID,
+		Items:        items,
+		Notes:        req.Notes,
+		Allergens:    req.Allergens,
+		Type:         models.OrderTypeDineIn,
+		TableID:      token.TableID,
+		GuestCount:   req.GuestCount,
+	}
+
+	var order *models.Order
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		order, err = s.orders.createOrder(tx, orderReq)
+		if err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, order.ID, "", order.Status, nil, "guest order via table QR")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ListOrders returns the orders placed with a table token, newest first.
+func (s *GuestService) ListOrders(token *models.TableToken) ([]*models.Order, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT "+database.OrderColumns+" FROM orders WHERE table_token_id = $1 ORDER BY created_at DESC",
+		token.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*models.Order{}
+	for rows.Next() {
+		order, err := database.ScanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.LoadOrderItems(orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetOrder returns an order placed with a table token. Other orders are not found.
+func (s *GuestService) GetOrder(token *models.TableToken, orderID string) (*models.Order, error) {
+	order, err := database.ScanOrder(s.db.Conn().QueryRow(
+		"SELECT "+database.OrderColumns+" FROM orders WHERE id = $1 AND table_token_id = $2",
+		orderID, token.ID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order.Items, err = s.db.GetOrderItems(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// RunSweeper deletes expired table tokens every interval. It never returns.
+func (s *GuestService) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Conn().Exec("DELETE FROM table_tokens WHERE expires_at < NOW()"); err != nil {
+			log.Println("table token sweeper:", err)
+		}
+	}
+}
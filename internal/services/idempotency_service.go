@@ -0,0 +1,124 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"time"
+)
+
+const (
+	// idempotencyTTL is how long a key is remembered.
+	idempotencyTTL = 24 * time.Hour
+	// idempotencyLease is how long a request may hold a key before a retry may take it over,
+	// in case the original request died without completing.
+	idempotencyLease = time.Minute
+	// idempotencyClaimAttempts bounds how often Begin retries a key that keeps
+	// disappearing under it.
+	idempotencyClaimAttempts = 3
+)
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService struct {
+	db *database.DB
+}
+
+func NewIdempotencyService(db *database.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Begin claims key for a request. It returns nil when the caller should process the
+// request, or the stored record when a completed response should be replayed.
+func (s *IdempotencyService) Begin(accountID, scope, key, requestHash string) (*models.IdempotencyRecord, error) {
+	// The key can be released or swept between the claim and reading it back; it is
+	// then free again, so claim it anew
+	for attempt := 0; attempt < idempotencyClaimAttempts; attempt++ {
+		claimed, err := s.claim(accountID, scope, key, requestHash)
+		if err != nil || claimed {
+			return nil, err
+		}
+
+		record, err := s.get(accountID, scope, key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.RequestHash != requestHash {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if record.Status != models.IdempotencyStatusCompleted {
+			return nil, ErrIdempotencyKeyInFlight
+		}
+		return record, nil
+	}
+	return nil, ErrIdempotencyKeyInFlight
+}
+
+// claim takes key for a request. It fails to when the key is held by a live request
+// or completed, or was used for a different request.
+func (s *IdempotencyService) claim(accountID, scope, key, requestHash string) (bool, error) {
+	now := time.Now()
+	result, err := s.db.Conn().Exec(
+		`INSERT INTO idempotency_keys (account_id, scope, key, request_hash, status, created_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (account_id, scope, key) DO UPDATE SET created_at = EXCLUDED.created_at
+		WHERE idempotency_keys.status = $5 AND idempotency_keys.request_hash = EXCLUDED.request_hash AND idempotency_keys.created_at < $7`,
+		accountID, scope, key, requestHash, models.IdempotencyStatusProcessing, now, now.Add(-idempotencyLease),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// Complete stores the response for a claimed key.
+func (s *IdempotencyService) Complete(accountID, scope, key string, status int, body []byte) error {
+	_, err := s.db.Conn().Exec(
+		"UPDATE idempotency_keys SET status = $1, response_status = $2, response_body = $3 WHERE account_id = $4 AND scope = $5 AND key = $6",
+		models.IdempotencyStatusCompleted, status, body, accountID, scope, key,
+	)
+	return err
+}
+
+// Release forgets a claimed key so the request can be retried, e.g. after a server error.
+func (s *IdempotencyService) Release(accountID, scope, key string) error {
+	_, err := s.db.Conn().Exec(
+		"DELETE FROM idempotency_keys WHERE account_id = $1 AND scope = $2 AND key = $3 AND status = $4",
+		accountID, scope, key, models.IdempotencyStatusProcessing,
+	)
+	return err
+}
+
+func (s *IdempotencyService) get(accountID, scope, key string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	var responseStatus sql.NullInt64
+	err := s.db.Conn().QueryRow(
+		"SELECT account_id, scope, key, request_hash, status, response_status, response_body, created_at FROM idempotency_keys WHERE account_id = $1 AND scope = $2 AND key = $3",
+		accountID, scope, key,
+	).Scan(&record.AccountID, &record.Scope, &record.Key, &record.RequestHash, &record.Status, &responseStatus, &record.ResponseBody, &record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	record.ResponseStatus = int(responseStatus.Int64)
+	return &record, nil
+}
+
+// RunSweeper deletes expired idempotency keys every interval. It never returns.
+func (s *IdempotencyService) RunSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.Conn().Exec("DELETE FROM idempotency_keys WHERE created_at < $1", time.Now().Add(-idempotencyTTL)); err != nil {
+			log.Println("idempotency sweeper:", err)
+		}
+	}
+}
@@ -1,12 +1,22 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/models"
 	"time"
 )
 
+var (
+	ErrOrderItemNotFound = errors.New("order item not found")
+	// ErrItemNotInKitchen is returned when an item's order is not being worked on.
+	ErrItemNotInKitchen = errors.New("order is not in the kitchen")
+	// ErrItemPrepStatus is returned when an item cannot be bumped or recalled any further.
+	ErrItemPrepStatus = errors.New("item cannot move any further")
+)
+
 type KitchenService struct {
 	db *database.DB
 }
@@ -18,7 +28,7 @@ func NewKitchenService(db *database.DB) *KitchenService {
 func (s *KitchenService) GetPendingOrders() ([]*models.Order, error) {
 	// Get orders that are confirmed or preparing
 	rows, err := s.db.Conn().Query(
-		"SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders WHERE status IN ('confirmed', 'preparing') ORDER BY created_at ASC",
+		"SELECT " + database.OrderColumns + " FROM orders WHERE status IN ('confirmed', 'preparing') ORDER BY created_at ASC",
 	)
 	if err != nil {
 		return nil, err
@@ -27,84 +37,183 @@ func (s *KitchenService) GetPendingOrders() ([]*models.Order, error) {
 
 	var orders []*models.Order
 	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.ID, &order.CustomerID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		// Get order items
-		order.Items, err = s.db.GetOrderItems(order.ID)
+		order, err := database.ScanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		orders = append(orders, &order)
+	// Get order items for all orders at once
+	if err := s.db.LoadOrderItems(orders); err != nil {
+		return nil, err
 	}
 
-	return orders, nil
+	return kitchenView(orders), nil
 }
 
-func (s *KitchenService) UpdateOrderStatus(orderID string, status models.OrderStatus) error {
-	// Validate status transition
-	currentStatus, err := s.getOrderStatus(orderID)
+// kitchenView hides held items from the orders and leaves out orders with every
+// item on hold, since the line has nothing to do for them yet.
+func kitchenView(orders []*models.Order) []*models.Order {
+	visible := []*models.Order{}
+	for _, order := range orders {
+		order.HideHeldItems()
+		if len(order.Items) == 0 {
+			continue
+		}
+		order.BuildAlerts()
+		visible = append(visible, order)
+	}
+	return visible
+}
+
+// GetStationTickets returns the station's tickets for the orders in the kitchen,
+// oldest first. Orders with nothing for the station are left out.
+func (s *KitchenService) GetStationTickets(station models.Station) ([]*models.StationTicket, error) {
+	if !station.Valid() {
+		return nil, ErrInvalidStation
+	}
+
+	orders, err := s.GetPendingOrders()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if !s.isValidStatusTransition(currentStatus, status) {
-		return fmt.Errorf("invalid status transition from %s to %s", currentStatus, status)
+	tickets := []*models.StationTicket{}
+	for _, order := range orders {
+		if ticket := order.StationTicket(station); ticket != nil {
+			tickets = append(tickets, ticket)
+		}
 	}
 
-	_, err = s.db.Conn().Exec(
-		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3",
-		status, time.Now(), orderID,
-	)
+	return tickets, nil
+}
 
-	return err
+// UpdateOrderStatus moves an order through the order state machine on behalf of actor.
+func (s *KitchenService) UpdateOrderStatus(orderID string, status models.OrderStatus, actor *models.Actor, reason string) error {
+	if status == models.OrderStatusCancelled {
+		return ErrUseCancelEndpoint
+	}
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		_, err := transitionOrder(tx, orderID, status, actor, reason)
+		return err
+	})
 }
 
-func (s *KitchenService) getOrderStatus(orderID string) (models.OrderStatus, error) {
-	var status models.OrderStatus
-	err := s.db.Conn().QueryRow(
-		"SELECT status FROM orders WHERE id = $1",
-		orderID,
-	).Scan(&status)
+// BumpItem moves an order item on to its next preparation status.
+func (s *KitchenService) BumpItem(itemID string, actor *models.Actor) (*models.ItemPrepChange, error) {
+	return s.moveItem(itemID, actor, models.ItemPrepStatus.Bumped)
+}
 
-	return status, err
+// RecallItem moves an order item back to its previous preparation status.
+func (s *KitchenService) RecallItem(itemID string, actor *models.Actor) (*models.ItemPrepChange, error) {
+	return s.moveItem(itemID, actor, models.ItemPrepStatus.Recalled)
 }
 
-func (s *KitchenService) isValidStatusTransition(current, new models.OrderStatus) bool {
-	// Define valid status transitions
-	validTransitions := map[models.OrderStatus][]models.OrderStatus{
-		models.OrderStatusPending:   {models.OrderStatusConfirmed, models.OrderStatusCancelled},
-		models.OrderStatusConfirmed: {models.OrderStatusPreparing, models.OrderStatusCancelled},
-		models.OrderStatusPreparing: {models.OrderStatusReady, models.OrderStatusCancelled},
-		models.OrderStatusReady:     {models.OrderStatusCompleted},
-		models.OrderStatusCompleted: {},
-		models.OrderStatusCancelled: {},
-	}
+// moveItem changes an item's preparation status and rolls the change up to its order:
+// the first item started puts the order into preparing, the last item done makes it
+// ready, and recalling an item of a ready order puts it back into preparing. Items on
+// hold are left out; firing their course puts a ready order back into preparing.
+func (s *KitchenService) moveItem(itemID string, actor *models.Actor, next func(models.ItemPrepStatus) (models.ItemPrepStatus, bool)) (*models.ItemPrepChange, error) {
+	var orderID string
+	var previous models.OrderStatus
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var name string
+		var held bool
+		var from models.ItemPrepStatus
+		var orderStatus models.OrderStatus
+		err := tx.QueryRow(
+			`SELECT oi.order_id, oi.name, oi.held, oi.prep_status, o.status FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE oi.id = $1 FOR UPDATE`,
+			itemID,
+		).Scan(&orderID, &name, &held, &from, &orderStatus)
+		if err == sql.ErrNoRows {
+			return ErrOrderItemNotFound
+		}
+		if err != nil {
+			return err
+		}
 
-	allowedStatuses, exists := validTransitions[current]
-	if !exists {
-		return false
-	}
+		switch orderStatus {
+		case models.OrderStatusConfirmed, models.OrderStatusPreparing, models.OrderStatusReady:
+		default:
+			return fmt.Errorf("%w: order is %s", ErrItemNotInKitchen, orderStatus)
+		}
+
+		if held {
+			return fmt.Errorf("%w: its course is on hold", ErrItemPrepStatus)
+		}
+
+		to, ok := next(from)
+		if !ok {
+			return fmt.Errorf("%w: item is %s", ErrItemPrepStatus, from)
+		}
+		if _, err := tx.Exec("UPDATE order_items SET prep_status = $1 WHERE id = $2", to, itemID); err != nil {
+			return err
+		}
 
-	for _, allowed := range allowedStatuses {
-		if allowed == new {
-			return true
+		// Held courses are not the kitchen's yet, so they don't keep the order from ready
+		var unfinished int
+		if err := tx.QueryRow(
+			"SELECT COUNT(*) FROM order_items WHERE order_id = $1 AND prep_status NOT IN ($2, $3) AND NOT held",
+			orderID, models.ItemPrepDone, models.ItemPrepServed,
+		).Scan(&unfinished); err != nil {
+			return err
 		}
+
+		// Walk the order along the state machine to where its items say it is
+		var target []models.OrderStatus
+		switch {
+		case unfinished == 0 && orderStatus == models.OrderStatusConfirmed:
+			target = []models.OrderStatus{models.OrderStatusPreparing, models.OrderStatusReady}
+		case unfinished == 0 && orderStatus == models.OrderStatusPreparing:
+			target = []models.OrderStatus{models.OrderStatusReady}
+		case unfinished > 0 && orderStatus == models.OrderStatusReady:
+			target = []models.OrderStatus{models.OrderStatusPreparing}
+		case to == models.ItemPrepCooking && orderStatus == models.OrderStatusConfirmed:
+			target = []models.OrderStatus{models.OrderStatusPreparing}
+		}
+		for _, status := range target {
+			reason := name + " is " + string(to)
+			if status == models.OrderStatusReady {
+				reason = "all items are done"
+			}
+			if _, err := transitionOrder(tx, orderID, status, actor, reason); err != nil {
+				return err
+			}
+		}
+		if len(target) > 0 {
+			previous = orderStatus
+		}
+
+		_, err = tx.Exec("UPDATE orders SET updated_at = $1 WHERE id = $2", time.Now(), orderID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return false
+	order, err := s.GetOrderDetails(orderID)
+	if err != nil {
+		return nil, err
+	}
+	item := order.Item(itemID)
+	if item == nil {
+		return nil, ErrOrderItemNotFound
+	}
+
+	return &models.ItemPrepChange{Item: *item, Order: order, PreviousOrderStatus: previous}, nil
 }
 
 func (s *KitchenService) GetOrderDetails(orderID string) (*models.Order, error) {
-	var order models.Order
-	err := s.db.Conn().QueryRow(
-		"SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders WHERE id = $1",
+	order, err := database.ScanOrder(s.db.Conn().QueryRow(
+		"SELECT "+database.OrderColumns+" FROM orders WHERE id = $1",
 		orderID,
-	).Scan(&order.ID, &order.CustomerID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+	))
 
 	if err != nil {
 		return nil, err
@@ -115,13 +224,14 @@ func (s *KitchenService) GetOrderDetails(orderID string) (*models.Order, error)
 	if err != nil {
 		return nil, err
 	}
+	order.BuildAlerts()
 
-	return &order, nil
+	return order, nil
 }
 
 func (s *KitchenService) GetOrdersByStatus(status models.OrderStatus) ([]*models.Order, error) {
 	rows, err := s.db.Conn().Query(
-		"SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders WHERE status = $1 ORDER BY created_at ASC",
+		"SELECT "+database.OrderColumns+" FROM orders WHERE status = $1 ORDER BY created_at ASC",
 		status,
 	)
 	if err != nil {
@@ -131,20 +241,20 @@ func (s *KitchenService) GetOrdersByStatus(status models.OrderStatus) ([]*models
 
 	var orders []*models.Order
 	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.ID, &order.CustomerID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-
-		// Get order items
-		order.Items, err = s.db.GetOrderItems(order.ID)
+		order, err := database.ScanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		orders = append(orders, &order)
+	// Get order items for all orders at once
+	if err := s.db.LoadOrderItems(orders); err != nil {
+		return nil, err
 	}
 
-	return orders, nil
+	return kitchenView(orders), nil
 }
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"log"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"time"
+)
+
+// EstimateReady works out when an order waiting for or in the kitchen should be
+// ready. Orders past the kitchen get no estimate; see estimateReadyAt for the sums.
+func (s *KitchenService) EstimateReady(order *models.Order) (*models.ReadyEstimate, error) {
+	switch order.Status {
+	case models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusPreparing:
+	default:
+		return nil, nil
+	}
+
+	now := time.Now()
+	// A pending order joins the back of the queue once it is confirmed
+	ahead := order.CreatedAt
+	if order.Status == models.OrderStatusPending {
+		ahead = now
+	}
+
+	backlog, err := stationBacklog(s.db.Conn(), order.ID, ahead)
+	if err != nil {
+		return nil, err
+	}
+
+	readyAt := estimateReadyAt(order.Items, backlog, config.Kitchen().StationCapacity, now)
+	return models.NewReadyEstimate(readyAt, now), nil
+}
+
+// estimateReadyAt is when items are done, given the backlog of item minutes queued
+// ahead of them at each station. A station works through capacity items at a time,
+// so both the backlog and the items' own minutes, prep time times quantity, take
+// 1/capacity of their total; but no item is done sooner than its own prep time.
+// The items are ready when their slowest station is done. Held courses are left
+// out until they are fired.
+func estimateReadyAt(items []models.OrderItem, backlog map[models.Station]int, capacity int, now time.Time) time.Time {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	work := map[models.Station]int{}
+	longest := map[models.Station]int{}
+	for _, item := range items {
+		if item.Held || item.PrepStatus.Finished() {
+			continue
+		}
+		station := item.StationOf()
+		work[station] += item.PrepMinutes * item.Quantity
+		if item.PrepMinutes > longest[station] {
+			longest[station] = item.PrepMinutes
+		}
+	}
+
+	readyAt := now
+	for station, minutes := range work {
+		wait := time.Duration(backlog[station]) * time.Minute / time.Duration(capacity)
+		cook := time.Duration(minutes) * time.Minute / time.Duration(capacity)
+		if floor := time.Duration(longest[station]) * time.Minute; cook < floor {
+			cook = floor
+		}
+		if done := now.Add(wait + cook); done.After(readyAt) {
+			readyAt = done
+		}
+	}
+	return readyAt
+}
+
+// stationBacklog sums the prep minutes of the unfinished items of kitchen orders
+// placed before ahead, other than orderID, per station. Held courses don't count
+// until they are fired.
+func stationBacklog(q database.Queryer, orderID string, ahead time.Time) (map[models.Station]int, error) {
+	rows, err := q.Query(
+		`SELECT oi.station, SUM(oi.prep_minutes * oi.quantity) FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.status IN ($1, $2) AND o.created_at < $3 AND o.id <> $4 AND oi.prep_status IN ($5, $6) AND NOT oi.held
+		GROUP BY oi.station`,
+		models.OrderStatusConfirmed, models.OrderStatusPreparing, ahead, orderID,
+		models.ItemPrepQueued, models.ItemPrepCooking,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backlog := map[models.Station]int{}
+	for rows.Next() {
+		var station models.Station
+		var minutes int
+		if err := rows.Scan(&station, &minutes); err != nil {
+			return nil, err
+		}
+		backlog[station] = minutes
+	}
+	return backlog, rows.Err()
+}
+
+// FindLateOrders returns the confirmed and preparing orders that have gone past
+// their target ready time since the last call. Each order is only reported once,
+// or once more after another course is fired. Orders with every item on hold are
+// never late.
+func (s *KitchenService) FindLateOrders() ([]*models.LateOrder, error) {
+	rows, err := s.db.Conn().Query(
+		`SELECT o.id,
+			GREATEST(
+				COALESCE((SELECT MAX(e.created_at) FROM order_events e WHERE e.order_id = o.id AND e.to_status = $1), o.created_at),
+				(SELECT MAX(oi.fired_at) FROM order_items oi WHERE oi.order_id = o.id)
+			),
+			COALESCE((SELECT MAX(oi.prep_minutes) FROM order_items oi WHERE oi.order_id = o.id AND NOT oi.held), 0)
+		FROM orders o
+		WHERE o.status IN ($1, $2) AND o.late_alerted_at IS NULL
+			AND EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id AND NOT oi.held)`,
+		models.OrderStatusConfirmed, models.OrderStatusPreparing,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		orderID  string
+		targetAt time.Time
+	}
+	now := time.Now()
+	grace := config.Kitchen().LateGrace
+	var late []candidate
+	for rows.Next() {
+		var orderID string
+		// startedAt is when the order was confirmed or a course last fired
+		var startedAt time.Time
+		var prepMinutes int
+		if err := rows.Scan(&orderID, &startedAt, &prepMinutes); err != nil {
+			return nil, err
+		}
+		if targetAt := startedAt.Add(time.Duration(prepMinutes)*time.Minute + grace); now.After(targetAt) {
+			late = append(late, candidate{orderID: orderID, targetAt: targetAt})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var orders []*models.LateOrder
+	for _, c := range late {
+		// Another instance may have alerted in the meantime
+		result, err := s.db.Conn().Exec(
+			"UPDATE orders SET late_alerted_at = $1 WHERE id = $2 AND late_alerted_at IS NULL",
+			now, c.orderID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+
+		order, err := s.GetOrderDetails(c.orderID)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, &models.LateOrder{
+			OrderID:       order.ID,
+			DisplayNumber: order.DisplayNumber,
+			Type:          order.Type,
+			TableID:       order.TableID,
+			Status:        order.Status,
+			TargetAt:      c.targetAt,
+			MinutesLate:   int(now.Sub(c.targetAt) / time.Minute),
+		})
+	}
+
+	return orders, nil
+}
+
+// RunLateMonitor calls FindLateOrders every interval and passes each late order
+// to notify. It never returns.
+func (s *KitchenService) RunLateMonitor(interval time.Duration, notify func(*models.LateOrder)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		orders, err := s.FindLateOrders()
+		if err != nil {
+			log.Println("late order monitor:", err)
+			continue
+		}
+		for _, order := range orders {
+			notify(order)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+func TestEstimateReadyAt(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := func(station models.Station, minutes, quantity int) models.OrderItem {
+		return models.OrderItem{Station: station, PrepMinutes: minutes, Quantity: quantity, PrepStatus: models.ItemPrepQueued}
+	}
+
+	tests := []struct {
+		name     string
+		items    []models.OrderItem
+		backlog  map[models.Station]int
+		capacity int
+		want     time.Duration
+	}{
+		{
+			name:     "empty kitchen, one item",
+			items:    []models.OrderItem{item(models.StationGrill, 12, 1)},
+			capacity: 3,
+			want:     12 * time.Minute,
+		},
+		{
+			name:     "items within capacity cook side by side",
+			items:    []models.OrderItem{item(models.StationGrill, 12, 1), item(models.StationGrill, 8, 2)},
+			capacity: 3,
+			want:     12 * time.Minute,
+		},
+		{
+			name:     "more items than capacity queue behind each other",
+			items:    []models.OrderItem{item(models.StationGrill, 10, 6)},
+			capacity: 3,
+			want:     20 * time.Minute,
+		},
+		{
+			name:     "backlog is shared by the station's capacity",
+			items:    []models.OrderItem{item(models.StationGrill, 10, 1)},
+			backlog:  map[models.Station]int{models.StationGrill: 30},
+			capacity: 3,
+			want:     20 * time.Minute,
+		},
+		{
+			name:     "backlog at other stations does not matter",
+			items:    []models.OrderItem{item(models.StationCold, 5, 1)},
+			backlog:  map[models.Station]int{models.StationGrill: 300},
+			capacity: 3,
+			want:     5 * time.Minute,
+		},
+		{
+			name: "slowest station decides",
+			items: []models.OrderItem{
+				item(models.StationGrill, 15, 1),
+				item(models.StationBar, 2, 1),
+			},
+			backlog:  map[models.Station]int{models.StationBar: 60},
+			capacity: 2,
+			want:     32 * time.Minute,
+		},
+		{
+			name: "finished items are left out",
+			items: []models.OrderItem{
+				{Station: models.StationGrill, PrepMinutes: 30, Quantity: 1, PrepStatus: models.ItemPrepDone},
+				item(models.StationGrill, 5, 1),
+			},
+			capacity: 3,
+			want:     5 * time.Minute,
+		},
+		{
+			name: "held courses are left out",
+			items: []models.OrderItem{
+				{Station: models.StationGrill, PrepMinutes: 40, Quantity: 2, PrepStatus: models.ItemPrepQueued, Held: true},
+				item(models.StationGrill, 8, 1),
+			},
+			capacity: 3,
+			want:     8 * time.Minute,
+		},
+		{
+			name:     "no capacity counts as one",
+			items:    []models.OrderItem{item(models.StationGrill, 10, 2)},
+			capacity: 0,
+			want:     20 * time.Minute,
+		},
+		{
+			name: "nothing left to cook",
+			items: []models.OrderItem{
+				{Station: models.StationGrill, PrepMinutes: 30, Quantity: 1, PrepStatus: models.ItemPrepServed},
+			},
+			capacity: 3,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		got := estimateReadyAt(tt.items, tt.backlog, tt.capacity, now)
+		if got.Sub(now) != tt.want {
+			t.Errorf("%s: ready in %v, want %v", tt.name, got.Sub(now), tt.want)
+		}
+	}
+}
+
+func TestFindLateOrdersAlertsOnce(t *testing.T) {
+	db := openTestDB(t)
+	orders := NewOrderService(db)
+	kitchen := NewKitchenService(db)
+	menuItems := availableMenuItems(t, db, 1)
+
+	order, err := orders.CreateOrder(&models.CreateOrderRequest{
+		Items: []models.CreateOrderItem{{MenuItemID: menuItems[0], Quantity: 1}},
+	}, models.SystemActor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Paying at the register confirms the order; the clock is then put back so it was
+	// confirmed long enough ago to be late whatever the prep time
+	payment, err := NewPaymentService(db).ProcessPayment(&models.ProcessPaymentRequest{
+		OrderID: order.ID,
+		Method:  models.PaymentMethodCash,
+	}, staffActor(models.RoleCashier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !payment.OrderConfirmed {
+		t.Fatal("paying did not confirm the order")
+	}
+	longAgo := time.Now().Add(-6 * time.Hour)
+	for _, query := range []string{
+		"UPDATE orders SET created_at = $1 WHERE id = $2",
+		"UPDATE order_events SET created_at = $1 WHERE order_id = $2",
+	} {
+		if _, err := db.Conn().Exec(query, longAgo, order.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reported := func() *models.LateOrder {
+		t.Helper()
+		late, err := kitchen.FindLateOrders()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range late {
+			if o.OrderID == order.ID {
+				return o
+			}
+		}
+		return nil
+	}
+
+	first := reported()
+	if first == nil {
+		t.Fatal("late order was not reported")
+	}
+	if first.MinutesLate <= 0 || first.DisplayNumber != order.DisplayNumber {
+		t.Errorf("late order = %+v", first)
+	}
+	if reported() != nil {
+		t.Error("late order was reported a second time")
+	}
+}
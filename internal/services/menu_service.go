@@ -0,0 +1,213 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidStation = errors.New("invalid kitchen station")
+
+// MenuAvailability filters menu listings.
+type MenuAvailability string
+
+const (
+	MenuAvailable   MenuAvailability = "available"
+	MenuUnavailable MenuAvailability = "unavailable"
+	MenuAll         MenuAvailability = "all"
+)
+
+type MenuService struct {
+	db *database.DB
+}
+
+func NewMenuService(db *database.DB) *MenuService {
+	return &MenuService{db: db}
+}
+
+// GetMenu returns the menu grouped by category. Deleted items are never included.
+func (s *MenuService) GetMenu(availability MenuAvailability) ([]*models.MenuCategory, error) {
+	query := "SELECT id, name, COALESCE(description, ''), price, category, station, prep_minutes, available FROM menu_items WHERE deleted_at IS NULL"
+	switch availability {
+	case MenuAvailable:
+		query += " AND available = TRUE"
+	case MenuUnavailable:
+		query += " AND available = FALSE"
+	}
+	query += " ORDER BY category, name"
+
+	rows, err := s.db.Conn().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*models.MenuItem
+	var itemIDs []string
+	for rows.Next() {
+		var item models.MenuItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Category, &item.Station, &item.PrepMinutes, &item.Available); err != nil {
+			return nil, err
+		}
+		items = append(items, &item)
+		itemIDs = append(itemIDs, item.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	groups, err := loadModifierGroups(s.db.Conn(), itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []*models.MenuCategory
+	byName := map[string]*models.MenuCategory{}
+	for _, item := range items {
+		item.ModifierGroups = groups[item.ID]
+		category, ok := byName[item.Category]
+		if !ok {
+			category = &models.MenuCategory{Category: item.Category}
+			byName[item.Category] = category
+			categories = append(categories, category)
+		}
+		category.Items = append(category.Items, item)
+	}
+
+	return categories, nil
+}
+
+func (s *MenuService) GetMenuItem(itemID string) (*models.MenuItem, error) {
+	var item models.MenuItem
+	err := s.db.Conn().QueryRow(
+		"SELECT id, name, COALESCE(description, ''), price, category, station, prep_minutes, available FROM menu_items WHERE id = $1 AND deleted_at IS NULL",
+		itemID,
+	).Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Category, &item.Station, &item.PrepMinutes, &item.Available)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("menu item not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := loadModifierGroups(s.db.Conn(), []string{item.ID})
+	if err != nil {
+		return nil, err
+	}
+	item.ModifierGroups = groups[item.ID]
+
+	return &item, nil
+}
+
+func (s *MenuService) CreateMenuItem(req *models.CreateMenuItemRequest) (*models.MenuItem, error) {
+	item := &models.MenuItem{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Category:    req.Category,
+		Station:     req.Station,
+		PrepMinutes: models.DefaultPrepMinutes,
+		Available:   true,
+	}
+	if req.PrepMinutes != nil {
+		item.PrepMinutes = *req.PrepMinutes
+	}
+	if item.Station == "" {
+		item.Station = models.StationForCategory(item.Category)
+	}
+	if !item.Station.Valid() {
+		return nil, ErrInvalidStation
+	}
+	if req.Available != nil {
+		item.Available = *req.Available
+	}
+
+	now := time.Now()
+	_, err := s.db.Conn().Exec(
+		"INSERT INTO menu_items (id, name, description, price, category, station, prep_minutes, available, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		item.ID, item.Name, item.Description, item.Price, item.Category, item.Station, item.PrepMinutes, item.Available, now, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *MenuService) UpdateMenuItem(itemID string, req *models.UpdateMenuItemRequest) (*models.MenuItem, error) {
+	if req.Station != nil && !req.Station.Valid() {
+		return nil, ErrInvalidStation
+	}
+	result, err := s.db.Conn().Exec(
+		`UPDATE menu_items SET
+			name = COALESCE($1, name),
+			description = COALESCE($2, description),
+			price = COALESCE($3, price),
+			category = COALESCE($4, category),
+			station = COALESCE($5, station),
+			prep_minutes = COALESCE($6, prep_minutes),
+			available = COALESCE($7, available),
+			updated_at = $8
+		WHERE id = $9 AND deleted_at IS NULL`,
+		req.Name, req.Description, req.Price, req.Category, req.Station, req.PrepMinutes, req.Available, time.Now(), itemID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("menu item not found")
+	}
+
+	return s.GetMenuItem(itemID)
+}
+
+func (s *MenuService) SetAvailability(itemID string, available bool) (*models.MenuItem, error) {
+	return s.UpdateMenuItem(itemID, &models.UpdateMenuItemRequest{Available: &available})
+}
+
+// DeleteMenuItem hides the item from the menu. Past orders still reference it,
+// so the row is kept and marked deleted rather than removed.
+func (s *MenuService) DeleteMenuItem(itemID string) error {
+	result, err := s.db.Conn().Exec(
+		"UPDATE menu_items SET deleted_at = $1, available = FALSE, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL",
+		time.Now(), itemID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("menu item not found")
+	}
+	return nil
+}
+
+// loadAvailableMenuItems fetches the given menu items in one query, keyed by ID.
+// Unavailable and deleted items are left out.
+func loadAvailableMenuItems(q database.Queryer, itemIDs []string) (map[string]*models.MenuItem, error) {
+	rows, err := q.Query(
+		"SELECT id, name, COALESCE(description, ''), price, category, station, prep_minutes, available FROM menu_items WHERE id = ANY($1) AND available = TRUE AND deleted_at IS NULL",
+		pq.Array(itemIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[string]*models.MenuItem{}
+	for rows.Next() {
+		var item models.MenuItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Category, &item.Station, &item.PrepMinutes, &item.Available); err != nil {
+			return nil, err
+		}
+		items[item.ID] = &item
+	}
+
+	return items, rows.Err()
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CreateModifierGroup attaches a new modifier group with its options to a menu item.
+func (s *MenuService) CreateModifierGroup(menuItemID string, req *models.ModifierGroupRequest) (*models.ModifierGroup, error) {
+	if _, err := s.GetMenuItem(menuItemID); err != nil {
+		return nil, err
+	}
+	if err := normalizeModifierGroup(req); err != nil {
+		return nil, err
+	}
+
+	groupID := uuid.New().String()
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			"INSERT INTO modifier_groups (id, menu_item_id, name, required, min_select, max_select, position) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			groupID, menuItemID, req.Name, req.Required, req.MinSelect, req.MaxSelect, req.Position,
+		); err != nil {
+			return err
+		}
+		return saveModifierOptions(tx, groupID, req.Options)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getModifierGroup(menuItemID, groupID)
+}
+
+// UpdateModifierGroup replaces a group's settings and options. Options sent with an ID
+// are updated, options without one are added, and options left out are removed.
+func (s *MenuService) UpdateModifierGroup(menuItemID, groupID string, req *models.ModifierGroupRequest) (*models.ModifierGroup, error) {
+	if err := normalizeModifierGroup(req); err != nil {
+		return nil, err
+	}
+
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			"UPDATE modifier_groups SET name = $1, required = $2, min_select = $3, max_select = $4, position = $5 WHERE id = $6 AND menu_item_id = $7",
+			req.Name, req.Required, req.MinSelect, req.MaxSelect, req.Position, groupID, menuItemID,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("modifier group not found")
+		}
+
+		keep := []string{}
+		for _, option := range req.Options {
+			if option.ID != "" {
+				keep = append(keep, option.ID)
+			}
+		}
+		if _, err := tx.Exec(
+			"DELETE FROM modifier_options WHERE group_id = $1 AND NOT (id = ANY($2))",
+			groupID, pq.Array(keep),
+		); err != nil {
+			return err
+		}
+		return saveModifierOptions(tx, groupID, req.Options)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getModifierGroup(menuItemID, groupID)
+}
+
+func (s *MenuService) DeleteModifierGroup(menuItemID, groupID string) error {
+	result, err := s.db.Conn().Exec("DELETE FROM modifier_groups WHERE id = $1 AND menu_item_id = $2", groupID, menuItemID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("modifier group not found")
+	}
+	return nil
+}
+
+func (s *MenuService) getModifierGroup(menuItemID, groupID string) (*models.ModifierGroup, error) {
+	groups, err := loadModifierGroups(s.db.Conn(), []string{menuItemID})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups[menuItemID] {
+		if group.ID == groupID {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("modifier group not found")
+}
+
+func saveModifierOptions(tx *sql.Tx, groupID string, options []models.ModifierOptionRequest) error {
+	for _, option := range options {
+		available := true
+		if option.Available != nil {
+			available = *option.Available
+		}
+
+		if option.ID == "" {
+			if _, err := tx.Exec(
+				"INSERT INTO modifier_options (id, group_id, name, price_delta, available, position) VALUES ($1, $2, $3, $4, $5, $6)",
+				uuid.New().String(), groupID, option.Name, option.PriceDelta, available, option.Position,
+			); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, err := tx.Exec(
+			"UPDATE modifier_options SET name = $1, price_delta = $2, available = $3, position = $4 WHERE id = $5 AND group_id = $6",
+			option.Name, option.PriceDelta, available, option.Position, option.ID, groupID,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("modifier option not found: %s", option.ID)
+		}
+	}
+	return nil
+}
+
+func normalizeModifierGroup(req *models.ModifierGroupRequest) error {
+	if req.Required && req.MinSelect == 0 {
+		req.MinSelect = 1
+	}
+	req.Required = req.MinSelect > 0
+	if req.MaxSelect > 0 && req.MinSelect > req.MaxSelect {
+		return fmt.Errorf("min_select cannot exceed max_select")
+	}
+	if req.MinSelect > len(req.Options) {
+		return fmt.Errorf("min_select cannot exceed the number of options")
+	}
+	return nil
+}
+
+// loadModifierGroups returns the modifier groups, with options, for each menu item ID.
+func loadModifierGroups(q database.Queryer, menuItemIDs []string) (map[string][]*models.ModifierGroup, error) {
+	groups := map[string][]*models.ModifierGroup{}
+	if len(menuItemIDs) == 0 {
+		return groups, nil
+	}
+
+	rows, err := q.Query(
+		`SELECT g.id, g.menu_item_id, g.name, g.required, g.min_select, g.max_select, g.position,
+			o.id, o.name, o.price_delta, o.available, o.position
+		FROM modifier_groups g
+		JOIN modifier_options o ON o.group_id = g.id
+		WHERE g.menu_item_id = ANY($1)
+		ORDER BY g.position, g.name, o.position, o.name`,
+		pq.Array(menuItemIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[string]*models.ModifierGroup{}
+	for rows.Next() {
+		var group models.ModifierGroup
+		var option models.ModifierOption
+		if err := rows.Scan(&group.ID, &group.MenuItemID, &group.Name, &group.Required, &group.MinSelect, &group.MaxSelect, &group.Position,
+			&option.ID, &option.Name, &option.PriceDelta, &option.Available, &option.Position); err != nil {
+			return nil, err
+		}
+		existing, ok := byID[group.ID]
+		if !ok {
+			existing = &group
+			byID[group.ID] = existing
+			groups[group.MenuItemID] = append(groups[group.MenuItemID], existing)
+		}
+		option.GroupID = group.ID
+		existing.Options = append(existing.Options, &option)
+	}
+
+	return groups, rows.Err()
+}
+
+// resolveModifiers checks the selected option IDs against a menu item's modifier
+// groups and returns the order item modifiers plus the total price delta. Options
+// may take money off, but not so much that the item costs less than nothing.
+func resolveModifiers(menuItem *models.MenuItem, groups []*models.ModifierGroup, selected []string) ([]models.OrderItemModifier, float64, error) {
+	menuItemName := menuItem.Name
+	type choice struct {
+		group  *models.ModifierGroup
+		option *models.ModifierOption
+	}
+	options := map[string]choice{}
+	for _, group := range groups {
+		for _, option := range group.Options {
+			options[option.ID] = choice{group, option}
+		}
+	}
+
+	var modifiers []models.OrderItemModifier
+	var delta float64
+	counts := map[string]int{}
+	seen := map[string]bool{}
+	for _, optionID := range selected {
+		c, ok := options[optionID]
+		if !ok {
+			return nil, 0, fmt.Errorf("invalid modifier %s for %s", optionID, menuItemName)
+		}
+		if seen[optionID] {
+			return nil, 0, fmt.Errorf("modifier %s selected more than once for %s", c.option.Name, menuItemName)
+		}
+		if !c.option.Available {
+			return nil, 0, fmt.Errorf("%s is unavailable for %s", c.option.Name, menuItemName)
+		}
+		seen[optionID] = true
+		counts[c.group.ID]++
+		delta += c.option.PriceDelta
+		modifiers = append(modifiers, models.OrderItemModifier{
+			ID:               uuid.New().String(),
+			ModifierOptionID: c.option.ID,
+			GroupName:        c.group.Name,
+			OptionName:       c.option.Name,
+			PriceDelta:       c.option.PriceDelta,
+		})
+	}
+
+	for _, group := range groups {
+		n := counts[group.ID]
+		if n < group.MinSelect {
+			return nil, 0, fmt.Errorf("choose at least %d %s for %s", group.MinSelect, group.Name, menuItemName)
+		}
+		if group.MaxSelect > 0 && n > group.MaxSelect {
+			return nil, 0, fmt.Errorf("choose at most %d %s for %s", group.MaxSelect, group.Name, menuItemName)
+		}
+	}
+
+	if menuItem.Price+delta < 0 {
+		return nil, 0, fmt.Errorf("the modifiers chosen take the price of %s below zero", menuItemName)
+	}
+
+	sort.SliceStable(modifiers, func(i, j int) bool { return modifiers[i].GroupName < modifiers[j].GroupName })
+	return modifiers, delta, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"restaurant-system/internal/models"
+)
+
+func TestNormalizeModifierGroup(t *testing.T) {
+	options := func(n int) []models.ModifierOptionRequest {
+		return make([]models.ModifierOptionRequest, n)
+	}
+
+	tests := []struct {
+		name         string
+		req          models.ModifierGroupRequest
+		wantMin      int
+		wantRequired bool
+		wantErr      string
+	}{
+		{"optional", models.ModifierGroupRequest{MaxSelect: 2, Options: options(3)}, 0, false, ""},
+		{"required means at least one", models.ModifierGroupRequest{Required: true, Options: options(2)}, 1, true, ""},
+		{"a minimum makes it required", models.ModifierGroupRequest{MinSelect: 2, Options: options(2)}, 2, true, ""},
+		{"min above max", models.ModifierGroupRequest{MinSelect: 3, MaxSelect: 2, Options: options(4)}, 0, false, "cannot exceed max_select"},
+		{"min above the options", models.ModifierGroupRequest{MinSelect: 3, Options: options(2)}, 0, false, "number of options"},
+		{"required with no options", models.ModifierGroupRequest{Required: true}, 0, false, "number of options"},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		err := normalizeModifierGroup(&req)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if req.MinSelect != tt.wantMin || req.Required != tt.wantRequired {
+			t.Errorf("%s: min_select = %d, required = %v, want %d and %v", tt.name, req.MinSelect, req.Required, tt.wantMin, tt.wantRequired)
+		}
+	}
+}
+
+func TestResolveModifiers(t *testing.T) {
+	burger := &models.MenuItem{Name: "Burger", Price: 200}
+	groups := []*models.ModifierGroup{
+		{
+			ID: "size", Name: "Size", Required: true, MinSelect: 1, MaxSelect: 1,
+			Options: []*models.ModifierOption{
+				{ID: "regular", Name: "Regular", Available: true},
+				{ID: "large", Name: "Large", PriceDelta: 50, Available: true},
+				{ID: "kids", Name: "Kids", PriceDelta: -60, Available: true},
+			},
+		},
+		{
+			ID: "extras", Name: "Extras", MaxSelect: 2,
+			Options: []*models.ModifierOption{
+				{ID: "cheese", Name: "Cheese", PriceDelta: 15, Available: true},
+				{ID: "bacon", Name: "Bacon", PriceDelta: 40, Available: true},
+				{ID: "egg", Name: "Egg", PriceDelta: 20, Available: true},
+				{ID: "truffle", Name: "Truffle", PriceDelta: 300, Available: false},
+				{ID: "no-bun", Name: "No bun", PriceDelta: -30, Available: true},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		selected  []string
+		wantDelta float64
+		wantErr   string
+	}{
+		{"required choice only", []string{"regular"}, 0, ""},
+		{"with extras", []string{"cheese", "large", "bacon"}, 105, ""},
+		{"discounts", []string{"kids", "no-bun"}, -90, ""},
+		{"required group left out", []string{"cheese"}, 0, "at least 1 Size"},
+		{"nothing chosen", nil, 0, "at least 1 Size"},
+		{"too many in a group", []string{"regular", "cheese", "bacon", "egg"}, 0, "at most 2 Extras"},
+		{"two sizes", []string{"regular", "large"}, 0, "at most 1 Size"},
+		{"unavailable option", []string{"regular", "truffle"}, 0, "Truffle is unavailable"},
+		{"duplicate option", []string{"regular", "cheese", "cheese"}, 0, "more than once"},
+		{"option of another item", []string{"regular", "mayo"}, 0, "invalid modifier mayo"},
+	}
+	for _, tt := range tests {
+		modifiers, delta, err := resolveModifiers(burger, groups, tt.selected)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if delta != tt.wantDelta {
+			t.Errorf("%s: delta = %v, want %v", tt.name, delta, tt.wantDelta)
+		}
+		if len(modifiers) != len(tt.selected) {
+			t.Errorf("%s: %d modifiers, want %d", tt.name, len(modifiers), len(tt.selected))
+		}
+		for i := 1; i < len(modifiers); i++ {
+			if modifiers[i-1].GroupName > modifiers[i].GroupName {
+				t.Errorf("%s: modifiers not grouped: %+v", tt.name, modifiers)
+			}
+		}
+	}
+}
+
+func TestResolveModifiersRejectsNegativePrice(t *testing.T) {
+	groups := []*models.ModifierGroup{{
+		ID: "deal", Name: "Deal", MaxSelect: 2,
+		Options: []*models.ModifierOption{
+			{ID: "half", Name: "Half portion", PriceDelta: -40, Available: true},
+			{ID: "staff", Name: "Staff discount", PriceDelta: -30, Available: true},
+		},
+	}}
+	tea := &models.MenuItem{Name: "Tea", Price: 70}
+
+	if _, delta, err := resolveModifiers(tea, groups, []string{"half", "staff"}); err != nil || delta != -70 {
+		t.Errorf("a free item: delta = %v, err = %v, want -70 and no error", delta, err)
+	}
+	tea.Price = 60
+	if _, _, err := resolveModifiers(tea, groups, []string{"half", "staff"}); err == nil || !strings.Contains(err.Error(), "below zero") {
+		t.Errorf("a negative price: error = %v, want it rejected", err)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/models"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeOrderCursor returns an opaque cursor pointing just past order in a listing
+// sorted by column.
+func encodeOrderCursor(order *models.Order, column string) string {
+	value := order.CreatedAt.Format(time.RFC3339Nano)
+	if column == "total_amount" {
+		value = strconv.FormatFloat(order.TotalAmount, 'g', -1, 64)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value + "|" + order.ID))
+}
+
+// decodeOrderCursor returns the sort value and order ID stored in a cursor.
+func decodeOrderCursor(cursor, column string) (interface{}, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	value, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, "", ErrInvalidCursor
+	}
+
+	if column == "total_amount" {
+		total, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		return total, id, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
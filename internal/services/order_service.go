@@ -1,12 +1,17 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"restaurant-system/internal/config"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type OrderService struct {
@@ -17,7 +22,46 @@ func NewOrderService(db *database.DB) *OrderService {
 	return &OrderService{db: db}
 }
 
-func (s *OrderService) CreateOrder(req *models.CreateOrderRequest) (*models.Order, error) {
+// CreateOrder prices and stores an order and its items in a single transaction,
+// so a failure part way through never leaves a partial order behind.
+func (s *OrderService) CreateOrder(req *models.CreateOrderRequest, actor *models.Actor) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		order, err = s.createOrder(tx, req)
+		if err != nil {
+			return err
+		}
+		return recordOrderEvent(tx, order.ID, "", order.Status, actor, "order placed")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (s *OrderService) createOrder(tx *sql.Tx, req *models.CreateOrderRequest) (*models.Order, error) {
+	if err := req.ValidateType(time.Now()); err != nil {
+		return nil, err
+	}
+	// Paying at the door is for delivery, where the branch allows it
+	if req.PayOnDelivery && (req.Type != models.OrderTypeDelivery || !config.Branch().CashOnDelivery) {
+		return nil, fmt.Errorf("pay on delivery is not available for this order")
+	}
+	if req.Type == models.OrderTypeDineIn {
+		var exists bool
+		if err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM dining_tables WHERE id = $1 AND deleted_at IS NULL)",
+			req.TableID,
+		).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrTableNotFound
+		}
+	}
+
 	// Generate order ID
 	orderID := uuid.New().String()
 
@@ -25,72 +69,150 @@ func (s *OrderService) CreateOrder(req *models.CreateOrderRequest) (*models.Orde
 	var totalAmount float64
 	var orderItems []models.OrderItem
 
+	var menuItemIDs []string
 	for _, item := range req.Items {
-		// Get menu item details
-		var menuItem models.MenuItem
-		err := s.db.Conn().QueryRow(
-			"SELECT id, name, price FROM menu_items WHERE id = $1 AND available = TRUE",
-			item.MenuItemID,
-		).Scan(&menuItem.ID, &menuItem.Name, &menuItem.Price)
+		menuItemIDs = append(menuItemIDs, item.MenuItemID)
+	}
+	menuItems, err := loadAvailableMenuItems(tx, menuItemIDs)
+	if err != nil {
+		return nil, err
+	}
+	modifierGroups, err := loadModifierGroups(tx, menuItemIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
+	for _, item := range req.Items {
+		menuItem, ok := menuItems[item.MenuItemID]
+		if !ok {
 			return nil, fmt.Errorf("menu item not found or unavailable: %s", item.MenuItemID)
 		}
 
-		itemTotal := menuItem.Price * float64(item.Quantity)
+		modifiers, priceDelta, err := resolveModifiers(menuItem, modifierGroups[item.MenuItemID], item.Modifiers)
+		if err != nil {
+			return nil, err
+		}
+
+		itemAllergens, err := models.NormalizeAllergens(item.Allergens)
+		if err != nil {
+			return nil, err
+		}
+
+		course := item.Course
+		if course == 0 {
+			course = 1
+		}
+		if item.Hold && req.Type != models.OrderTypeDineIn {
+			return nil, fmt.Errorf("only dine-in orders can hold courses")
+		}
+
+		// Price is per unit and includes the chosen modifiers
+		unitPrice := menuItem.Price + priceDelta
+		itemTotal := unitPrice * float64(item.Quantity)
 		totalAmount += itemTotal
 
+		orderItemID := uuid.New().String()
+		for i := range modifiers {
+			modifiers[i].OrderItemID = orderItemID
+		}
+
 		orderItems = append(orderItems, models.OrderItem{
-			ID:         uuid.New().String(),
-			OrderID:    orderID,
-			MenuItemID: item.MenuItemID,
-			Name:       menuItem.Name,
-			Price:      menuItem.Price,
-			Quantity:   item.Quantity,
-			TotalPrice: itemTotal,
+			ID:          orderItemID,
+			OrderID:     orderID,
+			MenuItemID:  item.MenuItemID,
+			Name:        menuItem.Name,
+			Price:       unitPrice,
+			Quantity:    item.Quantity,
+			TotalPrice:  itemTotal,
+			Notes:       strings.TrimSpace(item.Notes),
+			Allergens:   itemAllergens,
+			Station:     menuItem.Station,
+			PrepStatus:  models.ItemPrepQueued,
+			PrepMinutes: menuItem.PrepMinutes,
+			Course:      course,
+			Held:        item.Hold,
+			Modifiers:   modifiers,
 		})
 	}
 
+	orderAllergens, err := models.NormalizeAllergens(req.Allergens)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take the next daily number; the sequence row stays locked until the transaction
+	// ends, so numbers are handed out without gaps or duplicates
+	branch := config.Branch()
+	now := time.Now()
+	businessDay := branch.BusinessDay(now)
+	var orderNumber int
+	err = tx.QueryRow(
+		`INSERT INTO order_number_sequences (branch_id, business_day, last_number) VALUES ($1, $2, 1)
+		ON CONFLICT (branch_id, business_day) DO UPDATE SET last_number = order_number_sequences.last_number + 1
+		RETURNING last_number`,
+		branch.ID, businessDay,
+	).Scan(&orderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	source := req.Source
+	if source == "" {
+		source = models.OrderSourceApp
+	}
+
 	// Create order
 	order := &models.Order{
-		ID:          orderID,
-		CustomerID:  req.CustomerID,
-		Items:       orderItems,
-		TotalAmount: totalAmount,
-		Status:      models.OrderStatusPending,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:              orderID,
+		OrderNumber:     orderNumber,
+		DisplayNumber:   models.FormatOrderNumber(orderNumber),
+		BranchID:        branch.ID,
+		BusinessDay:     businessDay,
+		CustomerID:      req.CustomerID,
+		Source:          source,
+		Type:            req.Type,
+		TableID:         req.TableID,
+		GuestCount:      req.GuestCount,
+		PickupAt:        req.PickupAt,
+		DeliveryAddress: req.DeliveryAddress,
+		ContactName:     req.ContactName,
+		ContactPhone:    req.ContactPhone,
+		Items:           orderItems,
+		TotalAmount:     totalAmount,
+		Status:          models.OrderStatusPending,
+		Notes:           strings.TrimSpace(req.Notes),
+		Allergens:       orderAllergens,
+		PayOnDelivery:   req.PayOnDelivery,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}
 
 	// Store order in database
-	_, err := s.db.Conn().Exec(
-		"INSERT INTO orders (id, customer_id, total_amount, status, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
-		order.ID, order.CustomerID, order.TotalAmount, order.Status, order.CreatedAt, order.UpdatedAt,
+	_, err = tx.Exec(
+		`INSERT INTO orders (id, order_number, branch_id, business_day, customer_id, source, table_token_id,
+			order_type, table_id, guest_count, pickup_at, delivery_address, contact_name, contact_phone,
+			total_amount, status, notes, allergens, pay_on_delivery, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, 0), $11, NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), $15, $16, $17, $18, $19, $20, $21)`,
+		order.ID, order.OrderNumber, order.BranchID, order.BusinessDay, order.CustomerID, order.Source, req.TableTokenID,
+		order.Type, order.TableID, order.GuestCount, order.PickupAt, order.DeliveryAddress, order.ContactName, order.ContactPhone, order.TotalAmount, order.Status, order.Notes, pq.Array(models.AllergenStrings(order.Allergens)), order.PayOnDelivery, order.CreatedAt, order.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Store order items
-	for _, item := range orderItems {
-		_, err := s.db.Conn().Exec(
-			"INSERT INTO order_items (id, order_id, menu_item_id, name, price, quantity, total_price) VALUES ($1, $2, $3, $4, $5, $6, $7)",
-			item.ID, item.OrderID, item.MenuItemID, item.Name, item.Price, item.Quantity, item.TotalPrice,
-		)
-		if err != nil {
-			return nil, err
-		}
+	if err := database.StoreOrderItems(tx, orderItems); err != nil {
+		return nil, err
 	}
 
 	return order, nil
 }
 
 func (s *OrderService) GetOrder(orderID string) (*models.Order, error) {
-	var order models.Order
-	err := s.db.Conn().QueryRow(
-		"SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders WHERE id = $1",
+	order, err := database.ScanOrder(s.db.Conn().QueryRow(
+		"SELECT "+database.OrderColumns+" FROM orders WHERE id = $1",
 		orderID,
-	).Scan(&order.ID, &order.CustomerID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+	))
 
 	if err != nil {
 		return nil, err
@@ -102,70 +224,185 @@ func (s *OrderService) GetOrder(orderID string) (*models.Order, error) {
 		return nil, err
 	}
 
-	return &order, nil
+	return order, nil
 }
 
-func (s *OrderService) GetOrders(customerID string) ([]*models.Order, error) {
-	var query string
+// ListOrders returns one page of orders matching query, items included. Pages are
+// keyed on the sort column and order ID, so they stay stable while new orders arrive.
+func (s *OrderService) ListOrders(query *models.OrderListQuery) (*models.OrderPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
 	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
 
-	if customerID != "" {
-		query = "SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders WHERE customer_id = $1 ORDER BY created_at DESC"
-		args = []interface{}{customerID}
-	} else {
-		query = "SELECT id, customer_id, total_amount, status, created_at, updated_at FROM orders ORDER BY created_at DESC"
+	if len(query.Statuses) > 0 {
+		statuses := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, "status = ANY("+arg(pq.Array(statuses))+")")
+	}
+	if query.Number > 0 {
+		conditions = append(conditions, "branch_id = "+arg(config.Branch().ID), "order_number = "+arg(query.Number), "business_day = "+arg(query.BusinessDay))
+	}
+	if len(query.Types) > 0 {
+		types := make([]string, len(query.Types))
+		for i, orderType := range query.Types {
+			types[i] = string(orderType)
+		}
+		conditions = append(conditions, "order_type = ANY("+arg(pq.Array(types))+")")
+	}
+	if query.Source != "" {
+		conditions = append(conditions, "source = "+arg(query.Source))
+	}
+	if query.CustomerID != "" {
+		conditions = append(conditions, "customer_id = "+arg(query.CustomerID))
+	}
+	if query.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*query.From))
+	}
+	if query.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*query.To))
+	}
+	switch query.PaymentState {
+	case models.PaymentStatePaid:
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM payments p WHERE p.order_id = orders.id AND p.status = "+arg(models.PaymentStatusCompleted)+")")
+	case models.PaymentStateUnpaid:
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = orders.id AND p.status = ANY("+
+			arg(pq.Array([]string{string(models.PaymentStatusCompleted), string(models.PaymentStatusRefundPending), string(models.PaymentStatusRefunded)}))+"))")
+	case models.PaymentStateRefunded:
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM payments p WHERE p.order_id = orders.id AND p.status = ANY("+
+			arg(pq.Array([]string{string(models.PaymentStatusRefundPending), string(models.PaymentStatusRefunded)}))+"))")
 	}
 
-	rows, err := s.db.Conn().Query(query, args...)
+	column, direction := "created_at", "DESC"
+	switch query.Sort {
+	case models.OrderSortOldest:
+		direction = "ASC"
+	case models.OrderSortTotalDesc:
+		column = "total_amount"
+	case models.OrderSortTotalAsc:
+		column, direction = "total_amount", "ASC"
+	}
+
+	if query.Cursor != "" {
+		value, id, err := decodeOrderCursor(query.Cursor, column)
+		if err != nil {
+			return nil, err
+		}
+		comparison := "<"
+		if direction == "ASC" {
+			comparison = ">"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)", column, comparison, arg(value), arg(id)))
+	}
+
+	sqlQuery := "SELECT " + database.OrderColumns + " FROM orders"
+	if len(conditions) > 0 {
+		sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	// Fetch one extra row to learn whether there is a next page
+	sqlQuery += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", column, direction, direction, arg(query.Limit+1))
+
+	rows, err := s.db.Conn().Query(sqlQuery, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var orders []*models.Order
+	orders := []*models.Order{}
 	for rows.Next() {
-		var order models.Order
-		err := rows.Scan(&order.ID, &order.CustomerID, &order.TotalAmount, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+		order, err := database.ScanOrder(rows)
 		if err != nil {
 			return nil, err
 		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Get order items
-		order.Items, err = s.db.GetOrderItems(order.ID)
-		if err != nil {
-			return nil, err
-		}
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > query.Limit {
+		page.Orders = orders[:query.Limit]
+		page.NextCursor = encodeOrderCursor(page.Orders[query.Limit-1], column)
+	}
 
-		orders = append(orders, &order)
+	// Get order items for the whole page at once
+	if err := s.db.LoadOrderItems(page.Orders); err != nil {
+		return nil, err
 	}
 
-	return orders, nil
+	return page, nil
 }
 
-func (s *OrderService) UpdateOrderStatus(orderID string, status models.OrderStatus) error {
-	_, err := s.db.Conn().Exec(
-		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3",
-		status, time.Now(), orderID,
-	)
-	return err
+// UpdateOrderStatus moves an order through the order state machine on behalf of actor.
+func (s *OrderService) UpdateOrderStatus(orderID string, status models.OrderStatus, actor *models.Actor, reason string) error {
+	if status == models.OrderStatusCancelled {
+		return ErrUseCancelEndpoint
+	}
+	return s.db.WithTx(func(tx *sql.Tx) error {
+		_, err := transitionOrder(tx, orderID, status, actor, reason)
+		return err
+	})
 }
 
-func (s *OrderService) GetMenuItems() ([]*models.MenuItem, error) {
-	rows, err := s.db.Conn().Query("SELECT id, name, description, price, category, available FROM menu_items WHERE available = TRUE")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// ErrCourseNotHeld is returned when firing a course that has nothing on hold.
+var ErrCourseNotHeld = errors.New("course has no items on hold")
 
-	var items []*models.MenuItem
-	for rows.Next() {
-		var item models.MenuItem
-		err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.Price, &item.Category, &item.Available)
+// FireCourse sends the held items of one course of an order to the kitchen. The
+// order's late alert is reset, since its target now runs from the firing.
+func (s *OrderService) FireCourse(orderID string, course int) (*models.Order, error) {
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var status models.OrderStatus
+		err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrOrderNotFound
+		}
 		if err != nil {
-			return nil, err
+			return err
 		}
-		items = append(items, &item)
+		if status.Terminal() {
+			return &TransitionError{From: status, To: status, Reason: "order is already " + string(status)}
+		}
+
+		now := time.Now()
+		result, err := tx.Exec(
+			"UPDATE order_items SET held = FALSE, fired_at = $1 WHERE order_id = $2 AND course = $3 AND held",
+			now, orderID, course,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: course %d", ErrCourseNotHeld, course)
+		}
+
+		// The fired items still have to be cooked
+		if status == models.OrderStatusReady {
+			reason := fmt.Sprintf("course %d fired", course)
+			if _, err := transitionOrder(tx, orderID, models.OrderStatusPreparing, models.SystemActor(), reason); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec("UPDATE orders SET late_alerted_at = NULL, updated_at = $1 WHERE id = $2", now, orderID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return items, nil
+	return s.GetOrder(orderID)
+}
+
+// GetOrderHistory returns every status change of an order, oldest first.
+func (s *OrderService) GetOrderHistory(orderID string) ([]models.OrderEvent, error) {
+	return getOrderHistory(s.db.Conn(), orderID)
 }
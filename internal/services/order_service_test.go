@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateOrderRollsBackOnFailure(t *testing.T) {
+	// A branch of its own keeps the order number sequence apart from other tests
+	t.Setenv("BRANCH_ID", "test-"+uuid.New().String())
+	db := openTestDB(t)
+	service := NewOrderService(db)
+	menuItems := availableMenuItems(t, db, 2)
+
+	// Fail the second item insert of any order, after the order row, its number and
+	// the first item have been written
+	if _, err := db.Conn().Exec(`
+		CREATE OR REPLACE FUNCTION test_fail_second_item() RETURNS trigger AS $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM order_items WHERE order_id = NEW.order_id) THEN
+				RAISE EXCEPTION 'injected failure';
+			END IF;
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Conn().Exec(`CREATE TRIGGER test_fail_second_item BEFORE INSERT ON order_items
+		FOR EACH ROW EXECUTE FUNCTION test_fail_second_item()`); err != nil {
+		t.Fatal(err)
+	}
+	dropTrigger := func() {
+		db.Conn().Exec("DROP TRIGGER IF EXISTS test_fail_second_item ON order_items")
+	}
+	t.Cleanup(dropTrigger)
+
+	branchID := config.Branch().ID
+	ordersBefore := countRows(t, db, "SELECT COUNT(*) FROM orders")
+	itemsBefore := countRows(t, db, "SELECT COUNT(*) FROM order_items")
+	eventsBefore := countRows(t, db, "SELECT COUNT(*) FROM order_events")
+
+	req := &models.CreateOrderRequest{
+		Items: []models.CreateOrderItem{
+			{MenuItemID: menuItems[0], Quantity: 1},
+			{MenuItemID: menuItems[1], Quantity: 2},
+		},
+	}
+	_, err := service.CreateOrder(req, models.SystemActor())
+	if err == nil || !strings.Contains(err.Error(), "injected failure") {
+		t.Fatalf("CreateOrder error = %v, want the injected failure", err)
+	}
+
+	if n := countRows(t, db, "SELECT COUNT(*) FROM orders"); n != ordersBefore {
+		t.Errorf("orders went from %d to %d rows", ordersBefore, n)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM orders WHERE branch_id = $1", branchID); n != 0 {
+		t.Errorf("%d orders left for the test branch", n)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM order_items"); n != itemsBefore {
+		t.Errorf("order_items went from %d to %d rows", itemsBefore, n)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM order_events"); n != eventsBefore {
+		t.Errorf("order_events went from %d to %d rows", eventsBefore, n)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM order_number_sequences WHERE branch_id = $1", branchID); n != 0 {
+		t.Errorf("%d order number sequence rows left for the test branch", n)
+	}
+
+	// Without the failure the same order goes through, and gets the first number:
+	// the failed attempt did not use one up
+	dropTrigger()
+	order, err := service.CreateOrder(req, models.SystemActor())
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if order.OrderNumber != 1 {
+		t.Errorf("order number = %d, want 1", order.OrderNumber)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM order_items WHERE order_id = $1", order.ID); n != 2 {
+		t.Errorf("order has %d items stored, want 2", n)
+	}
+}
+
+func TestConfirmUnpaidOrders(t *testing.T) {
+	db := openTestDB(t)
+	service := NewOrderService(db)
+	menuItems := availableMenuItems(t, db, 1)
+	waiter := staffActor(models.RoleWaiter)
+	items := []models.CreateOrderItem{{MenuItemID: menuItems[0], Quantity: 1}}
+
+	// A dine-in order taken by a waiter is paid at the end of the meal
+	dineIn, err := service.CreateOrder(&models.CreateOrderRequest{
+		Items:      items,
+		Type:       models.OrderTypeDineIn,
+		TableID:    testTable(t, db),
+		GuestCount: 2,
+	}, waiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dineIn.PayOnDelivery {
+		t.Error("dine-in order is marked pay on delivery")
+	}
+	if err := service.UpdateOrderStatus(dineIn.ID, models.OrderStatusConfirmed, waiter, ""); err != nil {
+		t.Errorf("confirming an unpaid dine-in order: %v", err)
+	}
+
+	// A takeaway order still has to be paid first
+	takeaway, err := service.CreateOrder(&models.CreateOrderRequest{Items: items}, waiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = service.UpdateOrderStatus(takeaway.ID, models.OrderStatusConfirmed, waiter, "")
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) || !strings.Contains(err.Error(), "not been paid") {
+		t.Errorf("confirming an unpaid takeaway order: error = %v, want it refused", err)
+	}
+}
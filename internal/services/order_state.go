@@ -0,0 +1,137 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrOrderNotFound is returned when an order does not exist.
+var ErrOrderNotFound = errors.New("order not found")
+
+// TransitionError is returned when an order cannot move to the requested status
+// from the one it is in now.
+type TransitionError struct {
+	From   models.OrderStatus `json:"from"`
+	To     models.OrderStatus `json:"to"`
+	Reason string             `json:"reason"`
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change order from %s to %s: %s", e.From, e.To, e.Reason)
+}
+
+// transitionOrder moves an order to status to, enforcing the order state machine,
+// the actor's role and the transition guards, and records the change in the order's
+// history. The order row stays locked until q's transaction ends. It returns the
+// status the order was in before.
+func transitionOrder(q database.Queryer, orderID string, to models.OrderStatus, actor *models.Actor, reason string) (models.OrderStatus, error) {
+	if !to.Valid() {
+		return "", fmt.Errorf("invalid order status: %s", to)
+	}
+
+	var from models.OrderStatus
+	var customerID string
+	var orderType models.OrderType
+	var payOnDelivery bool
+	err := q.QueryRow(
+		"SELECT status, COALESCE(customer_id, ''), order_type, pay_on_delivery FROM orders WHERE id = $1 FOR UPDATE",
+		orderID,
+	).Scan(&from, &customerID, &orderType, &payOnDelivery)
+	if err == sql.ErrNoRows {
+		return "", ErrOrderNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if !models.CanTransition(from, to) {
+		reason := "not an allowed transition"
+		if from == to {
+			reason = "order is already " + string(to)
+		} else if from.Terminal() {
+			reason = "order is already " + string(from)
+		}
+		return from, &TransitionError{From: from, To: to, Reason: reason}
+	}
+	if !actor.MayTransition(from, to, customerID != "" && customerID == actor.AccountID) {
+		return from, fmt.Errorf("%w: not allowed to change an order from %s to %s", ErrForbidden, from, to)
+	}
+
+	// A course still on hold has not been served, so the order cannot be done yet
+	if to == models.OrderStatusCompleted {
+		var heldCourse int
+		err := q.QueryRow("SELECT COALESCE(MIN(course), 0) FROM order_items WHERE order_id = $1 AND held", orderID).Scan(&heldCourse)
+		if err != nil {
+			return from, err
+		}
+		if heldCourse > 0 {
+			return from, &TransitionError{From: from, To: to, Reason: fmt.Sprintf("course %d is still on hold; fire it first", heldCourse)}
+		}
+	}
+
+	// An order only goes to the kitchen once it is paid, unless it is settled at the
+	// table or on delivery
+	if to == models.OrderStatusConfirmed && !orderType.SettledAtTable() && !payOnDelivery {
+		var paid bool
+		if err := q.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM payments WHERE order_id = $1 AND status = $2)",
+			orderID, models.PaymentStatusCompleted,
+		).Scan(&paid); err != nil {
+			return from, err
+		}
+		if !paid {
+			return from, &TransitionError{From: from, To: to, Reason: "order has not been paid"}
+		}
+	}
+
+	_, err = q.Exec(
+		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3",
+		to, time.Now(), orderID,
+	)
+	if err != nil {
+		return from, err
+	}
+	return from, recordOrderEvent(q, orderID, from, to, actor, reason)
+}
+
+// recordOrderEvent appends a status change to the order's history.
+func recordOrderEvent(q database.Queryer, orderID string, from, to models.OrderStatus, actor *models.Actor, reason string) error {
+	var accountID, deviceID string
+	if actor != nil {
+		accountID, deviceID = actor.AccountID, actor.DeviceID
+	}
+	_, err := q.Exec(
+		"INSERT INTO order_events (id, order_id, from_status, to_status, actor_account_id, device_id, reason, created_at) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)",
+		uuid.New().String(), orderID, from, to, accountID, deviceID, reason, time.Now(),
+	)
+	return err
+}
+
+// getOrderHistory returns the status changes of an order, oldest first.
+func getOrderHistory(q database.Queryer, orderID string) ([]models.OrderEvent, error) {
+	rows, err := q.Query(
+		"SELECT id, order_id, from_status, to_status, COALESCE(actor_account_id, ''), device_id, reason, created_at FROM order_events WHERE order_id = $1 ORDER BY created_at ASC",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OrderEvent{}
+	for rows.Next() {
+		var event models.OrderEvent
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.FromStatus, &event.ToStatus, &event.ActorAccountID, &event.DeviceID, &event.Reason, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"restaurant-system/internal/config"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Machine-readable codes carried by AuthError.
+const (
+	AuthErrUnauthorizedDevice  = "unauthorized_device"
+	AuthErrOTPCooldown         = "otp_cooldown"
+	AuthErrOTPLocked           = "otp_locked"
+	AuthErrOTPInvalid          = "otp_invalid"
+	AuthErrOTPExpired          = "otp_expired"
+	AuthErrOTPAttemptsExceeded = "otp_attempts_exceeded"
+)
+
+// AuthError is returned by the OTP flow so clients can react to the specific failure,
+// e.g. show a countdown using RetryAfter.
+type AuthError struct {
+	Code              string
+	Message           string
+	RetryAfter        time.Duration
+	AttemptsRemaining int
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// throttleKeys returns the auth_throttles keys tracked for a phone/device pair.
+func throttleKeys(phoneNumber, deviceID string) []string {
+	return []string{"phone:" + phoneNumber, "device:" + deviceID}
+}
+
+func (s *AuthService) checkDevice(deviceID string) error {
+	var exists int
+	err := s.db.Conn().QueryRow("SELECT 1 FROM authorized_devices WHERE device_id = $1 AND NOT disabled", deviceID).Scan(&exists)
+	if err != nil {
+		return &AuthError{Code: AuthErrUnauthorizedDevice, Message: "unauthorized device"}
+	}
+	return nil
+}
+
+// checkLockout fails if any of keys is currently locked out.
+func (s *AuthService) checkLockout(keys []string) error {
+	for _, key := range keys {
+		var lockedUntil sql.NullTime
+		err := s.db.Conn().QueryRow("SELECT locked_until FROM auth_throttles WHERE key = $1", key).Scan(&lockedUntil)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if wait := time.Until(lockedUntil.Time); lockedUntil.Valid && wait > 0 {
+			return &AuthError{
+				Code:       AuthErrOTPLocked,
+				Message:    fmt.Sprintf("too many failed attempts; try again in %ds", retrySeconds(wait)),
+				RetryAfter: wait,
+			}
+		}
+	}
+	return nil
+}
+
+// claimOTPRequest stamps the last request time for each key, failing if any key
+// requested a code within the cooldown. The keys are claimed together: when one is
+// in its cooldown, none is stamped. It returns the stamp, for releaseOTPRequest.
+func (s *AuthService) claimOTPRequest(keys []string) (time.Time, error) {
+	cooldown := config.Auth().OTPRequestCooldown
+	now := time.Now()
+
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		for _, key := range keys {
+			var claimed string
+			err := tx.QueryRow(
+				`INSERT INTO auth_throttles (key, last_request_at, updated_at) VALUES ($1, $2, $2)
+				ON CONFLICT (key) DO UPDATE SET last_request_at = EXCLUDED.last_request_at, updated_at = EXCLUDED.updated_at
+				WHERE auth_throttles.last_request_at IS NULL OR auth_throttles.last_request_at <= $3
+				RETURNING key`,
+				key, now, now.Add(-cooldown),
+			).Scan(&claimed)
+			if err == sql.ErrNoRows {
+				var last time.Time
+				if err := tx.QueryRow("SELECT last_request_at FROM auth_throttles WHERE key = $1", key).Scan(&last); err != nil {
+					return err
+				}
+				wait := last.Add(cooldown).Sub(now)
+				return &AuthError{
+					Code:       AuthErrOTPCooldown,
+					Message:    fmt.Sprintf("please wait %ds before requesting another code", retrySeconds(wait)),
+					RetryAfter: wait,
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return now, err
+}
+
+// releaseOTPRequest clears the stamps claimOTPRequest made at claimedAt, so a code
+// that could not be sent does not hold the user in the cooldown. Stamps made by a
+// later request are left alone.
+func (s *AuthService) releaseOTPRequest(keys []string, claimedAt time.Time) error {
+	_, err := s.db.Conn().Exec(
+		"UPDATE auth_throttles SET last_request_at = NULL WHERE key = ANY($1) AND last_request_at = $2",
+		pq.Array(keys), claimedAt,
+	)
+	return err
+}
+
+// recordFailure counts a failed verification against each key and locks the key
+// out once it reaches the configured limit. Failures older than the lockout window
+// no longer count.
+func (s *AuthService) recordFailure(keys []string) error {
+	cfg := config.Auth()
+	now := time.Now()
+
+	for _, key := range keys {
+		_, err := s.db.Conn().Exec(
+			`INSERT INTO auth_throttles (key, failures, updated_at) VALUES ($1, 1, $2)
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE WHEN auth_throttles.updated_at < $3 THEN 1 ELSE auth_throttles.failures + 1 END,
+				updated_at = EXCLUDED.updated_at`,
+			key, now, now.Add(-cfg.OTPLockout),
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.db.Conn().Exec(
+			"UPDATE auth_throttles SET locked_until = $1, failures = 0 WHERE key = $2 AND failures >= $3",
+			now.Add(cfg.OTPLockout), key, cfg.OTPMaxFailures,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) resetFailures(keys []string) error {
+	for _, key := range keys {
+		if _, err := s.db.Conn().Exec(
+			"UPDATE auth_throttles SET failures = 0, locked_until = NULL, updated_at = $1 WHERE key = $2",
+			time.Now(), key,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func retrySeconds(d time.Duration) int {
+	secs := int((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		return 1
+	}
+	return secs
+}
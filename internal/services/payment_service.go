@@ -1,7 +1,10 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/models"
@@ -12,6 +15,8 @@ import (
 	"github.com/google/uuid"
 )
 
+var ErrPaymentNotFound = errors.New("payment not found")
+
 type PaymentService struct {
 	db *database.DB
 }
@@ -20,78 +25,90 @@ func NewPaymentService(db *database.DB) *PaymentService {
 	return &PaymentService{db: db}
 }
 
-func (s *PaymentService) ProcessPayment(req *models.ProcessPaymentRequest) (*models.PaymentResponse, error) {
-	// Check if order exists and get its details
-	var orderAmount float64
-	var orderStatus string
-	err := s.db.Conn().QueryRow(
-		"SELECT total_amount, status FROM orders WHERE id = $1",
-		req.OrderID,
-	).Scan(&orderAmount, &orderStatus)
-
-	if err != nil {
-		return nil, fmt.Errorf("order not found")
+func (s *PaymentService) ProcessPayment(req *models.ProcessPaymentRequest, actor *models.Actor) (*models.PaymentResponse, error) {
+	// Cash changes hands at the register, so only cashiers and managers may record it
+	if req.Method == models.PaymentMethodCash && !actor.Can(models.PermPaymentsRecordCash) {
+		return nil, fmt.Errorf("%w: only cashiers and managers can record cash payments", ErrForbidden)
 	}
 
-	if orderStatus == string(models.OrderStatusCompleted) {
-		return nil, fmt.Errorf("order already completed")
+	// Validate the method before touching the database
+	switch req.Method {
+	case models.PaymentMethodMobileMoney, models.PaymentMethodCash, models.PaymentMethodCard:
+	default:
+		return nil, fmt.Errorf("unsupported payment method")
 	}
 
-	// Generate payment ID
-	paymentID := uuid.New().String()
+	var payment *models.Payment
+	var resp *models.PaymentResponse
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		// Lock the order so concurrent payments and status changes serialize
+		var orderAmount float64
+		var orderStatus string
+		var customerID string
+		err := tx.QueryRow(
+			"SELECT total_amount, status, COALESCE(customer_id, '') FROM orders WHERE id = $1 FOR UPDATE",
+			req.OrderID,
+		).Scan(&orderAmount, &orderStatus, &customerID)
 
-	// Create payment record (initial status processing)
-	payment := &models.Payment{
-		ID:            paymentID,
-		OrderID:       req.OrderID,
-		Amount:        orderAmount,
-		Method:        req.Method,
-		Status:        models.PaymentStatusProcessing,
-		TransactionID: "",
-		PhoneNumber:   req.PhoneNumber,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-	if _, err = s.db.Conn().Exec(
-		"INSERT INTO payments (id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
-		payment.ID, payment.OrderID, payment.Amount, payment.Method, payment.Status, payment.TransactionID, payment.PhoneNumber, payment.CreatedAt, payment.UpdatedAt,
-	); err != nil {
-		return nil, err
-	}
+		// Someone else's order looks the same as a missing one
+		if err != nil || !mayPayFor(actor, customerID) {
+			return ErrOrderNotFound
+		}
 
-	// Route by method
-	switch req.Method {
-	case models.PaymentMethodMobileMoney:
-		return s.initiateTelebirr(payment)
-	case models.PaymentMethodCash:
-		resp := s.processCashPayment(payment)
-		// Update payment status
-		_, err = s.db.Conn().Exec("UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", resp.Status, time.Now(), resp.ID)
-		if err != nil {
-			return nil, err
+		switch models.OrderStatus(orderStatus) {
+		case models.OrderStatusCompleted:
+			return fmt.Errorf("order already completed")
+		case models.OrderStatusCancelled:
+			return fmt.Errorf("order is cancelled")
 		}
-		// Update order if completed
-		if resp.Status == models.PaymentStatusCompleted {
-			if err := s.updateOrderAfterPayment(payment.OrderID); err != nil {
-				return nil, err
-			}
+
+		// Create payment record (initial status processing)
+		payment = &models.Payment{
+			ID:            uuid.New().String(),
+			OrderID:       req.OrderID,
+			Amount:        orderAmount,
+			Method:        req.Method,
+			Status:        models.PaymentStatusProcessing,
+			TransactionID: "",
+			PhoneNumber:   req.PhoneNumber,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
 		}
-		return resp, nil
-	case models.PaymentMethodCard:
-		resp := s.processCardPayment(payment)
-		_, err = s.db.Conn().Exec("UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", resp.Status, time.Now(), resp.ID)
-		if err != nil {
-			return nil, err
+		if _, err = tx.Exec(
+			"INSERT INTO payments (id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+			payment.ID, payment.OrderID, payment.Amount, payment.Method, payment.Status, payment.TransactionID, payment.PhoneNumber, payment.CreatedAt, payment.UpdatedAt,
+		); err != nil {
+			return err
+		}
+
+		// Mobile money completes asynchronously through the gateway callback
+		switch req.Method {
+		case models.PaymentMethodCash:
+			resp = s.processCashPayment(payment)
+		case models.PaymentMethodCard:
+			resp = s.processCardPayment(payment)
+		default:
+			return nil
+		}
+
+		// Update payment status, and the order if completed, in the same transaction
+		if _, err := tx.Exec("UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", resp.Status, time.Now(), resp.ID); err != nil {
+			return err
 		}
 		if resp.Status == models.PaymentStatusCompleted {
-			if err := s.updateOrderAfterPayment(payment.OrderID); err != nil {
-				return nil, err
-			}
+			resp.OrderConfirmed, err = s.updateOrderAfterPayment(tx, payment)
+			return err
 		}
-		return resp, nil
-	default:
-		return nil, fmt.Errorf("unsupported payment method")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == models.PaymentMethodMobileMoney {
+		return s.initiateTelebirr(payment)
 	}
+	return resp, nil
 }
 
 func (s *PaymentService) initiateTelebirr(payment *models.Payment) (*models.PaymentResponse, error) {
@@ -177,12 +194,50 @@ func (s *PaymentService) processCardPayment(payment *models.Payment) *models.Pay
 	return response
 }
 
-func (s *PaymentService) updateOrderAfterPayment(orderID string) error {
-	_, err := s.db.Conn().Exec(
-		"UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3",
-		models.OrderStatusConfirmed, time.Now(), orderID,
-	)
-	return err
+// updateOrderAfterPayment confirms a pending order once it has been paid, and reports
+// whether it did. Orders that have moved on, or were cancelled in the meantime, keep
+// their status.
+func (s *PaymentService) updateOrderAfterPayment(q database.Queryer, payment *models.Payment) (bool, error) {
+	var status models.OrderStatus
+	if err := q.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", payment.OrderID).Scan(&status); err != nil {
+		return false, err
+	}
+	if status != models.OrderStatusPending {
+		return false, nil
+	}
+	reason := fmt.Sprintf("%s payment %s completed", payment.Method, payment.ID)
+	if _, err := transitionOrder(q, payment.OrderID, models.OrderStatusConfirmed, models.SystemActor(), reason); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// mayPayFor reports whether actor may pay for, or look at the payments of, an order
+// placed by customerID: their own orders, or any order for staff.
+func mayPayFor(actor *models.Actor, customerID string) bool {
+	return (customerID != "" && customerID == actor.AccountID) || actor.Can(models.PermOrdersViewAll)
+}
+
+// GetPayment returns a payment for the actor, or ErrPaymentNotFound when it belongs
+// to an order the actor may not see.
+func (s *PaymentService) GetPayment(paymentID string, actor *models.Actor) (*models.Payment, error) {
+	payment, err := s.GetPaymentStatus(paymentID)
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var customerID string
+	err = s.db.Conn().QueryRow("SELECT COALESCE(customer_id, '') FROM orders WHERE id = $1", payment.OrderID).Scan(&customerID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if !mayPayFor(actor, customerID) {
+		return nil, ErrPaymentNotFound
+	}
+	return payment, nil
 }
 
 func (s *PaymentService) GetPaymentStatus(paymentID string) (*models.Payment, error) {
@@ -202,7 +257,8 @@ func (s *PaymentService) GetPaymentStatus(paymentID string) (*models.Payment, er
 // HandleTelebirrCallback updates payment and order based on Telebirr callback payload.
 // Expected fields include at least one of: outTradeNo (our payment ID), tradeNo (gateway id),
 // and a status/result code (e.g., SUCCESS).
-func (s *PaymentService) HandleTelebirrCallback(data map[string]string) error {
+// It returns the updated payment, and whether the payment confirmed its order.
+func (s *PaymentService) HandleTelebirrCallback(data map[string]string) (*models.Payment, bool, error) {
 	// Extract identifiers
 	outTradeNo := data["outTradeNo"]
 	if outTradeNo == "" {
@@ -242,32 +298,178 @@ func (s *PaymentService) HandleTelebirrCallback(data map[string]string) error {
 			tradeNo,
 		).Scan(&payment.ID, &payment.OrderID, &payment.Amount, &payment.Method, &payment.Status, &payment.TransactionID, &payment.PhoneNumber, &payment.CreatedAt, &payment.UpdatedAt)
 		if e != nil {
-			return e
+			return nil, false, e
 		}
 	} else if payment.ID == "" && err != nil {
-		return err
+		return nil, false, err
 	}
 
-	// Update payment status
-	newStatus := models.PaymentStatusFailed
-	if success {
-		newStatus = models.PaymentStatusCompleted
-	}
 	if tradeNo != "" && payment.TransactionID == "" {
 		payment.TransactionID = tradeNo
 	}
-	if _, e := s.db.Conn().Exec(
-		"UPDATE payments SET status = $1, transaction_id = COALESCE(NULLIF($2,''), transaction_id), updated_at = $3 WHERE id = $4",
-		newStatus, payment.TransactionID, time.Now(), payment.ID,
-	); e != nil {
-		return e
-	}
 
-	// Update order on success
-	if success {
-		if e := s.updateOrderAfterPayment(payment.OrderID); e != nil {
+	// Update payment status and, on success, the order together. The order is locked
+	// before the payment, like cancellation does, so the two can't deadlock.
+	var refund, confirmed bool
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		var orderStatus models.OrderStatus
+		if e := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", payment.OrderID).Scan(&orderStatus); e != nil {
 			return e
 		}
+		if e := tx.QueryRow("SELECT status FROM payments WHERE id = $1 FOR UPDATE", payment.ID).Scan(&payment.Status); e != nil {
+			return e
+		}
+
+		// Only a payment still waiting on the gateway can be settled; a replayed or
+		// late callback must not undo a refund
+		if payment.Status != models.PaymentStatusProcessing && payment.Status != models.PaymentStatusPending {
+			return nil
+		}
+
+		switch {
+		case !success:
+			payment.Status = models.PaymentStatusFailed
+		case orderStatus == models.OrderStatusCancelled:
+			// The order was cancelled while the customer was paying, so the money
+			// goes straight back
+			payment.Status = models.PaymentStatusRefundPending
+			refund = true
+		default:
+			payment.Status = models.PaymentStatusCompleted
+		}
+		payment.UpdatedAt = time.Now()
+		if _, e := tx.Exec(
+			"UPDATE payments SET status = $1, transaction_id = COALESCE(NULLIF($2,''), transaction_id), updated_at = $3 WHERE id = $4",
+			payment.Status, payment.TransactionID, payment.UpdatedAt, payment.ID,
+		); e != nil {
+			return e
+		}
+
+		if payment.Status == models.PaymentStatusCompleted {
+			var e error
+			confirmed, e = s.updateOrderAfterPayment(tx, &payment)
+			return e
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
 	}
+
+	if refund {
+		s.CompleteRefunds([]*models.Payment{&payment}, "order cancelled before payment completed")
+	}
+	return &payment, confirmed, nil
+}
+
+// refundOrderPayments marks the completed payments of an order as refunded and returns
+// them. Cash and card are refunded at the register straight away; mobile money is left
+// refund_pending until CompleteRefunds has sent the refund to the gateway, which must
+// happen after q's transaction commits.
+func refundOrderPayments(q database.Queryer, orderID string) ([]*models.Payment, error) {
+	rows, err := q.Query(
+		"SELECT id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at FROM payments WHERE order_id = $1 AND status = $2 FOR UPDATE",
+		orderID, models.PaymentStatusCompleted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var refunds []*models.Payment
+	for rows.Next() {
+		var payment models.Payment
+		if err := rows.Scan(&payment.ID, &payment.OrderID, &payment.Amount, &payment.Method, &payment.Status, &payment.TransactionID, &payment.PhoneNumber, &payment.CreatedAt, &payment.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refunds = append(refunds, &payment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, payment := range refunds {
+		payment.Status = models.PaymentStatusRefunded
+		if payment.Method == models.PaymentMethodMobileMoney {
+			payment.Status = models.PaymentStatusRefundPending
+		}
+		payment.UpdatedAt = time.Now()
+		if _, err := q.Exec("UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", payment.Status, payment.UpdatedAt, payment.ID); err != nil {
+			return nil, err
+		}
+	}
+	return refunds, nil
+}
+
+// CompleteRefunds sends the refunds left refund_pending by refundOrderPayments to the
+// payment gateway. A refund the gateway rejects stays refund_pending and is logged;
+// RunRefundRetrier tries it again later. The refund is numbered after the payment, so
+// a retry of a refund the gateway did take is not paid out twice.
+func (s *PaymentService) CompleteRefunds(refunds []*models.Payment, reason string) {
+	for _, payment := range refunds {
+		if payment.Status != models.PaymentStatusRefundPending {
+			continue
+		}
+		_, err := payments.RefundPayment(&payments.RefundRequest{
+			OutTradeNo:   payment.ID,
+			TradeNo:      payment.TransactionID,
+			RefundNo:     payment.ID,
+			RefundAmount: payment.Amount,
+			Reason:       reason,
+		})
+		if err != nil {
+			log.Printf("refund of payment %s failed: %v", payment.ID, err)
+			continue
+		}
+
+		payment.Status = models.PaymentStatusRefunded
+		payment.UpdatedAt = time.Now()
+		if _, err := s.db.Conn().Exec("UPDATE payments SET status = $1, updated_at = $2 WHERE id = $3", payment.Status, payment.UpdatedAt, payment.ID); err != nil {
+			log.Printf("failed to record refund of payment %s: %v", payment.ID, err)
+		}
+	}
+}
+
+// RetryRefunds sends the refunds still refund_pending after interval to the gateway
+// again. Each is claimed by bumping its updated_at, so several servers don't retry
+// the same refund at once.
+func (s *PaymentService) RetryRefunds(interval time.Duration) error {
+	now := time.Now()
+	rows, err := s.db.Conn().Query(
+		`UPDATE payments SET updated_at = $1 WHERE status = $2 AND updated_at < $3
+		RETURNING id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at`,
+		now, models.PaymentStatusRefundPending, now.Add(-interval),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var refunds []*models.Payment
+	for rows.Next() {
+		var payment models.Payment
+		if err := rows.Scan(&payment.ID, &payment.OrderID, &payment.Amount, &payment.Method, &payment.Status, &payment.TransactionID, &payment.PhoneNumber, &payment.CreatedAt, &payment.UpdatedAt); err != nil {
+			return err
+		}
+		refunds = append(refunds, &payment)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	s.CompleteRefunds(refunds, "order cancelled")
 	return nil
 }
+
+// RunRefundRetrier calls RetryRefunds every interval. It never returns.
+func (s *PaymentService) RunRefundRetrier(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.RetryRefunds(interval); err != nil {
+			log.Println("refund retrier:", err)
+		}
+	}
+}
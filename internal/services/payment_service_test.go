@@ -0,0 +1,141 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// newRefundGateway starts a stub Telebirr API that accepts every refund and
+// returns the refund request numbers it was sent.
+func newRefundGateway(t *testing.T) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var refunds []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		refunds = append(refunds, body["refundRequestNo"])
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"code":"SUCCESS","data":{"refundOrderId":"R1"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("TELEBIRR_API_BASE", srv.URL)
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), refunds...)
+	}
+}
+
+func TestTelebirrCallbackAfterCancellationRefunds(t *testing.T) {
+	db := openTestDB(t)
+	refunds := newRefundGateway(t)
+	orders := NewOrderService(db)
+	service := NewPaymentService(db)
+	menuItems := availableMenuItems(t, db, 1)
+
+	order, err := orders.CreateOrder(&models.CreateOrderRequest{
+		Items: []models.CreateOrderItem{{MenuItemID: menuItems[0], Quantity: 1}},
+	}, models.SystemActor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The customer is still on the Telebirr checkout page
+	paymentID := uuid.New().String()
+	if _, err := db.Conn().Exec(
+		"INSERT INTO payments (id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, 'T1', '', NOW(), NOW())",
+		paymentID, order.ID, order.TotalAmount, models.PaymentMethodMobileMoney, models.PaymentStatusPending,
+	); err != nil {
+		t.Fatal(err)
+	}
+	err = db.WithTx(func(tx *sql.Tx) error {
+		_, err := transitionOrder(tx, order.ID, models.OrderStatusCancelled, models.SystemActor(), "test")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment, _, err := service.HandleTelebirrCallback(map[string]string{"outTradeNo": paymentID, "status": "SUCCESS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Errorf("payment for a cancelled order is %s, want refunded", payment.Status)
+	}
+	if got := refunds(); len(got) != 1 || got[0] != paymentID {
+		t.Errorf("gateway got refunds %q, want one numbered after the payment", got)
+	}
+
+	// A replayed callback leaves the refund alone
+	payment, _, err = service.HandleTelebirrCallback(map[string]string{"outTradeNo": paymentID, "status": "SUCCESS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Errorf("replayed callback turned the refund into %s", payment.Status)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM payments WHERE id = $1 AND status = $2", paymentID, models.PaymentStatusRefunded); n != 1 {
+		t.Error("replayed callback changed the stored payment")
+	}
+	if got := refunds(); len(got) != 1 {
+		t.Errorf("gateway got %d refunds, want 1", len(got))
+	}
+	var orderStatus models.OrderStatus
+	if err := db.Conn().QueryRow("SELECT status FROM orders WHERE id = $1", order.ID).Scan(&orderStatus); err != nil {
+		t.Fatal(err)
+	}
+	if orderStatus != models.OrderStatusCancelled {
+		t.Errorf("order is %s, want it still cancelled", orderStatus)
+	}
+}
+
+func TestRetryRefunds(t *testing.T) {
+	db := openTestDB(t)
+	refunds := newRefundGateway(t)
+	orders := NewOrderService(db)
+	service := NewPaymentService(db)
+	menuItems := availableMenuItems(t, db, 1)
+
+	order, err := orders.CreateOrder(&models.CreateOrderRequest{
+		Items: []models.CreateOrderItem{{MenuItemID: menuItems[0], Quantity: 1}},
+	}, models.SystemActor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, fresh := uuid.New().String(), uuid.New().String()
+	for id, updatedAt := range map[string]time.Time{stale: time.Now().Add(-time.Hour), fresh: time.Now()} {
+		if _, err := db.Conn().Exec(
+			"INSERT INTO payments (id, order_id, amount, method, status, transaction_id, phone_number, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, 'T1', '', $6, $6)",
+			id, order.ID, order.TotalAmount, models.PaymentMethodMobileMoney, models.PaymentStatusRefundPending, updatedAt,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := service.RetryRefunds(10 * time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM payments WHERE id = $1 AND status = $2", stale, models.PaymentStatusRefunded); n != 1 {
+		t.Error("stale refund was not retried")
+	}
+	if n := countRows(t, db, "SELECT COUNT(*) FROM payments WHERE id = $1 AND status = $2", fresh, models.PaymentStatusRefundPending); n != 1 {
+		t.Error("a refund just sent was retried straight away")
+	}
+	for _, id := range refunds() {
+		if id == fresh {
+			t.Error("gateway got the fresh refund")
+		}
+	}
+}
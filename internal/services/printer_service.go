@@ -0,0 +1,478 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/printing"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPrinterNotFound = errors.New("printer not found")
+	// ErrNoPrinter is returned when nothing was printed because no enabled printer
+	// takes the job.
+	ErrNoPrinter = errors.New("no printer is set up for this")
+)
+
+// PrinterService manages the thermal printers and prints kitchen tickets and
+// receipts on them. Tickets print by themselves as items reach the kitchen; see
+// RunTicketPrinter.
+type PrinterService struct {
+	db      *database.DB
+	events  RoleBroadcaster
+	spooler *printing.Spooler
+}
+
+// NewPrinterService sets up a queue for each enabled printer. Printers going
+// offline or coming back, and jobs they give up on, are announced to the kitchen
+// and front of house as printer_status events.
+func NewPrinterService(db *database.DB, events RoleBroadcaster) *PrinterService {
+	s := &PrinterService{db: db, events: events}
+	s.spooler = printing.NewSpooler(config.Printing(), s.notifyStatus)
+	s.reload()
+	return s
+}
+
+const printerColumns = "id, name, kind, address, stations, receipts, line_width, enabled, created_at, updated_at"
+
+func scanPrinter(row database.Scanner) (*models.Printer, error) {
+	var p models.Printer
+	var stations pq.StringArray
+	err := row.Scan(&p.ID, &p.Name, &p.Kind, &p.Address, &stations, &p.Receipts, &p.Columns, &p.Enabled, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	p.Stations = []models.Station{}
+	for _, station := range stations {
+		p.Stations = append(p.Stations, models.Station(station))
+	}
+	return &p, nil
+}
+
+func (s *PrinterService) ListPrinters() ([]*models.Printer, error) {
+	rows, err := s.db.Conn().Query("SELECT " + printerColumns + " FROM printers ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	printers := []*models.Printer{}
+	for rows.Next() {
+		p, err := scanPrinter(rows)
+		if err != nil {
+			return nil, err
+		}
+		printers = append(printers, p)
+	}
+	return printers, rows.Err()
+}
+
+func (s *PrinterService) GetPrinter(printerID string) (*models.Printer, error) {
+	p, err := scanPrinter(s.db.Conn().QueryRow("SELECT "+printerColumns+" FROM printers WHERE id = $1", printerID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPrinterNotFound
+	}
+	return p, err
+}
+
+func (s *PrinterService) CreatePrinter(req *models.CreatePrinterRequest) (*models.Printer, error) {
+	now := time.Now()
+	p := &models.Printer{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(req.Name),
+		Kind:      req.Kind,
+		Address:   strings.TrimSpace(req.Address),
+		Stations:  req.Stations,
+		Receipts:  req.Receipts,
+		Columns:   req.Columns,
+		Enabled:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	if err := validatePrinter(p); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Conn().Exec(
+		"INSERT INTO printers ("+printerColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		p.ID, p.Name, p.Kind, p.Address, pq.Array(stationStrings(p.Stations)), p.Receipts, p.Columns, p.Enabled, p.CreatedAt, p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reload()
+	return p, nil
+}
+
+func (s *PrinterService) UpdatePrinter(printerID string, req *models.UpdatePrinterRequest) (*models.Printer, error) {
+	p, err := s.GetPrinter(printerID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		p.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Kind != nil {
+		p.Kind = *req.Kind
+	}
+	if req.Address != nil {
+		p.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.Stations != nil {
+		p.Stations = *req.Stations
+	}
+	if req.Receipts != nil {
+		p.Receipts = *req.Receipts
+	}
+	if req.Columns != nil {
+		p.Columns = *req.Columns
+	}
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+	if err := validatePrinter(p); err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = time.Now()
+
+	_, err = s.db.Conn().Exec(
+		`UPDATE printers SET name = $1, kind = $2, address = $3, stations = $4, receipts = $5, line_width = $6, enabled = $7, updated_at = $8
+		WHERE id = $9`,
+		p.Name, p.Kind, p.Address, pq.Array(stationStrings(p.Stations)), p.Receipts, p.Columns, p.Enabled, p.UpdatedAt, p.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reload()
+	return p, nil
+}
+
+// DeletePrinter removes a printer. Jobs still waiting for it are dropped.
+func (s *PrinterService) DeletePrinter(printerID string) error {
+	result, err := s.db.Conn().Exec("DELETE FROM printers WHERE id = $1", printerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPrinterNotFound
+	}
+
+	s.reload()
+	return nil
+}
+
+func validatePrinter(p *models.Printer) error {
+	if p.Name == "" {
+		return errors.New("printer name is required")
+	}
+	if !p.Kind.Valid() {
+		return fmt.Errorf("invalid printer kind: %s", p.Kind)
+	}
+	if p.Kind == models.PrinterKindNetwork && p.Address == "" {
+		return errors.New("network printers need an address")
+	}
+	for _, station := range p.Stations {
+		if !station.Valid() {
+			return fmt.Errorf("%w: %s", ErrInvalidStation, station)
+		}
+	}
+	if p.Stations == nil {
+		p.Stations = []models.Station{}
+	}
+	if p.Columns == 0 {
+		p.Columns = models.DefaultPrinterColumns
+	}
+	return nil
+}
+
+func stationStrings(stations []models.Station) []string {
+	out := make([]string, len(stations))
+	for i, station := range stations {
+		out[i] = string(station)
+	}
+	return out
+}
+
+// reload points the print queues at the printers as they are now in the database.
+func (s *PrinterService) reload() {
+	printers, err := s.ListPrinters()
+	if err != nil {
+		log.Println("load printers:", err)
+		return
+	}
+	if err := s.spooler.Configure(printers); err != nil {
+		log.Println("set up printers:", err)
+	}
+}
+
+// Statuses returns the queue status of every printer. Disabled printers are
+// reported offline.
+func (s *PrinterService) Statuses() ([]models.PrinterStatus, error) {
+	printers, err := s.ListPrinters()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []models.PrinterStatus{}
+	for _, p := range printers {
+		status, ok := s.spooler.Status(p.ID)
+		if !ok {
+			status = models.PrinterStatus{PrinterID: p.ID, Name: p.Name, Enabled: p.Enabled}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// notifyStatus announces a printer going offline, coming back or giving up on a job.
+func (s *PrinterService) notifyStatus(status models.PrinterStatus) {
+	if !status.Online {
+		log.Printf("printer %s is offline: %s", status.Name, status.LastError)
+	}
+	s.events.BroadcastToRoles(map[string]interface{}{
+		"type": "printer_status",
+		"data": status,
+	}, string(models.RoleKitchen), "staff")
+}
+
+// PrintTest queues a test page on a printer.
+func (s *PrinterService) PrintTest(printerID string) error {
+	p, err := s.GetPrinter(printerID)
+	if err != nil {
+		return err
+	}
+	return s.spooler.Print(p.ID, printing.Job{
+		Title: "test page",
+		Data:  printing.TestPage(p.Name, p.Columns, time.Now()),
+	})
+}
+
+// PrintNewTickets prints the tickets for items that reached the kitchen since the
+// last call: items of newly confirmed orders and newly fired courses. Items are
+// claimed as they are queued, so each prints once even with several servers. Items
+// whose ticket no printer would take stay unclaimed and are tried again next call.
+func (s *PrinterService) PrintNewTickets() error {
+	var errs []error
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			`UPDATE order_items oi SET printed_at = $1 FROM orders o
+			WHERE o.id = oi.order_id AND oi.printed_at IS NULL AND NOT oi.held AND o.status IN ($2, $3)
+			RETURNING oi.order_id, oi.id`,
+			time.Now(), models.OrderStatusConfirmed, models.OrderStatusPreparing,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		var orderIDs []string
+		claimed := map[string]map[string]bool{}
+		for rows.Next() {
+			var orderID, itemID string
+			if err := rows.Scan(&orderID, &itemID); err != nil {
+				return err
+			}
+			if claimed[orderID] == nil {
+				claimed[orderID] = map[string]bool{}
+				orderIDs = append(orderIDs, orderID)
+			}
+			claimed[orderID][itemID] = true
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		var unclaim []string
+		for _, orderID := range orderIDs {
+			order, err := s.loadOrder(orderID)
+			if err != nil {
+				return err
+			}
+			var items []models.OrderItem
+			for _, item := range order.Items {
+				if claimed[orderID][item.ID] {
+					items = append(items, item)
+				}
+			}
+			order.Items = items
+
+			// Stations without a printer read their tickets off the screen instead
+			_, missed, err := s.printTickets(order, false)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, item := range items {
+				if missed[item.StationOf()] {
+					unclaim = append(unclaim, item.ID)
+				}
+			}
+		}
+
+		if len(unclaim) > 0 {
+			_, err := tx.Exec("UPDATE order_items SET printed_at = NULL WHERE id = ANY($1)", pq.Array(unclaim))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// ReprintTickets prints the tickets of every item of an order already sent to the
+// kitchen again, marked as a reprint.
+func (s *PrinterService) ReprintTickets(orderID string) error {
+	order, err := s.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	queued, _, err := s.printTickets(order, true)
+	if err != nil {
+		return err
+	}
+	if queued == 0 {
+		return fmt.Errorf("%w: no station printer takes this order's items", ErrNoPrinter)
+	}
+	return nil
+}
+
+// printTickets queues the order's station tickets on the printers of their stations
+// and returns how many jobs were queued, and the stations that have printers none
+// of which took their ticket. Held items are left off.
+func (s *PrinterService) printTickets(order *models.Order, reprint bool) (int, map[models.Station]bool, error) {
+	printers, err := s.ListPrinters()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	info := printing.TicketInfo{TableName: s.tableName(order), PrintedAt: time.Now(), Reprint: reprint}
+	queued := 0
+	missed := map[models.Station]bool{}
+	var errs []error
+	for _, ticket := range order.StationTickets() {
+		took := false
+		for _, p := range printers {
+			if !p.Enabled || !p.PrintsStation(ticket.Station) {
+				continue
+			}
+			err := s.spooler.Print(p.ID, printing.Job{
+				Title: order.DisplayNumber + " " + string(ticket.Station),
+				Data:  printing.KitchenTicket(ticket, info, p.Columns),
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("printer %s: %w", p.Name, err))
+				missed[ticket.Station] = !took
+				continue
+			}
+			took = true
+			missed[ticket.Station] = false
+			queued++
+		}
+	}
+	return queued, missed, errors.Join(errs...)
+}
+
+// PrintReceipt prints a customer receipt for an order on every receipt printer.
+func (s *PrinterService) PrintReceipt(orderID string) error {
+	order, err := s.loadOrder(orderID)
+	if err != nil {
+		return err
+	}
+	printers, err := s.ListPrinters()
+	if err != nil {
+		return err
+	}
+
+	var paidWith string
+	err = s.db.Conn().QueryRow(
+		"SELECT method FROM payments WHERE order_id = $1 AND status = $2 ORDER BY updated_at DESC LIMIT 1",
+		orderID, models.PaymentStatusCompleted,
+	).Scan(&paidWith)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	info := printing.ReceiptInfo{
+		Header:    config.Printing().ReceiptHeader,
+		TableName: s.tableName(order),
+		PaidWith:  paidWith,
+		PrintedAt: time.Now(),
+	}
+	queued := 0
+	for _, p := range printers {
+		if !p.Enabled || !p.Receipts {
+			continue
+		}
+		err := s.spooler.Print(p.ID, printing.Job{
+			Title: order.DisplayNumber + " receipt",
+			Data:  printing.Receipt(order, info, p.Columns),
+		})
+		if err != nil {
+			return fmt.Errorf("printer %s: %w", p.Name, err)
+		}
+		queued++
+	}
+	if queued == 0 {
+		return fmt.Errorf("%w: no receipt printer is enabled", ErrNoPrinter)
+	}
+	return nil
+}
+
+func (s *PrinterService) loadOrder(orderID string) (*models.Order, error) {
+	order, err := database.ScanOrder(s.db.Conn().QueryRow(
+		"SELECT "+database.OrderColumns+" FROM orders WHERE id = $1",
+		orderID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order.Items, err = s.db.GetOrderItems(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// tableName returns the name of a dine-in order's table, or "" when there is none.
+func (s *PrinterService) tableName(order *models.Order) string {
+	if order.TableID == "" {
+		return ""
+	}
+	var name string
+	if err := s.db.Conn().QueryRow("SELECT name FROM dining_tables WHERE id = $1", order.TableID).Scan(&name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// RunTicketPrinter calls PrintNewTickets every interval. It never returns.
+func (s *PrinterService) RunTicketPrinter(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.PrintNewTickets(); err != nil {
+			log.Println("ticket printer:", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/printing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// nopBroadcaster drops every event.
+type nopBroadcaster struct{}
+
+func (nopBroadcaster) Broadcast(interface{})                   {}
+func (nopBroadcaster) BroadcastToRoles(interface{}, ...string) {}
+
+func TestPrintNewTickets(t *testing.T) {
+	spoolDir := t.TempDir()
+	t.Setenv("PRINT_SPOOL_DIR", spoolDir)
+	db := openTestDB(t)
+	menuItems := availableMenuItems(t, db, 2)
+
+	waiter := staffActor(models.RoleWaiter)
+	orders := NewOrderService(db)
+	order, err := orders.CreateOrder(&models.CreateOrderRequest{
+		Type:       models.OrderTypeDineIn,
+		TableID:    testTable(t, db),
+		GuestCount: 2,
+		Items: []models.CreateOrderItem{
+			{MenuItemID: menuItems[0], Quantity: 1, Course: 1},
+			{MenuItemID: menuItems[1], Quantity: 1, Course: 2, Hold: true},
+		},
+	}, waiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var starter, main string
+	for _, item := range order.Items {
+		if item.Course == 1 {
+			starter = item.ID
+		} else {
+			main = item.ID
+		}
+	}
+	if err := orders.UpdateOrderStatus(order.ID, models.OrderStatusConfirmed, waiter, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	printed := func(itemID string) bool {
+		t.Helper()
+		return countRows(t, db, "SELECT COUNT(*) FROM order_items WHERE id = $1 AND printed_at IS NOT NULL", itemID) == 1
+	}
+
+	// A printer added behind the service's back has no queue yet, so nothing it
+	// should print can be queued
+	service := NewPrinterService(db, nopBroadcaster{})
+	printerID := uuid.New().String()
+	if _, err := db.Conn().Exec(
+		"INSERT INTO printers ("+printerColumns+") VALUES ($1, $2, $3, $4, $5, FALSE, $6, TRUE, NOW(), NOW())",
+		printerID, "test-"+printerID, models.PrinterKindFile, printerID, pq.Array(stationStrings(models.AllStations)), models.DefaultPrinterColumns,
+	); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Conn().Exec("DELETE FROM printers WHERE id = $1", printerID)
+	})
+
+	if err := service.PrintNewTickets(); !errors.Is(err, printing.ErrNoSuchQueue) {
+		t.Fatalf("PrintNewTickets with no queue = %v, want ErrNoSuchQueue", err)
+	}
+	if printed(starter) {
+		t.Error("items whose ticket could not be queued stay claimed")
+	}
+
+	service.reload()
+	if err := service.PrintNewTickets(); err != nil {
+		t.Fatal(err)
+	}
+	if !printed(starter) {
+		t.Error("course 1 was not printed")
+	}
+	if printed(main) {
+		t.Error("held course 2 was printed")
+	}
+
+	if _, err := orders.FireCourse(order.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.PrintNewTickets(); err != nil {
+		t.Fatal(err)
+	}
+	if !printed(main) {
+		t.Error("course 2 was not printed once fired")
+	}
+
+	// The queue prints in the background
+	deadline := time.Now().Add(2 * time.Second)
+	var files []string
+	for len(files) < 2 && time.Now().Before(deadline) {
+		files, _ = filepath.Glob(filepath.Join(spoolDir, printerID, "*.bin"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(files) < 2 {
+		t.Errorf("spooled %d tickets, want one for each course at least", len(files))
+	}
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/sms"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrNoTableAvailable is returned when no table can take the party at the requested time.
+	ErrNoTableAvailable = errors.New("no table available for this party at this time")
+	// ErrReservationStatus is returned for a status change the reservation's current status does not allow.
+	ErrReservationStatus = errors.New("reservation cannot be changed in its current status")
+	// ErrSMSFailed is returned when a text to a guest could not be sent.
+	ErrSMSFailed = errors.New("failed to send SMS")
+)
+
+const (
+	// reservationSlot is the step between alternative times offered when a time is full.
+	reservationSlot = 15 * time.Minute
+	// maxAlternatives is how many alternative times are offered.
+	maxAlternatives = 4
+	// bookingSlack lets a booking made at the door start a few minutes in the past.
+	bookingSlack = 5 * time.Minute
+)
+
+// ReservationService books tables ahead of time. A reservation holds its table for
+// the party's turn time, so two bookings never overlap on one table.
+type ReservationService struct {
+	db     *database.DB
+	tables *TableService
+	sms    sms.Sender
+}
+
+func NewReservationService(db *database.DB, tableService *TableService, sender sms.Sender) *ReservationService {
+	return &ReservationService{db: db, tables: tableService, sms: sender}
+}
+
+const reservationColumns = "id, account_id, previous_visits, phone_number, name, party_size, reserved_at, ends_at, table_id, notes, status, locale, COALESCE(created_by, ''), created_at, updated_at"
+
+func scanReservation(row database.Scanner) (*models.Reservation, error) {
+	var r models.Reservation
+	err := row.Scan(&r.ID, &r.AccountID, &r.PreviousVisits, &r.PhoneNumber, &r.Name, &r.PartySize, &r.ReservedAt, &r.EndsAt,
+		&r.TableID, &r.Notes, &r.Status, &r.Locale, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.ReturningGuest = r.PreviousVisits > 0
+	return &r, nil
+}
+
+// Availability returns the tables that can take a party of partySize at at, smallest
+// first. When there are none it suggests the nearest times that have one.
+func (s *ReservationService) Availability(partySize int, at time.Time) (*models.Availability, error) {
+	if partySize < 1 {
+		return nil, fmt.Errorf("party_size must be at least 1")
+	}
+	until := at.Add(config.Reservations().TurnTimeFor(partySize))
+	tables, err := availableTables(s.db.Conn(), partySize, at, until, "")
+	if err != nil {
+		return nil, err
+	}
+
+	availability := &models.Availability{PartySize: partySize, At: at, Until: until, Tables: tables}
+	if len(tables) == 0 {
+		availability.Alternatives, err = s.alternatives(partySize, at)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return availability, nil
+}
+
+// alternatives looks for free slots up to an hour either side of at, nearest first.
+func (s *ReservationService) alternatives(partySize int, at time.Time) ([]time.Time, error) {
+	turnTime := config.Reservations().TurnTimeFor(partySize)
+	earliest := time.Now().Add(-bookingSlack)
+	alternatives := []time.Time{}
+	for step := reservationSlot; step <= time.Hour && len(alternatives) < maxAlternatives; step += reservationSlot {
+		for _, candidate := range []time.Time{at.Add(-step), at.Add(step)} {
+			if candidate.Before(earliest) || len(alternatives) == maxAlternatives {
+				continue
+			}
+			tables, err := availableTables(s.db.Conn(), partySize, candidate, candidate.Add(turnTime), "")
+			if err != nil {
+				return nil, err
+			}
+			if len(tables) > 0 {
+				alternatives = append(alternatives, candidate)
+			}
+		}
+	}
+	return alternatives, nil
+}
+
+// CreateReservation books a table and texts the guest a confirmation. The guest is
+// matched to an account by phone number, so returning guests are recognized.
+func (s *ReservationService) CreateReservation(req *models.CreateReservationRequest, actor *models.Actor) (*models.Reservation, error) {
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	now := time.Now()
+	if req.ReservedAt.Before(now.Add(-bookingSlack)) {
+		return nil, fmt.Errorf("reserved_at must not be in the past")
+	}
+
+	accountID, visits, err := recognizeGuest(s.db, req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	reservation := &models.Reservation{
+		ID:             uuid.New().String(),
+		AccountID:      accountID,
+		PreviousVisits: visits,
+		ReturningGuest: visits > 0,
+		PhoneNumber:    req.PhoneNumber,
+		Name:           strings.TrimSpace(req.Name),
+		PartySize:      req.PartySize,
+		ReservedAt:     req.ReservedAt,
+		EndsAt:         req.ReservedAt.Add(config.Reservations().TurnTimeFor(req.PartySize)),
+		Notes:          strings.TrimSpace(req.Notes),
+		Status:         models.ReservationStatusBooked,
+		Locale:         req.Locale,
+		CreatedBy:      actor.AccountID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		tableID, err := pickTable(tx, reservation, req.TableID)
+		if err != nil {
+			return err
+		}
+		reservation.TableID = tableID
+
+		_, err = tx.Exec(
+			`INSERT INTO reservations (id, account_id, previous_visits, phone_number, name, party_size, reserved_at, ends_at,
+				table_id, notes, status, locale, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15)`,
+			reservation.ID, reservation.AccountID, reservation.PreviousVisits, reservation.PhoneNumber, reservation.Name,
+			reservation.PartySize, reservation.ReservedAt, reservation.EndsAt, reservation.TableID, reservation.Notes,
+			reservation.Status, reservation.Locale, reservation.CreatedBy, reservation.CreatedAt, reservation.UpdatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(reservation.PhoneNumber, reservation.Locale, sms.MessageReservationBooked, map[string]interface{}{
+		"PartySize":  reservation.PartySize,
+		"Restaurant": config.Reservations().RestaurantName,
+		"Time":       reservation.ReservedAt.In(config.Branch().Location).Format("Mon 2 Jan 15:04"),
+	})
+	return reservation, nil
+}
+
+// ListReservations returns the reservations starting on day (YYYY-MM-DD, branch time),
+// optionally only those in status, in time order.
+func (s *ReservationService) ListReservations(day string, status models.ReservationStatus) ([]*models.Reservation, error) {
+	start, err := time.ParseInLocation("2006-01-02", day, config.Branch().Location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", day)
+	}
+	if status != "" && !status.Valid() {
+		return nil, fmt.Errorf("invalid reservation status: %s", status)
+	}
+
+	rows, err := s.db.Conn().Query(
+		"SELECT "+reservationColumns+" FROM reservations WHERE reserved_at >= $1 AND reserved_at < $2 AND ($3 = '' OR status = $3) ORDER BY reserved_at, created_at",
+		start, start.AddDate(0, 0, 1), status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []*models.Reservation{}
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, rows.Err()
+}
+
+func (s *ReservationService) GetReservation(reservationID string) (*models.Reservation, error) {
+	reservation, err := scanReservation(s.db.Conn().QueryRow(
+		"SELECT "+reservationColumns+" FROM reservations WHERE id = $1",
+		reservationID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, err
+}
+
+// UpdateReservation changes a booked reservation. A new time, party size or table
+// must still be available; the current table is kept when it still fits.
+func (s *ReservationService) UpdateReservation(reservationID string, req *models.UpdateReservationRequest) (*models.Reservation, error) {
+	var reservation *models.Reservation
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if reservation, err = lockReservation(tx, reservationID); err != nil {
+			return err
+		}
+		if reservation.Status != models.ReservationStatusBooked {
+			return ErrReservationStatus
+		}
+
+		if req.Name != nil {
+			reservation.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Notes != nil {
+			reservation.Notes = strings.TrimSpace(*req.Notes)
+		}
+		if req.PartySize != nil || req.ReservedAt != nil || req.TableID != nil {
+			if req.PartySize != nil {
+				reservation.PartySize = *req.PartySize
+			}
+			if req.ReservedAt != nil {
+				if req.ReservedAt.Before(time.Now().Add(-bookingSlack)) {
+					return fmt.Errorf("reserved_at must not be in the past")
+				}
+				reservation.ReservedAt = *req.ReservedAt
+			}
+			reservation.EndsAt = reservation.ReservedAt.Add(config.Reservations().TurnTimeFor(reservation.PartySize))
+
+			wanted := reservation.TableID
+			if req.TableID != nil {
+				wanted = *req.TableID
+			}
+			tableID, err := pickTable(tx, reservation, wanted)
+			if errors.Is(err, ErrNoTableAvailable) && req.TableID == nil {
+				// The old table no longer works; any other that does will do
+				tableID, err = pickTable(tx, reservation, "")
+			}
+			if err != nil {
+				return err
+			}
+			reservation.TableID = tableID
+		}
+
+		reservation.UpdatedAt = time.Now()
+		_, err = tx.Exec(
+			"UPDATE reservations SET name = $1, notes = $2, party_size = $3, reserved_at = $4, ends_at = $5, table_id = $6, updated_at = $7 WHERE id = $8",
+			reservation.Name, reservation.Notes, reservation.PartySize, reservation.ReservedAt, reservation.EndsAt,
+			reservation.TableID, reservation.UpdatedAt, reservation.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// SetStatus moves a reservation along: seating the party takes its table, and
+// completing it releases the table for later bookings.
+func (s *ReservationService) SetStatus(reservationID string, status models.ReservationStatus) (*models.Reservation, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("invalid reservation status: %s", status)
+	}
+
+	var reservation *models.Reservation
+	var seated *models.DiningTable
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if reservation, err = lockReservation(tx, reservationID); err != nil {
+			return err
+		}
+		if !reservation.Status.CanMoveTo(status) {
+			return ErrReservationStatus
+		}
+
+		now := time.Now()
+		switch status {
+		case models.ReservationStatusSeated:
+			if seated, err = seatParty(tx, reservation.TableID); err != nil {
+				return err
+			}
+			// The party keeps the table for a full turn from when they sat down
+			if end := now.Add(config.Reservations().TurnTimeFor(reservation.PartySize)); end.After(reservation.EndsAt) {
+				reservation.EndsAt = end
+			}
+		case models.ReservationStatusCompleted:
+			reservation.EndsAt = now
+		}
+
+		reservation.Status = status
+		reservation.UpdatedAt = now
+		_, err = tx.Exec(
+			"UPDATE reservations SET status = $1, ends_at = $2, updated_at = $3 WHERE id = $4",
+			reservation.Status, reservation.EndsAt, reservation.UpdatedAt, reservation.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if seated != nil {
+		s.tables.notifyTableUpdated(seated)
+	}
+	return reservation, nil
+}
+
+// notify texts a guest. Failures are logged; the booking stands either way.
+func (s *ReservationService) notify(phoneNumber, locale, key string, data map[string]interface{}) {
+	if err := sendGuestSMS(s.sms, phoneNumber, locale, key, data); err != nil {
+		log.Println("reservations:", err)
+	}
+}
+
+func lockReservation(q database.Queryer, reservationID string) (*models.Reservation, error) {
+	reservation, err := scanReservation(q.QueryRow(
+		"SELECT "+reservationColumns+" FROM reservations WHERE id = $1 FOR UPDATE",
+		reservationID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrReservationNotFound
+	}
+	return reservation, err
+}
+
+// pickTable returns the table for a reservation: tableID if it is available, or the
+// smallest available table when tableID is empty. The tables that could seat the
+// party are locked first, so concurrent bookings cannot take the same slot.
+func pickTable(tx *sql.Tx, reservation *models.Reservation, tableID string) (string, error) {
+	if tableID != "" {
+		var exists bool
+		if err := tx.QueryRow(
+			"SELECT EXISTS (SELECT 1 FROM dining_tables WHERE id = $1 AND deleted_at IS NULL)",
+			tableID,
+		).Scan(&exists); err != nil {
+			return "", err
+		}
+		if !exists {
+			return "", ErrTableNotFound
+		}
+	}
+
+	if _, err := tx.Exec(
+		"SELECT id FROM dining_tables WHERE capacity >= $1 AND deleted_at IS NULL ORDER BY id FOR UPDATE",
+		reservation.PartySize,
+	); err != nil {
+		return "", err
+	}
+	tables, err := availableTables(tx, reservation.PartySize, reservation.ReservedAt, reservation.EndsAt, reservation.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, table := range tables {
+		if tableID == "" || table.ID == tableID {
+			return table.ID, nil
+		}
+	}
+	return "", ErrNoTableAvailable
+}
+
+// availableTables returns the tables that seat partySize, have no other reservation
+// overlapping [start, end) and are expected to be free by start, smallest first.
+// excludeID leaves a reservation being changed out of the overlap check.
+func availableTables(q database.Queryer, partySize int, start, end time.Time, excludeID string) ([]*models.DiningTable, error) {
+	rows, err := q.Query(
+		`SELECT `+tableColumns+` FROM dining_tables t
+		WHERE t.deleted_at IS NULL AND t.capacity >= $1
+		AND NOT EXISTS (
+			SELECT 1 FROM reservations r
+			WHERE r.table_id = t.id AND r.status IN ('booked', 'seated') AND r.id <> $4
+			AND r.reserved_at < $3 AND r.ends_at > $2
+		)
+		ORDER BY t.capacity, t.name`,
+		partySize, start, end, excludeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	tables := []*models.DiningTable{}
+	for rows.Next() {
+		table, err := scanTable(rows)
+		if err != nil {
+			return nil, err
+		}
+		// A table that is busy now only counts if its party should be gone by then
+		if tableFreeAt(table, now).After(start) {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// recognizeGuest returns the account for a guest's phone number, creating one for
+// first-time guests, and how many days they have visited before.
+func recognizeGuest(db *database.DB, phoneNumber string) (string, int, error) {
+	account, err := NewAccountService(db).CreateAccount(&models.CreateAccountRequest{PhoneNumber: phoneNumber})
+	if err != nil {
+		return "", 0, err
+	}
+
+	var visits int
+	err = db.Conn().QueryRow(
+		`SELECT COUNT(DISTINCT day) FROM (
+			SELECT business_day AS day FROM orders WHERE customer_id = $1 AND status = 'completed' AND business_day IS NOT NULL
+			UNION SELECT reserved_at::date FROM reservations WHERE account_id = $1 AND status IN ('seated', 'completed')
+			UNION SELECT seated_at::date FROM waitlist_entries WHERE account_id = $1 AND status = 'seated'
+		) visits`,
+		account.ID,
+	).Scan(&visits)
+	if err != nil {
+		return "", 0, err
+	}
+	return account.ID, visits, nil
+}
+
+// sendGuestSMS renders a message in the guest's language and sends it.
+func sendGuestSMS(sender sms.Sender, phoneNumber, locale, key string, data map[string]interface{}) error {
+	message, err := sms.Render(locale, config.SMS().DefaultLocale, key, data)
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(phoneNumber, message); err != nil {
+		return fmt.Errorf("%w: %v", ErrSMSFailed, err)
+	}
+	return nil
+}
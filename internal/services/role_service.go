@@ -0,0 +1,157 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"time"
+)
+
+type RoleService struct {
+	db *database.DB
+}
+
+func NewRoleService(db *database.DB) *RoleService {
+	return &RoleService{db: db}
+}
+
+// ListRoles returns every role with the permissions it grants.
+func (s *RoleService) ListRoles() ([]*models.RoleInfo, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT r.name, COALESCE(r.description, ''), rp.permission FROM roles r LEFT JOIN role_permissions rp ON rp.role = r.name ORDER BY r.name, rp.permission",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.RoleInfo
+	byName := map[models.Role]*models.RoleInfo{}
+	for rows.Next() {
+		var name models.Role
+		var description string
+		var perm sql.NullString
+		if err := rows.Scan(&name, &description, &perm); err != nil {
+			return nil, err
+		}
+		info, ok := byName[name]
+		if !ok {
+			info = &models.RoleInfo{Name: name, Description: description}
+			byName[name] = info
+			roles = append(roles, info)
+		}
+		if perm.Valid {
+			info.Permissions = append(info.Permissions, models.Permission(perm.String))
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+func (s *RoleService) GetAccountRoles(accountID string) ([]*models.AccountRole, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT account_id, role, COALESCE(granted_by, ''), granted_at FROM account_roles WHERE account_id = $1 ORDER BY granted_at",
+		accountID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.AccountRole
+	for rows.Next() {
+		var role models.AccountRole
+		if err := rows.Scan(&role.AccountID, &role.Role, &role.GrantedBy, &role.GrantedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (s *RoleService) GrantRole(accountID string, role models.Role, grantedBy string) error {
+	if !role.Valid() {
+		return fmt.Errorf("unknown role: %s", role)
+	}
+
+	var exists int
+	if err := s.db.Conn().QueryRow("SELECT 1 FROM accounts WHERE id = $1", accountID).Scan(&exists); err != nil {
+		return fmt.Errorf("account not found")
+	}
+
+	return grantRole(s.db, accountID, role, grantedBy)
+}
+
+func (s *RoleService) RevokeRole(accountID string, role models.Role, revokedBy string) error {
+	// Keep admins from locking themselves out of role management
+	if role == models.RoleAdmin && accountID == revokedBy {
+		return fmt.Errorf("cannot revoke your own admin role")
+	}
+
+	result, err := s.db.Conn().Exec(
+		"DELETE FROM account_roles WHERE account_id = $1 AND role = $2",
+		accountID, role,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("account does not have role %s", role)
+	}
+	return nil
+}
+
+// loadAccessForAccount returns the roles held by an account and the union of their permissions.
+func loadAccessForAccount(db *database.DB, accountID string) ([]models.Role, []models.Permission, error) {
+	rows, err := db.Conn().Query(
+		"SELECT ar.role, rp.permission FROM account_roles ar LEFT JOIN role_permissions rp ON rp.role = ar.role WHERE ar.account_id = $1",
+		accountID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	var perms []models.Permission
+	seenRoles := map[models.Role]bool{}
+	seenPerms := map[models.Permission]bool{}
+	for rows.Next() {
+		var role models.Role
+		var perm sql.NullString
+		if err := rows.Scan(&role, &perm); err != nil {
+			return nil, nil, err
+		}
+		if !seenRoles[role] {
+			seenRoles[role] = true
+			roles = append(roles, role)
+		}
+		if p := models.Permission(perm.String); perm.Valid && !seenPerms[p] {
+			seenPerms[p] = true
+			perms = append(perms, p)
+		}
+	}
+
+	return roles, perms, rows.Err()
+}
+
+// ensureBootstrapRoles grants the admin role to accounts listed in ADMIN_PHONE_NUMBERS.
+func ensureBootstrapRoles(db *database.DB, account *models.Account) error {
+	for _, phone := range config.Auth().AdminPhoneNumbers {
+		if phone == account.PhoneNumber {
+			return grantRole(db, account.ID, models.RoleAdmin, "")
+		}
+	}
+	return nil
+}
+
+func grantRole(db *database.DB, accountID string, role models.Role, grantedBy string) error {
+	_, err := db.Conn().Exec(
+		"INSERT INTO account_roles (account_id, role, granted_by, granted_at) VALUES ($1, $2, NULLIF($3, ''), $4) ON CONFLICT (account_id, role) DO NOTHING",
+		accountID, role, grantedBy, time.Now(),
+	)
+	return err
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAreaNotFound  = errors.New("area not found")
+	ErrTableNotFound = errors.New("table not found")
+	// ErrAreaNotEmpty is returned when deleting an area that still has tables.
+	ErrAreaNotEmpty = errors.New("area still has tables")
+	// ErrTableBusy is returned when a table change would lose track of orders in progress.
+	ErrTableBusy = errors.New("table has orders in progress")
+	// ErrTableOccupied is returned when seating a party at a table that already has one.
+	ErrTableOccupied = errors.New("table is occupied")
+)
+
+// TableService manages the floor plan and keeps table states in step with orders and
+// payments, announcing every change as a table_updated event.
+type TableService struct {
+	db     *database.DB
+	events Broadcaster
+}
+
+func NewTableService(db *database.DB, events Broadcaster) *TableService {
+	return &TableService{db: db, events: events}
+}
+
+const tableColumns = "id, area_id, name, capacity, pos_x, pos_y, width, height, state, occupied_since, state_changed_at, created_at, updated_at, COALESCE(qr_key, '')"
+
+func scanTable(row database.Scanner) (*models.DiningTable, error) {
+	var table models.DiningTable
+	var occupiedSince sql.NullTime
+	err := row.Scan(&table.ID, &table.AreaID, &table.Name, &table.Capacity, &table.PosX, &table.PosY, &table.Width, &table.Height,
+		&table.State, &occupiedSince, &table.StateChangedAt, &table.CreatedAt, &table.UpdatedAt, &table.QRKey)
+	if err != nil {
+		return nil, err
+	}
+	if occupiedSince.Valid {
+		table.OccupiedSince = &occupiedSince.Time
+	}
+	return &table, nil
+}
+
+// GetFloorPlan returns every area with its tables, in display order.
+func (s *TableService) GetFloorPlan() ([]*models.Area, error) {
+	rows, err := s.db.Conn().Query("SELECT id, name, sort_order, created_at, updated_at FROM areas ORDER BY sort_order, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	areas := []*models.Area{}
+	byID := map[string]*models.Area{}
+	for rows.Next() {
+		var area models.Area
+		if err := rows.Scan(&area.ID, &area.Name, &area.SortOrder, &area.CreatedAt, &area.UpdatedAt); err != nil {
+			return nil, err
+		}
+		area.Tables = []*models.DiningTable{}
+		areas = append(areas, &area)
+		byID[area.ID] = &area
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tableRows, err := s.db.Conn().Query("SELECT " + tableColumns + " FROM dining_tables WHERE deleted_at IS NULL ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer tableRows.Close()
+
+	for tableRows.Next() {
+		table, err := scanTable(tableRows)
+		if err != nil {
+			return nil, err
+		}
+		if area, ok := byID[table.AreaID]; ok {
+			area.Tables = append(area.Tables, table)
+		}
+	}
+	return areas, tableRows.Err()
+}
+
+func (s *TableService) GetTable(tableID string) (*models.DiningTable, error) {
+	table, err := scanTable(s.db.Conn().QueryRow(
+		"SELECT "+tableColumns+" FROM dining_tables WHERE id = $1 AND deleted_at IS NULL",
+		tableID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrTableNotFound
+	}
+	return table, err
+}
+
+func (s *TableService) CreateArea(req *models.CreateAreaRequest) (*models.Area, error) {
+	now := time.Now()
+	area := &models.Area{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		SortOrder: req.SortOrder,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err := s.db.Conn().Exec(
+		"INSERT INTO areas (id, name, sort_order, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)",
+		area.ID, area.Name, area.SortOrder, area.CreatedAt, area.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return area, nil
+}
+
+func (s *TableService) UpdateArea(areaID string, req *models.UpdateAreaRequest) (*models.Area, error) {
+	var area models.Area
+	err := s.db.Conn().QueryRow(
+		`UPDATE areas SET name = COALESCE($1, name), sort_order = COALESCE($2, sort_order), updated_at = $3
+		WHERE id = $4
+		RETURNING id, name, sort_order, created_at, updated_at`,
+		req.Name, req.SortOrder, time.Now(), areaID,
+	).Scan(&area.ID, &area.Name, &area.SortOrder, &area.CreatedAt, &area.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrAreaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &area, nil
+}
+
+func (s *TableService) DeleteArea(areaID string) error {
+	var tables int
+	if err := s.db.Conn().QueryRow(
+		"SELECT COUNT(*) FROM dining_tables WHERE area_id = $1 AND deleted_at IS NULL",
+		areaID,
+	).Scan(&tables); err != nil {
+		return err
+	}
+	if tables > 0 {
+		return ErrAreaNotEmpty
+	}
+
+	// Deleted tables keep pointing at the area, so only empty areas without history go
+	result, err := s.db.Conn().Exec(
+		"DELETE FROM areas WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM dining_tables WHERE area_id = $1)",
+		areaID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w, or it had tables that past orders refer to", ErrAreaNotFound)
+	}
+	return nil
+}
+
+func (s *TableService) CreateTable(req *models.CreateTableRequest) (*models.DiningTable, error) {
+	if err := s.checkArea(req.AreaID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	table := &models.DiningTable{
+		ID:             uuid.New().String(),
+		AreaID:         req.AreaID,
+		Name:           req.Name,
+		Capacity:       req.Capacity,
+		PosX:           req.PosX,
+		PosY:           req.PosY,
+		Width:          req.Width,
+		Height:         req.Height,
+		State:          models.TableStateFree,
+		StateChangedAt: now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		QRKey:          newQRKey(),
+	}
+	if table.Width == 0 {
+		table.Width = 1
+	}
+	if table.Height == 0 {
+		table.Height = 1
+	}
+
+	_, err := s.db.Conn().Exec(
+		`INSERT INTO dining_tables (id, area_id, name, capacity, pos_x, pos_y, width, height, state, state_changed_at, created_at, updated_at, qr_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		table.ID, table.AreaID, table.Name, table.Capacity, table.PosX, table.PosY, table.Width, table.Height,
+		table.State, table.StateChangedAt, table.CreatedAt, table.UpdatedAt, table.QRKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyTableUpdated(table)
+	return table, nil
+}
+
+func (s *TableService) UpdateTable(tableID string, req *models.UpdateTableRequest) (*models.DiningTable, error) {
+	if req.AreaID != nil {
+		if err := s.checkArea(*req.AreaID); err != nil {
+			return nil, err
+		}
+	}
+
+	table, err := scanTable(s.db.Conn().QueryRow(
+		`UPDATE dining_tables SET
+			area_id = COALESCE($1, area_id),
+			name = COALESCE($2, name),
+			capacity = COALESCE($3, capacity),
+			pos_x = COALESCE($4, pos_x),
+			pos_y = COALESCE($5, pos_y),
+			width = COALESCE($6, width),
+			height = COALESCE($7, height),
+			updated_at = $8
+		WHERE id = $9 AND deleted_at IS NULL
+		RETURNING `+tableColumns,
+		req.AreaID, req.Name, req.Capacity, req.PosX, req.PosY, req.Width, req.Height, time.Now(), tableID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrTableNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyTableUpdated(table)
+	return table, nil
+}
+
+// DeleteTable removes a table from the floor plan. Past orders still refer to it, so
+// the row is kept and marked deleted. Only free tables can be deleted.
+func (s *TableService) DeleteTable(tableID string) error {
+	result, err := s.db.Conn().Exec(
+		"UPDATE dining_tables SET deleted_at = $1, updated_at = $1 WHERE id = $2 AND deleted_at IS NULL AND state = $3",
+		time.Now(), tableID, models.TableStateFree,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		if _, err := s.GetTable(tableID); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: only free tables can be deleted", ErrTableBusy)
+	}
+
+	s.events.Broadcast(map[string]interface{}{
+		"type": "table_updated",
+		"data": map[string]interface{}{"id": tableID, "deleted": true},
+	})
+	return nil
+}
+
+// SetState puts a table in a state by hand: seating a party, marking it for clearing
+// or freeing it. Tables with orders in progress keep following their orders.
+func (s *TableService) SetState(tableID string, state models.TableState) (*models.DiningTable, error) {
+	if !state.Valid() || !state.Manual() {
+		return nil, fmt.Errorf("table state can only be set to free, seated or dirty")
+	}
+
+	var table *models.DiningTable
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		table, err = lockTable(tx, tableID)
+		if err != nil {
+			return err
+		}
+
+		counts, err := countTableOrders(tx, table)
+		if err != nil {
+			return err
+		}
+		if counts.active > 0 {
+			return ErrTableBusy
+		}
+
+		now := time.Now()
+		switch state {
+		case models.TableStateFree:
+			table.OccupiedSince = nil
+		case models.TableStateSeated:
+			// A new party starts a new bill
+			if table.State != models.TableStateSeated {
+				table.OccupiedSince = &now
+			}
+		}
+		return updateTableState(tx, table, state, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyTableUpdated(table)
+	return table, nil
+}
+
+// SyncOrderTable brings the table of a dine-in order up to date with its orders and
+// payments. It is called after order and payment changes have been committed, so
+// failures are logged rather than returned.
+func (s *TableService) SyncOrderTable(orderID string) {
+	var tableID string
+	err := s.db.Conn().QueryRow("SELECT COALESCE(table_id, '') FROM orders WHERE id = $1", orderID).Scan(&tableID)
+	if err != nil {
+		log.Printf("table sync for order %s: %v", orderID, err)
+		return
+	}
+	if tableID == "" {
+		return
+	}
+
+	var table *models.DiningTable
+	changed := false
+	err = s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		table, err = lockTable(tx, tableID)
+		if err != nil {
+			return err
+		}
+
+		counts, err := countTableOrders(tx, table)
+		if err != nil {
+			return err
+		}
+
+		next := table.State
+		switch {
+		case counts.active > 0:
+			next = models.TableStateOrdered
+		case counts.unpaid > 0:
+			next = models.TableStateAwaitingPayment
+		case table.State == models.TableStateAwaitingPayment:
+			// The bill is settled; the party is leaving
+			next = models.TableStateDirty
+		case table.State == models.TableStateOrdered:
+			// Everything was served and paid up front, or cancelled
+			next = models.TableStateSeated
+		}
+		if next == table.State {
+			return nil
+		}
+
+		// A party that ordered without being seated by the host counts from when the
+		// table was last freed, so the orders just counted stay part of its bill
+		if table.OccupiedSince == nil {
+			since := table.StateChangedAt
+			table.OccupiedSince = &since
+		}
+		changed = true
+		return updateTableState(tx, table, next, time.Now())
+	})
+	if err != nil {
+		log.Printf("table sync for order %s: %v", orderID, err)
+		return
+	}
+
+	if changed {
+		s.notifyTableUpdated(table)
+	}
+}
+
+// seatParty marks a table as taken by a newly seated party. The caller announces the
+// change with notifyTableUpdated once its transaction commits.
+func seatParty(q database.Queryer, tableID string) (*models.DiningTable, error) {
+	table, err := lockTable(q, tableID)
+	if err != nil {
+		return nil, err
+	}
+	switch table.State {
+	case models.TableStateSeated, models.TableStateOrdered, models.TableStateAwaitingPayment:
+		return nil, ErrTableOccupied
+	}
+
+	now := time.Now()
+	table.OccupiedSince = &now
+	if err := updateTableState(q, table, models.TableStateSeated, now); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// tableFreeAt estimates when a table will be ready for the next party: occupied tables
+// after their turn time, and every table that is not free after it has been cleared.
+func tableFreeAt(table *models.DiningTable, now time.Time) time.Time {
+	cfg := config.Reservations()
+	switch table.State {
+	case models.TableStateFree:
+		return now
+	case models.TableStateDirty:
+		return now.Add(cfg.CleanTime)
+	}
+
+	since := table.StateChangedAt
+	if table.OccupiedSince != nil {
+		since = *table.OccupiedSince
+	}
+	// Who is sitting there is not recorded, so go by what the table seats
+	leaves := since.Add(cfg.TurnTimeFor(table.Capacity))
+	if leaves.Before(now) {
+		leaves = now
+	}
+	return leaves.Add(cfg.CleanTime)
+}
+
+// RotateQRKey gives a table a new QR key, for when a printed code is lost or copied.
+// Codes printed earlier stop working and guests ordering with them must scan again.
+func (s *TableService) RotateQRKey(tableID string) (*models.DiningTable, error) {
+	var table *models.DiningTable
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if table, err = lockTable(tx, tableID); err != nil {
+			return err
+		}
+		table.QRKey = newQRKey()
+		table.UpdatedAt = time.Now()
+		if _, err := tx.Exec("UPDATE dining_tables SET qr_key = $1, updated_at = $2 WHERE id = $3", table.QRKey, table.UpdatedAt, table.ID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM table_tokens WHERE table_id = $1", table.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// QRLink returns the address a table's QR code opens: the guest web app, bound to
+// the table by its ID and QR key.
+func QRLink(table *models.DiningTable) string {
+	query := url.Values{"table": {table.ID}, "key": {table.QRKey}}
+	return config.Guest().WebURL + "?" + query.Encode()
+}
+
+func newQRKey() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+func (s *TableService) checkArea(areaID string) error {
+	var exists bool
+	if err := s.db.Conn().QueryRow("SELECT EXISTS (SELECT 1 FROM areas WHERE id = $1)", areaID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAreaNotFound
+	}
+	return nil
+}
+
+func (s *TableService) notifyTableUpdated(table *models.DiningTable) {
+	s.events.Broadcast(map[string]interface{}{
+		"type": "table_updated",
+		"data": table,
+	})
+}
+
+func lockTable(q database.Queryer, tableID string) (*models.DiningTable, error) {
+	table, err := scanTable(q.QueryRow(
+		"SELECT "+tableColumns+" FROM dining_tables WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		tableID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrTableNotFound
+	}
+	return table, err
+}
+
+func updateTableState(q database.Queryer, table *models.DiningTable, state models.TableState, now time.Time) error {
+	table.State = state
+	table.StateChangedAt = now
+	table.UpdatedAt = now
+	_, err := q.Exec(
+		"UPDATE dining_tables SET state = $1, occupied_since = $2, state_changed_at = $3, updated_at = $3 WHERE id = $4",
+		table.State, table.OccupiedSince, now, table.ID,
+	)
+	return err
+}
+
+type tableOrderCounts struct {
+	// active orders are still on their way from the kitchen.
+	active int
+	// unpaid orders have been served but not paid for.
+	unpaid int
+}
+
+// countTableOrders counts the current party's orders at a table: those placed since
+// it sat down, or since the table was last changed if nobody is seated.
+func countTableOrders(q database.Queryer, table *models.DiningTable) (tableOrderCounts, error) {
+	since := table.StateChangedAt
+	if table.OccupiedSince != nil {
+		since = *table.OccupiedSince
+	}
+
+	var counts tableOrderCounts
+	err := q.QueryRow(
+		`SELECT
+			COUNT(*) FILTER (WHERE o.status = ANY($3)),
+			COUNT(*) FILTER (WHERE o.status = $4 AND NOT EXISTS (
+				SELECT 1 FROM payments p WHERE p.order_id = o.id AND p.status = $5))
+		FROM orders o
+		WHERE o.table_id = $1 AND o.created_at >= $2`,
+		table.ID, since,
+		pq.Array([]string{string(models.OrderStatusPending), string(models.OrderStatusConfirmed), string(models.OrderStatusPreparing), string(models.OrderStatusReady)}),
+		models.OrderStatusCompleted, models.PaymentStatusCompleted,
+	).Scan(&counts.active, &counts.unpaid)
+	return counts, err
+}
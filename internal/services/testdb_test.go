@@ -0,0 +1,88 @@
+package services
+
+import (
+	"os"
+	"testing"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// openTestDB connects to the Postgres database named by TEST_PG_URL, creating the
+// schema if needed. Tests that need a database are skipped when it is not set.
+// The database should be a scratch one: tests leave their rows behind.
+func openTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	url := os.Getenv("TEST_PG_URL")
+	if url == "" {
+		t.Skip("TEST_PG_URL not set; skipping database test")
+	}
+	t.Setenv("PG_URL", url)
+	config.Load()
+
+	db, err := database.Initialize()
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// availableMenuItems returns the IDs of n available menu items from the seed menu.
+func availableMenuItems(t *testing.T, db *database.DB, n int) []string {
+	t.Helper()
+	rows, err := db.Conn().Query("SELECT id FROM menu_items WHERE available ORDER BY name LIMIT $1", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) < n {
+		t.Fatalf("need %d available menu items, the test database has %d", n, len(ids))
+	}
+	return ids
+}
+
+func countRows(t *testing.T, db *database.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.Conn().QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// staffActor returns a signed-in member of staff with role's default permissions.
+func staffActor(role models.Role) *models.Actor {
+	return &models.Actor{
+		AccountID:   uuid.New().String(),
+		Roles:       []models.Role{role},
+		Permissions: models.DefaultRolePermissions[role],
+	}
+}
+
+// testTable creates a dining table in an area of its own and returns its ID.
+func testTable(t *testing.T, db *database.DB) string {
+	t.Helper()
+	tables := NewTableService(db, nopBroadcaster{})
+	area, err := tables.CreateArea(&models.CreateAreaRequest{Name: "Test " + uuid.New().String()[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := tables.CreateTable(&models.CreateTableRequest{AreaID: area.ID, Name: "T1", Capacity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table.ID
+}
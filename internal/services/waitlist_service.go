@@ -0,0 +1,379 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"restaurant-system/internal/config"
+	"restaurant-system/internal/database"
+	"restaurant-system/internal/models"
+	"restaurant-system/internal/sms"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWaitlistEntryNotFound = errors.New("waitlist entry not found")
+	// ErrWaitlistEntryClosed is returned when acting on a party that was already seated or left.
+	ErrWaitlistEntryClosed = errors.New("party is no longer waiting")
+	// ErrPartyTooLarge is returned when no table seats the party.
+	ErrPartyTooLarge = errors.New("no table seats a party this large")
+)
+
+// waitlistHorizon is how far ahead reservations are taken into account when
+// estimating waits.
+const waitlistHorizon = 6 * time.Hour
+
+// WaitlistService queues walk-in parties and estimates how long each will wait by
+// playing the queue forward against the tables, their current parties and upcoming
+// reservations.
+type WaitlistService struct {
+	db     *database.DB
+	tables *TableService
+	sms    sms.Sender
+}
+
+func NewWaitlistService(db *database.DB, tableService *TableService, sender sms.Sender) *WaitlistService {
+	return &WaitlistService{db: db, tables: tableService, sms: sender}
+}
+
+const waitlistColumns = "id, account_id, previous_visits, phone_number, name, party_size, notes, status, locale, quoted_wait_minutes, COALESCE(table_id, ''), notified_at, seated_at, created_at, updated_at"
+
+func scanWaitlistEntry(row database.Scanner) (*models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	var notifiedAt, seatedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.AccountID, &e.PreviousVisits, &e.PhoneNumber, &e.Name, &e.PartySize, &e.Notes, &e.Status,
+		&e.Locale, &e.QuotedWaitMinutes, &e.TableID, &notifiedAt, &seatedAt, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if notifiedAt.Valid {
+		e.NotifiedAt = &notifiedAt.Time
+	}
+	if seatedAt.Valid {
+		e.SeatedAt = &seatedAt.Time
+	}
+	e.ReturningGuest = e.PreviousVisits > 0
+	return &e, nil
+}
+
+// Join adds a walk-in party to the end of the queue and texts them the quoted wait.
+func (s *WaitlistService) Join(req *models.JoinWaitlistRequest) (*models.WaitlistEntry, error) {
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	quote, err := s.Quote(req.PartySize)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, visits, err := recognizeGuest(s.db, req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry := &models.WaitlistEntry{
+		ID:                   uuid.New().String(),
+		AccountID:            accountID,
+		PreviousVisits:       visits,
+		ReturningGuest:       visits > 0,
+		PhoneNumber:          req.PhoneNumber,
+		Name:                 strings.TrimSpace(req.Name),
+		PartySize:            req.PartySize,
+		Notes:                strings.TrimSpace(req.Notes),
+		Status:               models.WaitlistStatusWaiting,
+		Locale:               req.Locale,
+		QuotedWaitMinutes:    quote.EstimatedWaitMinutes,
+		Position:             quote.Position,
+		EstimatedWaitMinutes: quote.EstimatedWaitMinutes,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	_, err = s.db.Conn().Exec(
+		`INSERT INTO waitlist_entries (id, account_id, previous_visits, phone_number, name, party_size, notes, status, locale,
+			quoted_wait_minutes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		entry.ID, entry.AccountID, entry.PreviousVisits, entry.PhoneNumber, entry.Name, entry.PartySize, entry.Notes,
+		entry.Status, entry.Locale, entry.QuotedWaitMinutes, entry.CreatedAt, entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sendGuestSMS(s.sms, entry.PhoneNumber, entry.Locale, sms.MessageWaitlistJoined, map[string]interface{}{
+		"PartySize":  entry.PartySize,
+		"Restaurant": config.Reservations().RestaurantName,
+		"Minutes":    entry.QuotedWaitMinutes,
+	}); err != nil {
+		log.Println("waitlist:", err)
+	}
+	return entry, nil
+}
+
+// Quote estimates the wait for a party of partySize joining now, without joining.
+func (s *WaitlistService) Quote(partySize int) (*models.WaitlistEntry, error) {
+	entries, err := s.openEntries()
+	if err != nil {
+		return nil, err
+	}
+	quote := &models.WaitlistEntry{PartySize: partySize, Status: models.WaitlistStatusWaiting}
+	if err := s.estimate(append(entries, quote)); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// List returns the parties still waiting or notified, in queue order, with fresh estimates.
+func (s *WaitlistService) List() ([]*models.WaitlistEntry, error) {
+	entries, err := s.openEntries()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.estimate(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *WaitlistService) GetEntry(entryID string) (*models.WaitlistEntry, error) {
+	entry, err := scanWaitlistEntry(s.db.Conn().QueryRow(
+		"SELECT "+waitlistColumns+" FROM waitlist_entries WHERE id = $1",
+		entryID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrWaitlistEntryNotFound
+	}
+	return entry, err
+}
+
+// NotifyTableReady texts a waiting party that tableID is ready for them. It can be
+// repeated if they do not show up.
+func (s *WaitlistService) NotifyTableReady(entryID, tableID string) (*models.WaitlistEntry, error) {
+	entry, err := s.GetEntry(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.Status.Open() {
+		return nil, ErrWaitlistEntryClosed
+	}
+	if _, err := s.tables.GetTable(tableID); err != nil {
+		return nil, err
+	}
+
+	if err := sendGuestSMS(s.sms, entry.PhoneNumber, entry.Locale, sms.MessageTableReady, map[string]interface{}{
+		"PartySize":  entry.PartySize,
+		"Restaurant": config.Reservations().RestaurantName,
+	}); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry.Status = models.WaitlistStatusNotified
+	entry.TableID = tableID
+	entry.NotifiedAt = &now
+	entry.UpdatedAt = now
+	_, err = s.db.Conn().Exec(
+		"UPDATE waitlist_entries SET status = $1, table_id = $2, notified_at = $3, updated_at = $3 WHERE id = $4",
+		entry.Status, entry.TableID, now, entry.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Seat seats a waiting party at tableID and takes them off the queue.
+func (s *WaitlistService) Seat(entryID, tableID string) (*models.WaitlistEntry, error) {
+	var entry *models.WaitlistEntry
+	var table *models.DiningTable
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if entry, err = lockWaitlistEntry(tx, entryID); err != nil {
+			return err
+		}
+		if !entry.Status.Open() {
+			return ErrWaitlistEntryClosed
+		}
+		if table, err = seatParty(tx, tableID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		entry.Status = models.WaitlistStatusSeated
+		entry.TableID = tableID
+		entry.SeatedAt = &now
+		entry.UpdatedAt = now
+		_, err = tx.Exec(
+			"UPDATE waitlist_entries SET status = $1, table_id = $2, seated_at = $3, updated_at = $3 WHERE id = $4",
+			entry.Status, entry.TableID, now, entry.ID,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.tables.notifyTableUpdated(table)
+	return entry, nil
+}
+
+// Remove takes a party that gave up off the queue.
+func (s *WaitlistService) Remove(entryID string) (*models.WaitlistEntry, error) {
+	var entry *models.WaitlistEntry
+	err := s.db.WithTx(func(tx *sql.Tx) error {
+		var err error
+		if entry, err = lockWaitlistEntry(tx, entryID); err != nil {
+			return err
+		}
+		if !entry.Status.Open() {
+			return ErrWaitlistEntryClosed
+		}
+
+		entry.Status = models.WaitlistStatusLeft
+		entry.UpdatedAt = time.Now()
+		_, err = tx.Exec("UPDATE waitlist_entries SET status = $1, updated_at = $2 WHERE id = $3", entry.Status, entry.UpdatedAt, entry.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *WaitlistService) openEntries() ([]*models.WaitlistEntry, error) {
+	rows, err := s.db.Conn().Query(
+		"SELECT " + waitlistColumns + " FROM waitlist_entries WHERE status IN ('waiting', 'notified') ORDER BY created_at, id",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.WaitlistEntry{}
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// estimate fills in Position and EstimatedWaitMinutes for the waiting entries, in
+// queue order. Each party takes the table that seats it soonest, which then stays
+// busy for the party's turn time; reservations keep their tables for their slots.
+// Notified parties already have a table and just hold it.
+func (s *WaitlistService) estimate(entries []*models.WaitlistEntry) error {
+	now := time.Now()
+	cfg := config.Reservations()
+
+	tableRows, err := s.db.Conn().Query("SELECT " + tableColumns + " FROM dining_tables WHERE deleted_at IS NULL ORDER BY capacity, name")
+	if err != nil {
+		return err
+	}
+	defer tableRows.Close()
+	var tables []*models.DiningTable
+	freeAt := map[string]time.Time{}
+	for tableRows.Next() {
+		table, err := scanTable(tableRows)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+		freeAt[table.ID] = tableFreeAt(table, now)
+	}
+	if err := tableRows.Err(); err != nil {
+		return err
+	}
+
+	type slot struct{ start, end time.Time }
+	booked := map[string][]slot{}
+	resRows, err := s.db.Conn().Query(
+		"SELECT table_id, reserved_at, ends_at FROM reservations WHERE status = 'booked' AND ends_at > $1 AND reserved_at < $2",
+		now, now.Add(waitlistHorizon),
+	)
+	if err != nil {
+		return err
+	}
+	defer resRows.Close()
+	for resRows.Next() {
+		var tableID string
+		var r slot
+		if err := resRows.Scan(&tableID, &r.start, &r.end); err != nil {
+			return err
+		}
+		booked[tableID] = append(booked[tableID], r)
+	}
+	if err := resRows.Err(); err != nil {
+		return err
+	}
+	for _, slots := range booked {
+		sort.Slice(slots, func(i, j int) bool { return slots[i].start.Before(slots[j].start) })
+	}
+
+	for _, entry := range entries {
+		if entry.Status == models.WaitlistStatusNotified && entry.TableID != "" {
+			freeAt[entry.TableID] = now.Add(cfg.TurnTimeFor(entry.PartySize))
+		}
+	}
+
+	position := 0
+	for _, entry := range entries {
+		if entry.Status != models.WaitlistStatusWaiting {
+			continue
+		}
+		position++
+		entry.Position = position
+
+		turnTime := cfg.TurnTimeFor(entry.PartySize)
+		var best *models.DiningTable
+		var bestStart time.Time
+		for _, table := range tables {
+			if table.Capacity < entry.PartySize {
+				continue
+			}
+			// Start when the table frees up, after any reservation the party would run into
+			start := freeAt[table.ID]
+			for _, r := range booked[table.ID] {
+				if r.start.Before(start.Add(turnTime)) && r.end.After(start) {
+					start = r.end
+				}
+			}
+			if best == nil || start.Before(bestStart) {
+				best, bestStart = table, start
+			}
+		}
+		if best == nil {
+			if entry.ID == "" {
+				return ErrPartyTooLarge
+			}
+			// Seated by hand, e.g. at pushed together tables
+			entry.EstimatedWaitMinutes = 0
+			continue
+		}
+
+		freeAt[best.ID] = bestStart.Add(turnTime)
+		if wait := bestStart.Sub(now); wait > 0 {
+			entry.EstimatedWaitMinutes = int(math.Ceil(wait.Minutes()))
+		} else {
+			entry.EstimatedWaitMinutes = 0
+		}
+	}
+	return nil
+}
+
+func lockWaitlistEntry(q database.Queryer, entryID string) (*models.WaitlistEntry, error) {
+	entry, err := scanWaitlistEntry(q.QueryRow(
+		"SELECT "+waitlistColumns+" FROM waitlist_entries WHERE id = $1 FOR UPDATE",
+		entryID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrWaitlistEntryNotFound
+	}
+	return entry, err
+}
@@ -0,0 +1,51 @@
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SpooledMessage is the JSON document written for each message by FileSender.
+type SpooledMessage struct {
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// FileSender writes each message as a JSON file into a spool directory,
+// so tests can read what would have been sent.
+type FileSender struct {
+	dir string
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if dir == "" {
+		return nil, errors.New("SMS spool directory is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+func (s *FileSender) Send(to, message string) error {
+	msg := SpooledMessage{To: to, Message: message, SentAt: time.Now()}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temp name first so readers never see a partial file
+	name := fmt.Sprintf("%d-%s.json", msg.SentAt.UnixNano(), uuid.New().String())
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"restaurant-system/internal/config"
+)
+
+func TestFileSenderSpoolsEachMessage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	sender, err := New(config.SMSConfig{Provider: "file", SpoolDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send("+251911000001", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send("+251911000002", "ሁለተኛ"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("spooled %d messages, want 2", len(files))
+	}
+	var got []SpooledMessage
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg SpooledMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("%s is not a spooled message: %v", file, err)
+		}
+		if msg.SentAt.IsZero() {
+			t.Errorf("%s has no sent_at", file)
+		}
+		got = append(got, msg)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].To < got[j].To })
+	if got[0].To != "+251911000001" || got[0].Message != "first" ||
+		got[1].To != "+251911000002" || got[1].Message != "ሁለተኛ" {
+		t.Errorf("spooled %+v", got)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("spool directory has %d entries, want no temp files left", len(entries))
+	}
+}
+
+func TestNewFileSenderRequiresDir(t *testing.T) {
+	if _, err := NewFileSender(""); err == nil {
+		t.Error("NewFileSender accepted an empty directory")
+	}
+}
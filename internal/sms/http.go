@@ -0,0 +1,84 @@
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+// DefaultBodyTemplate is used when no gateway body template is configured.
+const DefaultBodyTemplate = `{"to":{{json .To}},"message":{{json .Message}}}`
+
+// HTTPSender posts messages to an SMS gateway. The request body is rendered from a
+// text/template with .To and .Message; the json and urlquery functions are available
+// for escaping.
+type HTTPSender struct {
+	url         string
+	body        *template.Template
+	contentType string
+	authHeader  string
+	client      *http.Client
+}
+
+func NewHTTPSender(url, bodyTemplate, contentType, authHeader string) (*HTTPSender, error) {
+	if url == "" {
+		return nil, errors.New("SMS gateway URL is required")
+	}
+	if bodyTemplate == "" {
+		bodyTemplate = DefaultBodyTemplate
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	tmpl, err := template.New("sms").Funcs(template.FuncMap{"json": jsonString}).Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMS gateway body template: %w", err)
+	}
+
+	return &HTTPSender{
+		url:         url,
+		body:        tmpl,
+		contentType: contentType,
+		authHeader:  authHeader,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *HTTPSender) Send(to, message string) error {
+	var body bytes.Buffer
+	if err := s.body.Execute(&body, struct{ To, Message string }{to, message}); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.authHeader != "" {
+		req.Header.Set("Authorization", s.authHeader)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+func jsonString(v string) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,126 @@
+package sms
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type gatewayRequest struct {
+	contentType string
+	auth        string
+	body        string
+}
+
+// newGateway starts a stub SMS gateway that records each request and answers with status.
+func newGateway(t *testing.T, status int) (*httptest.Server, *[]gatewayRequest) {
+	t.Helper()
+	var got []gatewayRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, gatewayRequest{
+			contentType: r.Header.Get("Content-Type"),
+			auth:        r.Header.Get("Authorization"),
+			body:        string(body),
+		})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("  rejected: bad number \n"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func TestHTTPSenderDefaultTemplate(t *testing.T) {
+	srv, got := newGateway(t, http.StatusOK)
+
+	sender, err := NewHTTPSender(srv.URL, "", "", "Bearer secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := `Your code is 123456. "Quotes" & <tags>`
+	if err := sender.Send("+251911000000", message); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(*got) != 1 {
+		t.Fatalf("gateway got %d requests, want 1", len(*got))
+	}
+	req := (*got)[0]
+	if req.contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", req.contentType)
+	}
+	if req.auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", req.auth)
+	}
+	var body struct{ To, Message string }
+	if err := json.Unmarshal([]byte(req.body), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", req.body, err)
+	}
+	if body.To != "+251911000000" || body.Message != message {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestHTTPSenderCustomTemplate(t *testing.T) {
+	srv, got := newGateway(t, http.StatusAccepted)
+
+	sender, err := NewHTTPSender(srv.URL, "to={{urlquery .To}}&text={{urlquery .Message}}", "application/x-www-form-urlencoded", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send("+251911000000", "code 42 & more"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	req := (*got)[0]
+	if req.contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type = %q", req.contentType)
+	}
+	if req.auth != "" {
+		t.Errorf("Authorization = %q, want none", req.auth)
+	}
+	if want := "to=%2B251911000000&text=code+42+%26+more"; req.body != want {
+		t.Errorf("body = %q, want %q", req.body, want)
+	}
+}
+
+func TestHTTPSenderGatewayError(t *testing.T) {
+	srv, _ := newGateway(t, http.StatusBadRequest)
+
+	sender, err := NewHTTPSender(srv.URL, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sender.Send("+251911000000", "hello")
+	if err == nil {
+		t.Fatal("Send succeeded on a 400 from the gateway")
+	}
+	if !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "rejected: bad number") {
+		t.Errorf("error %q should carry the status and the gateway's reply", err)
+	}
+}
+
+func TestHTTPSenderUnreachable(t *testing.T) {
+	srv, _ := newGateway(t, http.StatusOK)
+	srv.Close()
+
+	sender, err := NewHTTPSender(srv.URL, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send("+251911000000", "hello"); err == nil {
+		t.Fatal("Send succeeded with the gateway down")
+	}
+}
+
+func TestNewHTTPSenderInvalid(t *testing.T) {
+	if _, err := NewHTTPSender("", "", "", ""); err == nil {
+		t.Error("NewHTTPSender accepted an empty URL")
+	}
+	if _, err := NewHTTPSender("http://localhost", "{{.To", "", ""); err == nil {
+		t.Error("NewHTTPSender accepted a broken template")
+	}
+}
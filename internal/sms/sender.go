@@ -0,0 +1,32 @@
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"restaurant-system/internal/config"
+)
+
+// Sender delivers a text message to a phone number.
+type Sender interface {
+	Send(to, message string) error
+}
+
+// New builds the Sender selected by cfg.Provider. There is no default: the console
+// sender logs verification codes, so it has to be asked for.
+func New(cfg config.SMSConfig) (Sender, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, errors.New("SMS_PROVIDER is not set; use http or file, or console for local development")
+	case "console":
+		log.Println("warning: SMS_PROVIDER=console logs messages, including verification codes, instead of sending them")
+		return NewConsoleSender(), nil
+	case "http":
+		return NewHTTPSender(cfg.GatewayURL, cfg.BodyTemplate, cfg.ContentType, cfg.AuthHeader)
+	case "file":
+		return NewFileSender(cfg.SpoolDir)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider: %s", cfg.Provider)
+	}
+}
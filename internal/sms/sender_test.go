@@ -0,0 +1,19 @@
+package sms
+
+import (
+	"testing"
+
+	"restaurant-system/internal/config"
+)
+
+func TestNewRequiresProvider(t *testing.T) {
+	if _, err := New(config.SMSConfig{}); err == nil {
+		t.Error("New fell back to a sender without SMS_PROVIDER being set")
+	}
+	if _, err := New(config.SMSConfig{Provider: "carrier-pigeon"}); err == nil {
+		t.Error("New accepted an unknown provider")
+	}
+	if _, err := New(config.SMSConfig{Provider: "console"}); err != nil {
+		t.Errorf("New(console): %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const (
+	LocaleEnglish = "en"
+	LocaleAmharic = "am"
+)
+
+// Message template keys.
+const (
+	MessageOTP = "otp"
+	// MessageReservationBooked confirms a reservation.
+	MessageReservationBooked = "reservation_booked"
+	// MessageWaitlistJoined tells a walk-in party their place in the queue.
+	MessageWaitlistJoined = "waitlist_joined"
+	// MessageTableReady calls a waiting party to their table.
+	MessageTableReady = "table_ready"
+)
+
+var catalog = map[string]map[string]string{
+	LocaleEnglish: {
+		MessageOTP:               "Your verification code is {{.Code}}. It expires in {{.Minutes}} minutes.",
+		MessageReservationBooked: "Your table for {{.PartySize}} at {{.Restaurant}} is booked for {{.Time}}. See you then!",
+		MessageWaitlistJoined:    "You are on the waitlist at {{.Restaurant}} for {{.PartySize}}. Estimated wait: about {{.Minutes}} minutes. We will text you when your table is ready.",
+		MessageTableReady:        "Your table at {{.Restaurant}} is ready! Please come to the host stand.",
+	},
+	LocaleAmharic: {
+		MessageOTP:               "የማረጋገጫ ኮድዎ {{.Code}} ነው። በ{{.Minutes}} ደቂቃ ውስጥ ያበቃል።",
+		MessageReservationBooked: "በ{{.Restaurant}} ለ{{.PartySize}} ሰዎች ጠረጴዛ ለ{{.Time}} ተይዟል።",
+		MessageWaitlistJoined:    "በ{{.Restaurant}} ለ{{.PartySize}} ሰዎች ተራ ይዘዋል። የሚጠበቀው ጊዜ፦ {{.Minutes}} ደቂቃ ገደማ። ጠረጴዛዎ ሲዘጋጅ እናሳውቅዎታለን።",
+		MessageTableReady:        "በ{{.Restaurant}} ጠረጴዛዎ ዝግጁ ነው! እባክዎ ወደ እንግዳ መቀበያው ይምጡ።",
+	},
+}
+
+var templates = map[string]map[string]*template.Template{}
+
+func init() {
+	for locale, messages := range catalog {
+		templates[locale] = map[string]*template.Template{}
+		for key, text := range messages {
+			templates[locale][key] = template.Must(template.New(locale + "/" + key).Parse(text))
+		}
+	}
+}
+
+// Render renders the message key in locale, falling back to fallbackLocale and
+// then English when the locale has no translation.
+func Render(locale, fallbackLocale, key string, data interface{}) (string, error) {
+	for _, l := range []string{locale, fallbackLocale, LocaleEnglish} {
+		if tmpl, ok := templates[l][key]; ok {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		}
+	}
+	return "", fmt.Errorf("unknown SMS message: %s", key)
+}
@@ -0,0 +1,63 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestRenderLocaleFallback(t *testing.T) {
+	data := map[string]interface{}{"Code": "123456", "Minutes": 5}
+
+	tests := []struct {
+		locale, fallback string
+		want             string
+	}{
+		{LocaleAmharic, LocaleEnglish, "የማረጋገጫ ኮድዎ 123456 ነው። በ5 ደቂቃ ውስጥ ያበቃል።"},
+		{LocaleEnglish, LocaleAmharic, "Your verification code is 123456. It expires in 5 minutes."},
+		// An unknown locale falls back to the branch's locale, then to English
+		{"fr", LocaleAmharic, "የማረጋገጫ ኮድዎ 123456 ነው። በ5 ደቂቃ ውስጥ ያበቃል።"},
+		{"fr", "de", "Your verification code is 123456. It expires in 5 minutes."},
+		{"", "", "Your verification code is 123456. It expires in 5 minutes."},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.locale, tt.fallback, MessageOTP, data)
+		if err != nil {
+			t.Errorf("Render(%q, %q): %v", tt.locale, tt.fallback, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Render(%q, %q) = %q, want %q", tt.locale, tt.fallback, got, tt.want)
+		}
+	}
+}
+
+func TestRenderUntranslatedMessage(t *testing.T) {
+	// A message only written in English yet
+	templates[LocaleEnglish]["test_only"] = template.Must(template.New("test_only").Parse("Hello {{.Name}}"))
+	t.Cleanup(func() { delete(templates[LocaleEnglish], "test_only") })
+
+	got, err := Render(LocaleAmharic, LocaleAmharic, "test_only", map[string]string{"Name": "Abebe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Hello Abebe" {
+		t.Errorf("got %q, want the English text", got)
+	}
+}
+
+func TestRenderUnknownMessage(t *testing.T) {
+	if _, err := Render(LocaleEnglish, LocaleEnglish, "no_such_message", nil); err == nil || !strings.Contains(err.Error(), "no_such_message") {
+		t.Errorf("Render of an unknown message = %v, want an error naming it", err)
+	}
+}
+
+func TestCatalogIsComplete(t *testing.T) {
+	for key := range catalog[LocaleEnglish] {
+		for locale := range catalog {
+			if _, ok := catalog[locale][key]; !ok {
+				t.Errorf("%s has no %s translation", key, locale)
+			}
+		}
+	}
+}
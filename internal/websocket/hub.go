@@ -17,9 +17,36 @@ var upgrader = websocket.Upgrader{
 }
 
 type Client struct {
-	conn *websocket.Conn
-	mu   sync.Mutex
-	role string
+	conn  *websocket.Conn
+	mu    sync.Mutex
+	roles []string
+	// accountID is the signed-in account, for messages about its own orders
+	accountID string
+	// stations is set for kitchen screens that only show some stations' tickets
+	stations []string
+}
+
+func (c *Client) hasRole(role string) bool {
+	for _, r := range c.roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// stationScreen reports whether the client only wants tickets for its stations
+func (c *Client) stationScreen() bool {
+	return len(c.stations) > 0
+}
+
+func (c *Client) hasStation(station string) bool {
+	for _, s := range c.stations {
+		if s == station {
+			return true
+		}
+	}
+	return false
 }
 
 type Hub struct {
@@ -39,6 +66,10 @@ func (h *Hub) Run() {
 	for msg := range h.broadcast {
 		h.mu.RLock()
 		for c := range h.clients {
+			// Station screens get their own tickets only, see BroadcastToStation
+			if c.stationScreen() {
+				continue
+			}
 			c.send(msg)
 		}
 		h.mu.RUnlock()
@@ -60,26 +91,89 @@ func (h *Hub) Broadcast(v interface{}) {
 	}
 }
 
-// BroadcastToKitchen sends a message only to clients with role kitchen
+// BroadcastToKitchen sends a message only to clients with role kitchen that watch
+// every station, such as the pass
 func (h *Hub) BroadcastToKitchen(v interface{}) {
 	h.mu.RLock()
 	for c := range h.clients {
-		if c.role == "kitchen" {
+		if c.hasRole("kitchen") && !c.stationScreen() {
+			c.send(v)
+		}
+	}
+	h.mu.RUnlock()
+}
+
+// BroadcastToStation sends a message only to kitchen clients subscribed to station
+func (h *Hub) BroadcastToStation(station string, v interface{}) {
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.hasRole("kitchen") && c.hasStation(station) {
+			c.send(v)
+		}
+	}
+	h.mu.RUnlock()
+}
+
+// BroadcastToStaff sends a message only to clients with role staff, i.e. front of house
+func (h *Hub) BroadcastToStaff(v interface{}) {
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.hasRole("staff") && !c.stationScreen() {
+			c.send(v)
+		}
+	}
+	h.mu.RUnlock()
+}
+
+// SendToAccount sends a message only to the clients signed in as accountID. Like
+// Broadcast it skips station screens.
+func (h *Hub) SendToAccount(accountID string, v interface{}) {
+	if accountID == "" {
+		return
+	}
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.accountID == accountID && !c.stationScreen() {
 			c.send(v)
 		}
 	}
 	h.mu.RUnlock()
 }
 
-// HandleWebSocket upgrades the connection and registers the client
-func HandleWebSocket(h *Hub, w http.ResponseWriter, r *http.Request) {
+// BroadcastToRoles sends a message once to each client with any of the roles.
+// Like BroadcastToKitchen it skips station screens.
+func (h *Hub) BroadcastToRoles(v interface{}, roles ...string) {
+	h.mu.RLock()
+	for c := range h.clients {
+		if c.stationScreen() {
+			continue
+		}
+		for _, role := range roles {
+			if c.hasRole(role) {
+				c.send(v)
+				break
+			}
+		}
+	}
+	h.mu.RUnlock()
+}
+
+// HandleWebSocket upgrades the connection and registers the client for accountID.
+// The account and roles must come from the authenticated session, never from the
+// request.
+func HandleWebSocket(h *Hub, w http.ResponseWriter, r *http.Request, accountID string, roles ...string) {
+	HandleStationWebSocket(h, w, r, accountID, nil, roles...)
+}
+
+// HandleStationWebSocket is HandleWebSocket for a kitchen screen that shows only
+// the tickets of the given stations. With no stations it sees every order.
+func HandleStationWebSocket(h *Hub, w http.ResponseWriter, r *http.Request, accountID string, stations []string, roles ...string) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return
 	}
 
-	role := r.URL.Query().Get("role")
-	client := &Client{conn: conn, role: role}
+	client := &Client{conn: conn, roles: roles, accountID: accountID, stations: stations}
 
 	h.mu.Lock()
 	h.clients[client] = true
@@ -2,11 +2,17 @@ package main
 
 import (
 	"log"
+	"net/http"
+	"os"
 	"restaurant-system/internal/config"
 	"restaurant-system/internal/database"
 	"restaurant-system/internal/handlers"
+	"restaurant-system/internal/middleware"
+	"restaurant-system/internal/models"
 	"restaurant-system/internal/services"
+	"restaurant-system/internal/sms"
 	"restaurant-system/internal/websocket"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -26,30 +32,84 @@ func main() {
 	}
 	defer db.Close()
 
+	// Administrative subcommands run against the database and exit
+	if len(os.Args) > 1 {
+		if err := runCLI(db, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Initialize SMS delivery
+	smsSender, err := sms.New(config.SMS())
+	if err != nil {
+		log.Fatal("Failed to initialize SMS provider:", err)
+	}
+
 	// Initialize services
 	orderService := services.NewOrderService(db)
 	paymentService := services.NewPaymentService(db)
 	accountService := services.NewAccountService(db)
 	kitchenService := services.NewKitchenService(db)
-	authService := services.NewAuthService(db)
+	authService := services.NewAuthService(db, smsSender)
+	roleService := services.NewRoleService(db)
+	deviceService := services.NewDeviceService(db)
+	menuService := services.NewMenuService(db)
+	idempotencyService := services.NewIdempotencyService(db)
+	cancellationService := services.NewCancellationService(db, paymentService)
+	guestService := services.NewGuestService(db, orderService)
+
+	// Periodically drop expired sessions and OTPs
+	go authService.RunSweeper(config.Auth().SweepInterval)
+	go idempotencyService.RunSweeper(config.Auth().SweepInterval)
+	go guestService.RunSweeper(config.Auth().SweepInterval)
+
+	// Retry refunds the payment gateway did not take
+	go paymentService.RunRefundRetrier(config.Payments().RefundRetryInterval)
 
 	// Initialize WebSocket hub
 	hub := websocket.NewHub()
 	go hub.Run()
 
+	tableService := services.NewTableService(db, hub)
+	reservationService := services.NewReservationService(db, tableService, smsSender)
+	waitlistService := services.NewWaitlistService(db, tableService, smsSender)
+	printerService := services.NewPrinterService(db, hub)
+
 	// Initialize handlers
-	orderHandler := handlers.NewOrderHandler(orderService, hub)
-	paymentHandler := handlers.NewPaymentHandler(paymentService, hub)
+	orderHandler := handlers.NewOrderHandler(orderService, tableService, kitchenService, hub)
+	paymentHandler := handlers.NewPaymentHandler(paymentService, orderService, tableService, hub)
 	accountHandler := handlers.NewAccountHandler(accountService)
-	kitchenHandler := handlers.NewKitchenHandler(kitchenService, hub)
+	kitchenHandler := handlers.NewKitchenHandler(kitchenService, tableService, hub)
 	authHandler := handlers.NewAuthHandler(authService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	deviceHandler := handlers.NewDeviceHandler(deviceService)
+	menuHandler := handlers.NewMenuHandler(menuService, hub)
+	cancellationHandler := handlers.NewCancellationHandler(cancellationService, orderService, tableService, hub)
+	tableHandler := handlers.NewTableHandler(tableService)
+	guestHandler := handlers.NewGuestHandler(guestService, tableService, hub)
+	reservationHandler := handlers.NewReservationHandler(reservationService, hub)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService, hub)
+	printerHandler := handlers.NewPrinterHandler(printerService)
+
+	// Alert the kitchen and front of house about orders running late
+	go kitchenService.RunLateMonitor(config.Kitchen().MonitorInterval, kitchenHandler.NotifyOrderLate)
+
+	// Print kitchen tickets as items reach the kitchen
+	go printerService.RunTicketPrinter(config.Printing().PollInterval)
 
 	// Setup router
 	router := gin.Default()
+	if err := router.SetTrustedProxies(config.Auth().TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 
 	// Share services in context
 	router.Use(func(c *gin.Context) {
 		c.Set("paymentService", paymentService)
+		c.Set("orderService", orderService)
+		c.Set("tableService", tableService)
+		c.Set("hub", hub)
 		c.Next()
 	})
 
@@ -57,7 +117,7 @@ func main() {
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+middleware.DeviceIDHeader+", "+middleware.IdempotencyKeyHeader)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -77,40 +137,201 @@ func main() {
 			auth.POST("/verify-otp", authHandler.VerifyOTP)
 		}
 
+		// Public menu routes
+		api.GET("/menu", menuHandler.GetMenu)
+		api.GET("/menu/:id", menuHandler.GetMenuItem)
+
+		// Device pairing routes, used by devices that are not authorized yet
+		pairing := api.Group("/devices/pair")
+		{
+			pairing.POST("", deviceHandler.StartPairing)
+			pairing.GET("/:device_id", deviceHandler.GetPairingStatus)
+		}
+
+		// Guests at a table order with the token they get by scanning its QR code
+		api.POST("/guest/session", guestHandler.StartSession)
+		guest := api.Group("/guest", middleware.RequireTableToken(guestService))
+		{
+			guest.POST("/orders", middleware.Idempotency(idempotencyService, "guest.orders.create"), guestHandler.CreateOrder)
+			guest.GET("/orders", guestHandler.GetOrders)
+			guest.GET("/orders/:id", guestHandler.GetOrder)
+		}
+
+		// Payment gateway callbacks are authenticated by the gateway, not by a session
+		api.POST("/payments/notify/telebirr", handlers.TelebirrNotifyHandler)
+
+		// Everything below requires a valid session
+		protected := api.Group("", middleware.RequireAuth(authService))
+
+		// Session management routes
+		sessions := protected.Group("/auth")
+		{
+			sessions.POST("/logout", authHandler.Logout)
+			sessions.POST("/refresh", authHandler.RefreshSession)
+			sessions.GET("/sessions", authHandler.ListSessions)
+			sessions.DELETE("/sessions/:id", authHandler.RevokeSession)
+		}
+
 		// Order routes
-		orders := api.Group("/orders")
+		orders := protected.Group("/orders")
 		{
-			orders.POST("", orderHandler.CreateOrder)
+			orders.POST("", middleware.RequirePermission(models.PermOrdersCreate), middleware.Idempotency(idempotencyService, "orders.create"), orderHandler.CreateOrder)
 			orders.GET("/:id", orderHandler.GetOrder)
+			orders.GET("/:id/history", orderHandler.GetOrderHistory)
+			orders.POST("/:id/cancel", cancellationHandler.CancelOrder)
 			orders.GET("", orderHandler.GetOrders)
-			orders.PUT("/:id/status", orderHandler.UpdateOrderStatus)
+			orders.PUT("/:id/status", middleware.RequirePermission(models.PermOrdersUpdateStatus), orderHandler.UpdateOrderStatus)
+			orders.POST("/:id/courses/:course/fire", middleware.RequirePermission(models.PermOrdersUpdateStatus), orderHandler.FireCourse)
+			orders.POST("/:id/receipt/print", middleware.RequirePermission(models.PermOrdersUpdateStatus), printerHandler.PrintReceipt)
 		}
 
 		// Payment routes
-		payments := api.Group("/payments")
+		payments := protected.Group("/payments")
 		{
-			payments.POST("", paymentHandler.ProcessPayment)
+			payments.POST("", middleware.Idempotency(idempotencyService, "payments.create"), paymentHandler.ProcessPayment)
 			payments.GET("/:id", paymentHandler.GetPaymentStatus)
-			payments.POST("/notify/telebirr", handlers.TelebirrNotifyHandler)
 		}
 
 		// Account routes
-		accounts := api.Group("/accounts")
+		accounts := protected.Group("/accounts")
 		{
 			accounts.GET("/:id/balance", accountHandler.GetAccountBalance)
 			accounts.POST("", accountHandler.CreateAccount)
 		}
 
 		// Kitchen routes
-		kitchen := api.Group("/kitchen")
+		kitchen := protected.Group("/kitchen", middleware.RequirePermission(models.PermKitchenAccess))
 		{
 			kitchen.GET("/orders", kitchenHandler.GetPendingOrders)
 			kitchen.PUT("/orders/:id/status", kitchenHandler.UpdateOrderStatus)
+			kitchen.GET("/stations/:station/tickets", kitchenHandler.GetStationTickets)
+			kitchen.POST("/items/:id/bump", kitchenHandler.BumpItem)
+			kitchen.POST("/items/:id/recall", kitchenHandler.RecallItem)
+			kitchen.POST("/orders/:id/print", printerHandler.ReprintTickets)
+		}
+
+		// Admin routes
+		admin := protected.Group("/admin", middleware.RequirePermission(models.PermRolesManage))
+		{
+			admin.GET("/roles", roleHandler.ListRoles)
+			admin.GET("/accounts/:id/roles", roleHandler.GetAccountRoles)
+			admin.POST("/accounts/:id/roles", roleHandler.GrantRole)
+			admin.DELETE("/accounts/:id/roles/:role", roleHandler.RevokeRole)
+		}
+
+		// Cancellation approval routes
+		cancellations := protected.Group("/cancellations", middleware.RequirePermission(models.PermOrdersApproveCancel))
+		{
+			cancellations.GET("", cancellationHandler.ListCancellations)
+			cancellations.POST("/:id/approve", cancellationHandler.ApproveCancellation)
+			cancellations.POST("/:id/reject", cancellationHandler.RejectCancellation)
+		}
+
+		// Host stand routes
+		tables := protected.Group("/tables", middleware.RequirePermission(models.PermTablesOperate))
+		{
+			tables.GET("", tableHandler.GetFloorPlan)
+			tables.GET("/:id", tableHandler.GetTable)
+			tables.PUT("/:id/state", tableHandler.SetTableState)
+		}
+
+		// Reservation routes
+		reservations := protected.Group("/reservations", middleware.RequirePermission(models.PermReservationsManage))
+		{
+			reservations.GET("", reservationHandler.GetReservations)
+			reservations.GET("/availability", reservationHandler.GetAvailability)
+			reservations.POST("", reservationHandler.CreateReservation)
+			reservations.GET("/:id", reservationHandler.GetReservation)
+			reservations.PUT("/:id", reservationHandler.UpdateReservation)
+			reservations.PUT("/:id/status", reservationHandler.SetReservationStatus)
+		}
+
+		// Walk-in waitlist routes
+		waitlist := protected.Group("/waitlist", middleware.RequirePermission(models.PermReservationsManage))
+		{
+			waitlist.GET("", waitlistHandler.GetWaitlist)
+			waitlist.GET("/quote", waitlistHandler.GetQuote)
+			waitlist.POST("", waitlistHandler.JoinWaitlist)
+			waitlist.POST("/:id/notify", waitlistHandler.NotifyTableReady)
+			waitlist.POST("/:id/seat", waitlistHandler.SeatParty)
+			waitlist.DELETE("/:id", waitlistHandler.RemoveParty)
+		}
+
+		// Floor plan management routes
+		floor := protected.Group("/admin", middleware.RequirePermission(models.PermTablesManage))
+		{
+			floor.POST("/areas", tableHandler.CreateArea)
+			floor.PUT("/areas/:id", tableHandler.UpdateArea)
+			floor.DELETE("/areas/:id", tableHandler.DeleteArea)
+			floor.POST("/tables", tableHandler.CreateTable)
+			floor.PUT("/tables/:id", tableHandler.UpdateTable)
+			floor.DELETE("/tables/:id", tableHandler.DeleteTable)
+			floor.GET("/tables/qr", tableHandler.GetTableQRSheet)
+			floor.GET("/tables/:id/qr", tableHandler.GetTableQR)
+			floor.POST("/tables/:id/qr/rotate", tableHandler.RotateTableQR)
+		}
+
+		// Menu management routes
+		menu := protected.Group("/menu", middleware.RequirePermission(models.PermMenuManage))
+		{
+			menu.POST("", menuHandler.CreateMenuItem)
+			menu.PUT("/:id", menuHandler.UpdateMenuItem)
+			menu.PUT("/:id/availability", menuHandler.SetAvailability)
+			menu.DELETE("/:id", menuHandler.DeleteMenuItem)
+			menu.POST("/:id/modifier-groups", menuHandler.CreateModifierGroup)
+			menu.PUT("/:id/modifier-groups/:group_id", menuHandler.UpdateModifierGroup)
+			menu.DELETE("/:id/modifier-groups/:group_id", menuHandler.DeleteModifierGroup)
+		}
+
+		// Device management routes
+		devices := protected.Group("/admin/devices", middleware.RequirePermission(models.PermDevicesManage))
+		{
+			devices.GET("", deviceHandler.ListDevices)
+			devices.POST("", deviceHandler.RegisterDevice)
+			devices.PUT("/:id", deviceHandler.UpdateDevice)
+			devices.DELETE("/:id", deviceHandler.DeleteDevice)
+			devices.GET("/pairings", deviceHandler.ListPendingPairings)
+			devices.POST("/pairings/:code/approve", deviceHandler.ApprovePairing)
+		}
+
+		// Printer management routes
+		printers := protected.Group("/admin/printers", middleware.RequirePermission(models.PermDevicesManage))
+		{
+			printers.GET("", printerHandler.ListPrinters)
+			printers.POST("", printerHandler.CreatePrinter)
+			printers.GET("/status", printerHandler.GetStatus)
+			printers.PUT("/:id", printerHandler.UpdatePrinter)
+			printers.DELETE("/:id", printerHandler.DeletePrinter)
+			printers.POST("/:id/test", printerHandler.PrintTest)
 		}
 
 		// WebSocket route
-		api.GET("/ws", func(c *gin.Context) {
-			websocket.HandleWebSocket(hub, c.Writer, c.Request)
+		protected.GET("/ws", func(c *gin.Context) {
+			// Kitchen screens get kitchen events, front of house gets guest orders to confirm
+			actor := middleware.CurrentActor(c)
+			var roles []string
+			if actor.Can(models.PermKitchenAccess) {
+				roles = append(roles, string(models.RoleKitchen))
+			}
+			if actor.Can(models.PermTablesOperate) {
+				roles = append(roles, "staff")
+			}
+			if len(roles) == 0 {
+				roles = append(roles, string(models.RoleCustomer))
+			}
+			// A station screen subscribes with ?stations=grill,fryer and sees only those tickets
+			var stations []string
+			if actor.Can(models.PermKitchenAccess) && c.Query("stations") != "" {
+				for _, station := range strings.Split(c.Query("stations"), ",") {
+					station = strings.TrimSpace(station)
+					if !models.Station(station).Valid() {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kitchen station: " + station})
+						return
+					}
+					stations = append(stations, station)
+				}
+			}
+			websocket.HandleStationWebSocket(hub, c.Writer, c.Request, actor.AccountID, stations, roles...)
 		})
 	}
 
@@ -1,7 +1,7 @@
 "use client"
 
 import { useState } from 'react'
-import { requestOTP, verifyOTP } from '@/src/lib/api'
+import { ApiError, requestOTP, startPairing, verifyOTP } from '@/src/lib/api'
 import { getOrCreateDeviceId } from '@/src/lib/device'
 
 export default function AppPage() {
@@ -11,21 +11,40 @@ export default function AppPage() {
   const [token, setToken] = useState('')
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState('')
+  const [pairingCode, setPairingCode] = useState('')
 
   async function onRequestOTP() {
     setError('')
+    setPairingCode('')
     setLoading(true)
     try {
       const device_id = getOrCreateDeviceId()
       await requestOTP(phone, device_id)
       setStep('otp')
     } catch (e: any) {
-      setError(e.message || 'Failed to request OTP')
+      if (e instanceof ApiError && e.code === 'unauthorized_device') {
+        await onUnauthorizedDevice()
+        return
+      }
+      setError(describeError(e, 'Failed to request OTP'))
     } finally {
       setLoading(false)
     }
   }
 
+  async function onUnauthorizedDevice() {
+    try {
+      const pairing = await startPairing(getOrCreateDeviceId(), navigator.userAgent.slice(0, 60))
+      if (pairing.status === 'approved') {
+        setError('This device was just approved. Please try again.')
+        return
+      }
+      setPairingCode(pairing.code || '')
+    } catch (e: any) {
+      setError(describeError(e, 'This device is not authorized'))
+    }
+  }
+
   async function onVerify() {
     setError('')
     setLoading(true)
@@ -35,7 +54,7 @@ export default function AppPage() {
       setToken(res.token)
       setStep('done')
     } catch (e: any) {
-      setError(e.message || 'Failed to verify OTP')
+      setError(describeError(e, 'Failed to verify OTP'))
     } finally {
       setLoading(false)
     }
@@ -89,11 +108,23 @@ export default function AppPage() {
           </div>
         )}
 
+        {pairingCode && (
+          <div className="mt-6 card text-center">
+            <p className="text-white/70 text-sm">This device is not registered yet. Ask a manager to approve this code:</p>
+            <p className="mt-2 text-3xl font-semibold tracking-widest">{pairingCode}</p>
+          </div>
+        )}
+
         {error && <p className="mt-4 text-red-400 text-sm">{error}</p>}
       </div>
     </main>
   )
 }
 
-
-
+function describeError(e: any, fallback: string) {
+  if (e instanceof ApiError) {
+    if (e.retryAfter) return `${e.message}. Try again in ${e.retryAfter}s.`
+    if (e.attemptsRemaining !== undefined) return `${e.message}. ${e.attemptsRemaining} attempts left.`
+  }
+  return e?.message || fallback
+}
@@ -0,0 +1,151 @@
+"use client"
+
+import { useEffect, useState } from 'react'
+import {
+  ApiError,
+  GuestOrder,
+  GuestSession,
+  MenuItem,
+  getGuestOrders,
+  getMenu,
+  placeGuestOrder,
+  startGuestSession,
+} from '@/src/lib/api'
+
+const SESSION_KEY = 'restaurant_table_session'
+
+// Guests land here from the QR code on their table: ?table=<id>&key=<key>
+export default function TablePage() {
+  const [session, setSession] = useState<GuestSession | null>(null)
+  const [menu, setMenu] = useState<{ category: string; items: MenuItem[] }[]>([])
+  const [cart, setCart] = useState<Record<string, number>>({})
+  const [guests, setGuests] = useState(1)
+  const [orders, setOrders] = useState<GuestOrder[]>([])
+  const [loading, setLoading] = useState(false)
+  const [error, setError] = useState('')
+
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.search)
+    const table = params.get('table')
+    const key = params.get('key')
+    const saved = sessionStorage.getItem(SESSION_KEY)
+
+    async function start() {
+      try {
+        let s: GuestSession | null = saved ? JSON.parse(saved) : null
+        if (table && key && (!s || s.table.id !== table || new Date(s.expires_at) < new Date())) {
+          s = await startGuestSession(table, key)
+          sessionStorage.setItem(SESSION_KEY, JSON.stringify(s))
+        }
+        if (!s) {
+          setError('Scan the QR code on your table to order.')
+          return
+        }
+        setSession(s)
+        setMenu(await getMenu())
+        setOrders(await getGuestOrders(s.token))
+      } catch (e: any) {
+        sessionStorage.removeItem(SESSION_KEY)
+        setError(e?.message || 'Failed to open the menu')
+      }
+    }
+    start()
+  }, [])
+
+  const items = menu.flatMap((c) => c.items)
+  const total = items.reduce((sum, item) => sum + item.price * (cart[item.id] || 0), 0)
+
+  function add(id: string, delta: number) {
+    setCart((c) => {
+      const quantity = Math.max(0, (c[id] || 0) + delta)
+      const next = { ...c, [id]: quantity }
+      if (quantity === 0) delete next[id]
+      return next
+    })
+  }
+
+  async function onPlaceOrder() {
+    if (!session) return
+    setError('')
+    setLoading(true)
+    try {
+      const order = await placeGuestOrder(session.token, crypto.randomUUID(), {
+        items: Object.entries(cart).map(([menu_item_id, quantity]) => ({ menu_item_id, quantity })),
+        guest_count: guests,
+      })
+      setOrders((o) => [order, ...o])
+      setCart({})
+    } catch (e: any) {
+      if (e instanceof ApiError && e.code === 'table_token_expired') {
+        sessionStorage.removeItem(SESSION_KEY)
+      }
+      setError(e?.message || 'Failed to place order')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <main className="container-prose py-12">
+      <div className="max-w-2xl mx-auto space-y-6">
+        <h1 className="text-2xl font-semibold">{session ? `Table ${session.table.name}` : 'Welcome'}</h1>
+
+        {menu.map((category) => (
+          <section key={category.category} className="card">
+            <h2 className="text-lg font-semibold">{category.category}</h2>
+            <ul className="mt-3 space-y-3">
+              {category.items.map((item) => (
+                <li key={item.id} className="flex items-center justify-between gap-4">
+                  <div>
+                    <div>{item.name}</div>
+                    <div className="text-sm text-white/60">{item.price.toFixed(2)}</div>
+                  </div>
+                  <div className="flex items-center gap-3">
+                    <button onClick={() => add(item.id, -1)} disabled={!cart[item.id]}>−</button>
+                    <span>{cart[item.id] || 0}</span>
+                    <button onClick={() => add(item.id, 1)}>+</button>
+                  </div>
+                </li>
+              ))}
+            </ul>
+          </section>
+        ))}
+
+        {session && (
+          <div className="card space-y-4">
+            <label className="flex items-center justify-between">
+              <span>Guests at the table</span>
+              <input
+                type="number"
+                min={1}
+                className="w-20 rounded-md bg-white/5 border border-white/10 px-3 py-2"
+                value={guests}
+                onChange={(e) => setGuests(Math.max(1, Number(e.target.value)))}
+              />
+            </label>
+            <button className="btn-primary w-full" onClick={onPlaceOrder} disabled={loading || total === 0}>
+              {loading ? 'Sending…' : `Place order · ${total.toFixed(2)}`}
+            </button>
+            <p className="text-sm text-white/60">A waiter will confirm your order before it goes to the kitchen.</p>
+          </div>
+        )}
+
+        {orders.length > 0 && (
+          <section className="card">
+            <h2 className="text-lg font-semibold">Your orders</h2>
+            <ul className="mt-3 space-y-2">
+              {orders.map((order) => (
+                <li key={order.id} className="flex justify-between">
+                  <span>{order.display_number}</span>
+                  <span className="text-white/70">{order.status === 'pending' ? 'waiting for a waiter' : order.status}</span>
+                </li>
+              ))}
+            </ul>
+          </section>
+        )}
+
+        {error && <p className="text-red-400 text-sm">{error}</p>}
+      </div>
+    </main>
+  )
+}
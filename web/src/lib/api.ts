@@ -1,5 +1,28 @@
 const API_BASE = process.env.NEXT_PUBLIC_API_BASE || 'http://localhost:8080/api/v1'
 
+export class ApiError extends Error {
+  code?: string
+  retryAfter?: number
+  attemptsRemaining?: number
+
+  constructor(message: string, body: any = {}) {
+    super(message)
+    this.code = body.code
+    this.retryAfter = body.retry_after
+    this.attemptsRemaining = body.attempts_remaining
+  }
+}
+
+async function toApiError(res: Response) {
+  const text = await res.text()
+  try {
+    const body = JSON.parse(text)
+    return new ApiError(body.error || text, body)
+  } catch {
+    return new ApiError(text)
+  }
+}
+
 export async function requestOTP(phone_number: string, device_id: string) {
   const res = await fetch(`${API_BASE}/auth/request-otp`, {
     method: 'POST',
@@ -7,7 +30,7 @@ export async function requestOTP(phone_number: string, device_id: string) {
     body: JSON.stringify({ phone_number, device_id }),
     cache: 'no-store',
   })
-  if (!res.ok) throw new Error(await res.text())
+  if (!res.ok) throw await toApiError(res)
   return res.json()
 }
 
@@ -18,9 +41,91 @@ export async function verifyOTP(phone_number: string, code: string, device_id: s
     body: JSON.stringify({ phone_number, code, device_id }),
     cache: 'no-store',
   })
-  if (!res.ok) throw new Error(await res.text())
+  if (!res.ok) throw await toApiError(res)
   return res.json() as Promise<{ token: string }>
 }
 
+export type DevicePairing = {
+  code?: string
+  device_id: string
+  status: 'pending' | 'approved' | 'expired'
+  expires_at?: string
+}
 
+export async function startPairing(device_id: string, name: string) {
+  const res = await fetch(`${API_BASE}/devices/pair`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ device_id, name }),
+    cache: 'no-store',
+  })
+  if (!res.ok) throw await toApiError(res)
+  return (await res.json()).pairing as DevicePairing
+}
+
+export type MenuItem = {
+  id: string
+  name: string
+  description: string
+  price: number
+  category: string
+}
+
+export type GuestSession = {
+  token: string
+  expires_at: string
+  table: { id: string; name: string; capacity: number }
+}
+
+export type GuestOrder = {
+  id: string
+  display_number: string
+  status: string
+  total_amount: number
+  items: { name: string; quantity: number }[]
+}
 
+export async function getMenu() {
+  const res = await fetch(`${API_BASE}/menu`, { cache: 'no-store' })
+  if (!res.ok) throw await toApiError(res)
+  return (await res.json()).categories as { category: string; items: MenuItem[] }[]
+}
+
+export async function startGuestSession(table_id: string, key: string) {
+  const res = await fetch(`${API_BASE}/guest/session`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ table_id, key }),
+    cache: 'no-store',
+  })
+  if (!res.ok) throw await toApiError(res)
+  return (await res.json()).session as GuestSession
+}
+
+export async function placeGuestOrder(
+  token: string,
+  idempotencyKey: string,
+  order: { items: { menu_item_id: string; quantity: number }[]; guest_count: number; notes?: string },
+) {
+  const res = await fetch(`${API_BASE}/guest/orders`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      Authorization: `Bearer ${token}`,
+      'Idempotency-Key': idempotencyKey,
+    },
+    body: JSON.stringify(order),
+    cache: 'no-store',
+  })
+  if (!res.ok) throw await toApiError(res)
+  return (await res.json()).order as GuestOrder
+}
+
+export async function getGuestOrders(token: string) {
+  const res = await fetch(`${API_BASE}/guest/orders`, {
+    headers: { Authorization: `Bearer ${token}` },
+    cache: 'no-store',
+  })
+  if (!res.ok) throw await toApiError(res)
+  return (await res.json()).orders as GuestOrder[]
+}